@@ -4,6 +4,7 @@ import (
 //	"flag"
 	"os"
 	"fmt"
+	"encoding/json"
 	"time"
 	"strings"
 //	genericapiserver "k8s.io/apiserver/pkg/server"
@@ -45,39 +46,73 @@ func main() {
 			}
 		}
 		if commandType == "composition" {
-			if len(os.Args) < 5 {
-				panic("Not enough arguments: ./kubediscovery composition <kind> <instance> <namespace>")
+			// kubediscovery composition Moodle moodle1 default
+			// kubediscovery composition Moodle moodle1 --all-namespaces
+			args := []string{}
+			kubeconfigpath := ""
+			allNamespaces := false
+			outputFormat := "json"
+			for _, opt := range os.Args[2:] {
+				if strings.EqualFold(opt, "--all-namespaces") {
+					allNamespaces = true
+					continue
+				}
+				if strings.EqualFold(opt, "--infer-compositions") {
+					discovery.InferCompositions = true
+					continue
+				}
+				parts := strings.Split(opt, "=")
+				if len(parts) == 2 && strings.EqualFold(parts[0], "--kubeconfig") {
+					kubeconfigpath = parts[1]
+					continue
+				}
+				if len(parts) == 2 && strings.EqualFold(parts[0], "--output") {
+					outputFormat = parts[1]
+					continue
+				}
+				args = append(args, opt)
 			}
-			if len(os.Args) == 5 {
-				kind = os.Args[2]
-				instance = os.Args[3]
-				namespace = os.Args[4]
-				discovery.BuildConfig("")
+			if len(args) < 2 || (len(args) < 3 && !allNamespaces) {
+				panic("Not enough arguments: ./kubediscovery composition <kind> <instance> <namespace|--all-namespaces> [--infer-compositions] [--output=json|dot|mermaid]")
 			}
-			if len(os.Args) == 6 {
-				kind = os.Args[2]
-				instance = os.Args[3]
-				namespace = os.Args[4]
-				kubeconfig := os.Args[5]
-				kubeconfigparts := strings.Split(kubeconfig, "=")
-				kubeconfigpath := kubeconfigparts[1]
-				//fmt.Printf("Kubeconfig Path:%s\n", kubeconfigpath)
-				discovery.BuildConfig(kubeconfigpath)
+			kind = args[0]
+			instance = args[1]
+			if !allNamespaces {
+				namespace = args[2]
 			}
+			discovery.BuildConfig(kubeconfigpath)
 			discovery.BuildCompositionTree(namespace)
-			composition := discovery.TotalClusterCompositions.GetCompositionsString(kind,
-																			  instance,
-																			  namespace)
-			fmt.Printf("%s\n", composition)
+			if outputFormat == "dot" || outputFormat == "mermaid" {
+				composition := discovery.TotalClusterCompositions.GetCompositionsGraphString(kind,
+																				  instance,
+																				  namespace,
+																				  outputFormat)
+				fmt.Printf("%s", composition)
+			} else {
+				composition := discovery.TotalClusterCompositions.GetCompositionsString(kind,
+																				  instance,
+																				  namespace)
+				fmt.Printf("%s\n", composition)
+			}
 		}
 		if commandType == "connections" {
-			if len(os.Args) < 5  {
+			// kubediscovery connections Pod pod1 default
+			// kubediscovery connections Node worker-3 (cluster-scoped kinds need no namespace)
+			// kubediscovery connections Pod pod1 default --namespaces=db,monitoring
+			args := []string{}
+			for _, opt := range os.Args[2:] {
+				if !strings.HasPrefix(opt, "-") {
+					args = append(args, opt)
+				}
+			}
+			if len(args) < 2 {
 				panic("Not enough arguments:./kubediscovery connections <kind> <instance> <namespace>")
 			}
-			// change to 3, 4, 5 when collecting profiling data
-			kind = os.Args[2]
-			instance = os.Args[3]
-			namespace = os.Args[4]
+			kind = args[0]
+			instance = args[1]
+			if len(args) > 2 {
+				namespace = args[2]
+			}
 			discovery.OriginalInputNamespace = namespace
 			discovery.OriginalInputKind = kind
 			discovery.OriginalInputInstance = instance
@@ -103,6 +138,15 @@ func main() {
 			kubeconfigpath := ""
 			for _, opt := range os.Args {
 				//fmt.Printf("Opt:%s\n", opt)
+				if strings.EqualFold(opt, "--rbac-summary") {
+					discovery.RBACSummary = true
+				}
+				if strings.EqualFold(opt, "--endpoints") {
+					discovery.EnableEndpointsRelationships()
+				}
+				if strings.EqualFold(opt, "--all-namespaces") {
+					discovery.SearchAllNamespaces = true
+				}
 				parts := strings.Split(opt, "=")
 				if len(parts) == 2 {
 					option := parts[0]
@@ -116,6 +160,14 @@ func main() {
 					    //parts = strings.Split(opt, "=")
 						discovery.OutputFormat = optVal
 					}
+					namespacesfound := strings.EqualFold(option, "--namespaces")
+					if namespacesfound {
+						for _, ns := range strings.Split(optVal, ",") {
+							if strings.TrimSpace(ns) != "" {
+								discovery.SearchNamespaces = append(discovery.SearchNamespaces, strings.TrimSpace(ns))
+							}
+						}
+					}
 					kubeconfigfound := strings.EqualFold(option, "--kubeconfig")
 					if kubeconfigfound {
 						kubeconfigpath = optVal
@@ -132,6 +184,9 @@ func main() {
 			discovery.BuildConfig(kubeconfigpath)
 
 			_ = discovery.ReadKinds(kind)
+			if namespace == "" && !discovery.IsClusterScoped(kind) {
+				panic("Not enough arguments:./kubediscovery connections <kind> <instance> <namespace>")
+			}
 			exists := discovery.CheckExistence(kind, instance, namespace)
 			if exists {
 				// Prefetching does not seem to improve performance.
@@ -166,11 +221,60 @@ func main() {
 				if len(discovery.TotalClusterConnections) > 0 {
 					discovery.PrintRelatives(discovery.OutputFormat, discovery.TotalClusterConnections)
 				}
+				// The summary is plain text; it is not added to json, dot or mermaid output
+				if discovery.RBACSummary && discovery.OutputFormat != "json" && discovery.OutputFormat != "dot" && discovery.OutputFormat != "mermaid" {
+					fmt.Printf("%s", discovery.GetRBACSummaryString(discovery.TotalClusterConnections))
+				}
 			} else {
 				fmt.Printf("Resource %s of kind %s in namespace %s does not exist.\n", instance, kind, namespace)
 				os.Exit(1)
 			}
 		}
+		if commandType == "ancestry" {
+			// kubediscovery ancestry Pod <name> <namespace>
+			if len(os.Args) < 5 {
+				panic("Not enough arguments: ./kubediscovery ancestry <kind> <instance> <namespace>")
+			}
+			kind = os.Args[2]
+			instance = os.Args[3]
+			namespace = os.Args[4]
+			kubeconfigpath := ""
+			for _, opt := range os.Args {
+				parts := strings.Split(opt, "=")
+				if len(parts) == 2 && strings.EqualFold(parts[0], "--kubeconfig") {
+					kubeconfigpath = parts[1]
+				}
+			}
+			discovery.BuildConfig(kubeconfigpath)
+			_ = discovery.ReadKinds(kind)
+			ancestry, err := discovery.GetAncestry(kind, instance, namespace)
+			if err != nil {
+				fmt.Printf("%s\n", err.Error())
+				os.Exit(1)
+			}
+			ancestryBytes, _ := json.Marshal(ancestry)
+			fmt.Printf("%s\n", string(ancestryBytes))
+		}
+		if commandType == "kinds" {
+			// kubediscovery kinds --infer-compositions
+			kubeconfigpath := ""
+			for _, opt := range os.Args[2:] {
+				if strings.EqualFold(opt, "--infer-compositions") {
+					discovery.InferCompositions = true
+				}
+				parts := strings.Split(opt, "=")
+				if len(parts) == 2 && strings.EqualFold(parts[0], "--kubeconfig") {
+					kubeconfigpath = parts[1]
+				}
+			}
+			discovery.BuildConfig(kubeconfigpath)
+			err := discovery.ReadKinds("")
+			if err != nil {
+				fmt.Printf("%s\n", err.Error())
+				os.Exit(1)
+			}
+			fmt.Printf("%s\n", discovery.GetCompositionKindsString())
+		}
 		if commandType == "man" {
 			discovery.BuildConfig("")
 			if len(os.Args) != 3 {
@@ -185,19 +289,32 @@ func main() {
 			nodeName := os.Args[2]
 			cAdvisorMetrics := discovery.GetCAdvisorMetrics(nodeName)
 			fmt.Printf("-----\n")
-			fmt.Printf(cAdvisorMetrics)
+			fmt.Printf("%s", cAdvisorMetrics)
 		}
 		if commandType == "podmetrics" {
 			discovery.BuildConfig("")
 			nodeName := os.Args[2]
 			podMetrics := discovery.GetKubeletMetrics(nodeName)
 			//fmt.Printf("-----\n")
-			fmt.Printf(podMetrics)
+			fmt.Printf("%s", podMetrics)
 		}
 	} else {
 		fmt.Printf("Running from within cluster.\n")
 		fmt.Printf("Installing KubePlus paths.\n")
 		discovery.BuildConfig("")
+		// Server mode counterpart of --endpoints
+		if strings.EqualFold(os.Getenv("ENDPOINTS_RELATIONSHIPS"), "true") {
+			discovery.EnableEndpointsRelationships()
+		}
+		// Maintain compositions incrementally from informer events.
+		// Compositions are built on each query until the index has synced.
+		stopCh := make(chan struct{})
+		go func() {
+			err := discovery.StartCompositionIndex(stopCh)
+			if err != nil {
+				fmt.Printf("Error: %s\n", err.Error())
+			}
+		}()
 		go apiserver.InstallKubePlusPaths()
 		fmt.Printf("After installing KubePlus paths.\n")
 		// Run forever
@@ -25,6 +25,8 @@ const GroupVersion = "v1"
 const KIND_QUERY_PARAM = "kind"
 const INSTANCE_QUERY_PARAM = "instance"
 const NAMESPACE_QUERY_PARAM = "namespace"
+const ALL_NAMESPACES_QUERY_PARAM = "all-namespaces"
+const OUTPUT_QUERY_PARAM = "output"
 
 var (
 	Scheme             = runtime.NewScheme()
@@ -158,6 +160,8 @@ func InstallKubePlusPaths() {//discoveryServer *DiscoveryServer) {
 	ws1.Route(ws1.GET("/helloworld").To(handleHelloWorld))
 	ws1.Route(ws1.GET("/explain").To(handleExplainEndpoint))
 	ws1.Route(ws1.GET("/composition").To(handleCompositionEndpoint))
+	ws1.Route(ws1.GET("/ancestry").To(handleAncestryEndpoint))
+	ws1.Route(ws1.GET("/kinds").To(handleKindsEndpoint))
 	//ws1.Route(ws1.GET("/implementation_details").To(handleImplementationDetailsEndpoint))
 	//ws1.Route(ws1.GET("/usage").To(handleUsageEndpoint))
 	ws1.Route(ws1.GET("/man").To(handleManPageEndpoint))
@@ -205,7 +209,7 @@ func GetManPage(customResourceKind string) string {
 		implementationDetails = "Error in retrieving implementation details for Custom Resource:"
 	}
 
-	fmt.Println("Implementation choices:%v", implementationDetails)
+	fmt.Printf("Implementation choices:%v\n", implementationDetails)
 	*/
 
 	manPage := discovery.GetUsageDetails(customResourceKind)
@@ -224,7 +228,7 @@ func handleImplementationDetailsEndpoint(request *restful.Request, response *res
 		implementationDetails = "Error in retrieving implementation details for Custom Resource:"
 	}
 
-	fmt.Println("Implementation details:%v", implementationDetails)
+	fmt.Printf("Implementation details:%v\n", implementationDetails)
 
 	response.Write([]byte(implementationDetails))
 }
@@ -235,7 +239,7 @@ func handleUsageEndpoint(request *restful.Request, response *restful.Response) {
 
 	usageDetails := discovery.GetUsageDetails(customResourceKind)
 
-	fmt.Println("Usage details:%v", usageDetails)
+	fmt.Printf("Usage details:%v\n", usageDetails)
 
 	response.Write([]byte(usageDetails))
 }
@@ -244,21 +248,58 @@ func handleCompositionEndpoint(request *restful.Request, response *restful.Respo
 	resourceKind := request.QueryParameter(KIND_QUERY_PARAM)
 	resourceInstance := request.QueryParameter(INSTANCE_QUERY_PARAM)
 	namespace := request.QueryParameter(NAMESPACE_QUERY_PARAM)
+	allNamespaces := request.QueryParameter(ALL_NAMESPACES_QUERY_PARAM)
 	fmt.Printf("Kind:%s, Instance:%s\n", resourceKind, resourceInstance)
 	if namespace == "" {
 		namespace = "default"
 	}
+	// Empty namespace looks for the instance across all namespaces.
+	if strings.EqualFold(allNamespaces, "true") {
+		namespace = ""
+	}
 
-	discovery.BuildCompositionTree(namespace)
-
-	compositionInfo := discovery.TotalClusterCompositions.GetCompositionsString(resourceKind,
-																		  resourceInstance,
-																		  namespace)
+	// Compositions of the indexed Kinds are maintained by the composition index once it has synced.
+	discovery.BuildKindCompositions(resourceKind, namespace)
+
+	var compositionInfo string
+	outputFormat := request.QueryParameter(OUTPUT_QUERY_PARAM)
+	if outputFormat == "dot" || outputFormat == "mermaid" {
+		compositionInfo = discovery.TotalClusterCompositions.GetCompositionsGraphString(resourceKind,
+																			  resourceInstance,
+																			  namespace,
+																			  outputFormat)
+	} else {
+		compositionInfo = discovery.TotalClusterCompositions.GetCompositionsString(resourceKind,
+																			  resourceInstance,
+																			  namespace)
+	}
 	fmt.Printf("Composition:%v\n", compositionInfo)
 
 	response.Write([]byte(compositionInfo))
 }
 
+func handleAncestryEndpoint(request *restful.Request, response *restful.Response) {
+	resourceKind := request.QueryParameter(KIND_QUERY_PARAM)
+	resourceInstance := request.QueryParameter(INSTANCE_QUERY_PARAM)
+	namespace := request.QueryParameter(NAMESPACE_QUERY_PARAM)
+	fmt.Printf("Kind:%s, Instance:%s\n", resourceKind, resourceInstance)
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	ancestryInfo := discovery.GetAncestryString(resourceKind, resourceInstance, namespace)
+	fmt.Printf("Ancestry:%v\n", ancestryInfo)
+
+	response.Write([]byte(ancestryInfo))
+}
+
+func handleKindsEndpoint(request *restful.Request, response *restful.Response) {
+	kindsInfo := discovery.GetCompositionKindsString()
+	fmt.Printf("Kinds:%v\n", kindsInfo)
+
+	response.Write([]byte(kindsInfo))
+}
+
 func getWebService() *restful.WebService {
 	ws := new(restful.WebService)
 	ws.Path("/apis")
@@ -0,0 +1,86 @@
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// GetAncestry walks owner references upward from the given resource to its
+// top-level roots (e.g. Pod -> ReplicaSet -> Deployment -> Moodle).
+// All the owners of a resource are followed, not only the first one.
+func GetAncestry(kind, instance, namespace string) (Ancestor, error) {
+	visited := make(map[string]bool)
+	return getAncestor(kind, "", instance, namespace, 0, visited)
+}
+
+func GetAncestryString(kind, instance, namespace string) string {
+	ancestry, err := GetAncestry(kind, instance, namespace)
+	if err != nil {
+		return err.Error()
+	}
+	ancestryBytes, err := json.Marshal(ancestry)
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+	return string(ancestryBytes)
+}
+
+func getAncestor(kind, apiVersion, instance, namespace string, depth int, visited map[string]bool) (Ancestor, error) {
+	ancestor := Ancestor{
+		Kind:      kind,
+		Name:      instance,
+		Namespace: namespace,
+		Owners:    []Ancestor{},
+	}
+	if apiVersion != "" {
+		discoverKind(kind, apiVersion)
+	}
+	resKindPlural, _, resApiVersion, resGroup := getKindAPIDetails(kind)
+	if resKindPlural == "" {
+		return ancestor, fmt.Errorf("Unknown kind %s", kind)
+	}
+	// Owners of a namespaced object can be cluster-scoped (e.g. a Node owning a mirror Pod)
+	if IsClusterScoped(kind) {
+		namespace = ""
+		ancestor.Namespace = ""
+	}
+	res := schema.GroupVersionResource{Group: resGroup,
+		Version:  resApiVersion,
+		Resource: resKindPlural}
+	instanceObj, err := getKubeObject(kind, instance, namespace, res)
+	if err != nil {
+		return ancestor, fmt.Errorf("Resource %s of kind %s in namespace %s does not exist.", instance, kind, namespace)
+	}
+	ancestor.Namespace = instanceObj.GetNamespace()
+	ancestor.UID = string(instanceObj.GetUID())
+	ancestor.Status = getAncestorStatus(instanceObj)
+	ancestor.Health = computeHealth(kind, instanceObj.UnstructuredContent())
+
+	if visited[ancestor.UID] || depth > maxOwnerDepth {
+		return ancestor, nil
+	}
+	visited[ancestor.UID] = true
+
+	for _, owner := range findOwners(instanceObj) {
+		ownerAncestor, err := getAncestor(owner.Kind, owner.APIVersion, owner.Name, namespace, depth+1, visited)
+		if err != nil {
+			// Owner is not accessible (e.g. already deleted); record what the reference tells us.
+			ownerAncestor.UID = owner.UID
+			ownerAncestor.Status = "NotFound"
+		}
+		ancestor.Owners = append(ancestor.Owners, ownerAncestor)
+	}
+	return ancestor, nil
+}
+
+func getAncestorStatus(instanceObj unstructured.Unstructured) string {
+	content := instanceObj.UnstructuredContent()
+	phase, found, _ := unstructured.NestedString(content, "status", "phase")
+	if found {
+		return phase
+	}
+	return ""
+}
@@ -0,0 +1,124 @@
+package discovery
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// Serves the given objects from a fake dynamic client for the duration of a test.
+func setTestDynamicClient(t *testing.T, objects ...unstructured.Unstructured) *dynamicfake.FakeDynamicClient {
+	savedCfg, savedClient := cfg, dynamicClient
+	savedObjectCache, savedListCache := kubeObjectCache, kubeObjectListCache
+	kubeObjectCache = make(map[KubeObjectCacheEntry]interface{})
+	kubeObjectListCache = make(map[KubeObjectCacheEntry]interface{})
+	runtimeObjects := make([]runtime.Object, 0)
+	for i := range objects {
+		obj := objects[i].DeepCopy()
+		_, _, version, group := getKindAPIDetails(obj.GetKind())
+		if group != "" {
+			obj.SetAPIVersion(group + "/" + version)
+		} else {
+			obj.SetAPIVersion(version)
+		}
+		runtimeObjects = append(runtimeObjects, obj)
+	}
+	// Every known kind can be listed, also the ones without objects
+	listKinds := make(map[schema.GroupVersionResource]string)
+	for kind := range KindPluralMap {
+		plural, _, version, group := getKindAPIDetails(kind)
+		listKinds[schema.GroupVersionResource{Group: group, Version: version, Resource: plural}] = kind + "List"
+	}
+	fakeClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), listKinds, runtimeObjects...)
+	cfg, dynamicClient = &rest.Config{}, fakeClient
+	t.Cleanup(func() {
+		cfg, dynamicClient = savedCfg, savedClient
+		kubeObjectCache, kubeObjectListCache = savedObjectCache, savedListCache
+	})
+	return fakeClient
+}
+
+func ancestryDepth(ancestor Ancestor) int {
+	depth := 0
+	for _, owner := range ancestor.Owners {
+		if ownerDepth := ancestryDepth(owner); ownerDepth > depth {
+			depth = ownerDepth
+		}
+	}
+	return depth + 1
+}
+
+func TestGetAncestryDepthLimit(t *testing.T) {
+	objects := make([]unstructured.Unstructured, 0)
+	names := []string{"cm0", "cm1", "cm2", "cm3", "cm4", "cm5", "cm6", "cm7",
+		"cm8", "cm9", "cm10", "cm11", "cm12", "cm13", "cm14", "cm15"}
+	for i, name := range names {
+		if i+1 < len(names) {
+			objects = append(objects, newTestObject(CONFIG_MAP, name, "default", name,
+				"ConfigMap/"+names[i+1]+"/"+names[i+1]))
+		} else {
+			objects = append(objects, newTestObject(CONFIG_MAP, name, "default", name))
+		}
+	}
+	setTestDynamicClient(t, objects...)
+
+	ancestry, err := GetAncestry(CONFIG_MAP, "cm0", "default")
+	if err != nil {
+		t.Fatalf("GetAncestry: %v", err)
+	}
+	// Levels 0 to maxOwnerDepth+1; the owners of the last level are not followed.
+	if depth := ancestryDepth(ancestry); depth != maxOwnerDepth+2 {
+		t.Errorf("ancestry depth = %d, expected %d", depth, maxOwnerDepth+2)
+	}
+}
+
+func TestGetAncestryOwnerCycle(t *testing.T) {
+	setTestDynamicClient(t,
+		newTestObject(CONFIG_MAP, "a", "default", "a1", "Secret/b/b1"),
+		newTestObject(SECRET, "b", "default", "b1", "ConfigMap/a/a1"),
+	)
+
+	ancestry, err := GetAncestry(CONFIG_MAP, "a", "default")
+	if err != nil {
+		t.Fatalf("GetAncestry: %v", err)
+	}
+	if len(ancestry.Owners) != 1 || ancestry.Owners[0].Kind != SECRET {
+		t.Fatalf("owners of ConfigMap/a = %+v, expected Secret/b", ancestry.Owners)
+	}
+	secret := ancestry.Owners[0]
+	if len(secret.Owners) != 1 || secret.Owners[0].UID != "a1" {
+		t.Fatalf("owners of Secret/b = %+v, expected ConfigMap/a", secret.Owners)
+	}
+	if len(secret.Owners[0].Owners) != 0 {
+		t.Errorf("owners of a visited object were followed again: %+v", secret.Owners[0].Owners)
+	}
+}
+
+func TestGetAncestryClusterScopedOwner(t *testing.T) {
+	fakeClient := setTestDynamicClient(t,
+		newTestObject(POD, "kube-proxy-node1", "kube-system", "p1", "Node/node1/n1"),
+		newTestObject(NODE, "node1", "", "n1"),
+	)
+
+	ancestry, err := GetAncestry(POD, "kube-proxy-node1", "kube-system")
+	if err != nil {
+		t.Fatalf("GetAncestry: %v", err)
+	}
+	if len(ancestry.Owners) != 1 {
+		t.Fatalf("owners of Pod = %+v, expected Node/node1", ancestry.Owners)
+	}
+	node := ancestry.Owners[0]
+	if node.Kind != NODE || node.UID != "n1" || node.Namespace != "" {
+		t.Errorf("owner = %+v, expected cluster-scoped Node/node1", node)
+	}
+	for _, action := range fakeClient.Actions() {
+		if action.GetResource().Resource == "nodes" && action.GetNamespace() != "" {
+			t.Errorf("Node looked up in namespace %q: %v", action.GetNamespace(), action.(k8stesting.GetAction).GetName())
+		}
+	}
+}
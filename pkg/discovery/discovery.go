@@ -35,16 +35,27 @@ var (
 func init() {
 }
 
+// Builds compositions of the resources in the given namespace.
+// An empty namespace builds compositions across all namespaces.
+// Cluster-scoped resources are always included.
 func BuildCompositionTree(namespace string) {
-	var namespaces []string
-	namespaces = append(namespaces, namespace)
-
 	err := readKindCompositionFile("")
 	if err != nil {
 		fmt.Printf("Error: %s\n", err.Error())
 		return
 	}
-	resourceKindList := getResourceKinds()
+	// Children are looked up across namespaces. List each Kind only once per build.
+	startCompositionListCache()
+	defer stopCompositionListCache()
+
+	buildAllCompositions(getResourceKinds(), namespace)
+}
+
+// Builds and stores the compositions of all the resources of the given Kinds,
+// and drops the stored compositions of the resources of these Kinds that no longer exist.
+func buildAllCompositions(resourceKindList []string, namespace string) {
+	var namespaces []string
+	namespaces = append(namespaces, namespace)
 
 	resourceInCluster := []MetaDataAndOwnerReferences{}
 	for _, resourceKind := range resourceKindList {
@@ -52,18 +63,19 @@ func BuildCompositionTree(namespace string) {
 			topLevelMetaDataOwnerRefList := getTopLevelResourceMetaData(resourceKind, namespace)
 			for _, topLevelObject := range topLevelMetaDataOwnerRefList {
 				resourceName := topLevelObject.MetaDataName
+				resourceUID := topLevelObject.UID
 				namespace := topLevelObject.Namespace
 				level := 1
 				compositionTree := []CompositionTreeNode{}
 				//fmt.Printf("ResKind:%s ResName:%s\n", resourceKind, resourceName)
-				buildCompositions(resourceKind, resourceName, namespace, level, &compositionTree)
+				buildCompositions(resourceKind, resourceName, resourceUID, level, &compositionTree)
 				//fmt.Printf("CompositionTree:%v\n", compositionTree)
 				TotalClusterCompositions.storeCompositions(topLevelObject, resourceKind, resourceName, namespace, &compositionTree)
 			}
 			for _, resource := range topLevelMetaDataOwnerRefList {
 				present := false
 				for _, res := range resourceInCluster {
-					if res.MetaDataName == resource.MetaDataName {
+					if res.UID == resource.UID {
 						present = true
 					}
 				}
@@ -73,7 +85,7 @@ func BuildCompositionTree(namespace string) {
 			}
 		}
 	}
-	TotalClusterCompositions.purgeCompositionOfDeletedItems(resourceInCluster)
+	TotalClusterCompositions.purgeCompositionOfDeletedItems(resourceKindList, resourceInCluster)
 }
 
 func ReadKinds(inputKind string) error {
@@ -82,6 +94,9 @@ func ReadKinds(inputKind string) error {
 }
 
 func readKindCompositionFile(inputKind string) error {
+	if InferCompositions {
+		defer mergeInferredCompositions()
+	}
 	filePath, ok := os.LookupEnv("KIND_COMPOSITION_FILE")
 	if ok {
 		yamlFile, err := ioutil.ReadFile(filePath)
@@ -100,9 +115,18 @@ func readKindCompositionFile(inputKind string) error {
 			composition := compositionObj.Composition
 			plural := compositionObj.Plural
 
+			kindMapsMux.Lock()
 			KindPluralMap[kind] = plural
 			kindVersionMap[kind] = endpoint
-			compositionMap[kind] = composition
+			kindMapsMux.Unlock()
+			setCompositionChildKinds(kind, composition)
+			// Relationships are given in their full form, e.g.
+			// objectref, on:INSTANCE.spec.issuerRef, value:Issuer; ClusterIssuer
+			addRelationshipRules(kind, parseRelationshipRules(kind, compositionObj.Relationships))
+			if compositionObj.ManagedBy != "" {
+				parseManagedBy(kind, compositionObj.ManagedBy)
+				addRelationshipRules(kind, []RelationshipRule{getManagedByRelationship()})
+			}
 		}
 	} else {
 		crdClient, err1 := apiextensionsclientset.NewForConfig(cfg)
@@ -114,7 +138,7 @@ func readKindCompositionFile(inputKind string) error {
 		crdList, err := crdClient.CustomResourceDefinitions().List(context.TODO(),
 																   metav1.ListOptions{})
 		if err != nil {
-			fmt.Errorf("Error:%s\n", err)
+			fmt.Printf("Error:%s\n", err)
 			return err
 		}
 		for _, crd := range crdList.Items {
@@ -124,7 +148,7 @@ func readKindCompositionFile(inputKind string) error {
 													     			 crdName, 
 																	 metav1.GetOptions{})
 			if err != nil {
-				fmt.Errorf("Error:%s\n", err)
+				fmt.Printf("Error:%s\n", err)
 				fmt.Printf("Cannot discover Custom Resource connections. But can do rest..")
 				//panic(err)
 				return err
@@ -152,34 +176,41 @@ func parseCRDAnnotions(crdObj *apiextensionsv1beta1.CustomResourceDefinition) {
 	endpoint := "apis/" + group + "/" + version
 	kind := crdObj.Spec.Names.Kind
 	plural := crdObj.Spec.Names.Plural
-	KindPluralMap[kind] = plural
-	kindVersionMap[kind] = endpoint
-	kindGroupMap[kind] = group
+	setKindAPIDetails(kind, plural, endpoint, group, crdObj.Spec.Scope == apiextensionsv1beta1.ClusterScoped)
 
 	objectMeta := crdObj.ObjectMeta
 	annotations := objectMeta.GetAnnotations()
 	//fmt.Printf("%v\n", annotations)
 	//fmt.Printf("&&&&\n")
 	compositionAnnotation := annotations[COMPOSITION_ANNOTATION]
+	if compositionAnnotation == "" && InferCompositions {
+		// Use the Kinds that are actually owned by instances of this Kind
+		compositionAnnotation = strings.Join(getInferredCompositionKinds(kind, group), ", ")
+	}
 	if compositionAnnotation == "" {
 		// Default set
 		compositionAnnotation = "Deployment, StatefulSet, DaemonSet, ReplicationController, Service, Secret, PodDisruptionBudget, ServiceAccount, PersistentVolumeClaim"
 	} 
 
 	componentKinds := strings.Split(compositionAnnotation, ",")
-	compositionMap[kind] = componentKinds
+	setCompositionChildKinds(kind, componentKinds)
 	crdcompositionMap[kind] = componentKinds
 
 	//fmt.Printf("=====\n")
 	allRels := getAllRelationships(annotations)
 	//printRels(allRels)
-	relationshipMap[kind] = allRels
+	relRules := parseRelationshipRules(kind, allRels)
+	// Custom resources are connected to the workload of their operator
+	parseManagedBy(kind, annotations[MANAGED_BY_ANNOTATION])
+	relRules = append(relRules, getManagedByRelationship())
+	setRelationshipRules(kind, relRules)
 }
 
 func getAllRelationships(annotations map[string]string) []string {
 	annotationRels := parseRels(annotations, ANNOTATION_REL_ANNOTATION, "annotation")
 	labelRels := parseRels(annotations, LABEL_REL_ANNOTATION, "label")
 	specPropertyRels := parseRels(annotations, SPECPROPERTY_REL_ANNOTATION, "specproperty")
+	objectRefRels := parseRels(annotations, OBJECTREF_REL_ANNOTATION, "objectref")
 		//	fmt.Printf(annotationRels)
 	//fmt.Printf("A\n")
 	//printRels(annotationRels)
@@ -191,6 +222,7 @@ func getAllRelationships(annotations map[string]string) []string {
 	allRels = mergeRels(allRels, specPropertyRels)
 	allRels = mergeRels(allRels, labelRels)
 	allRels = mergeRels(allRels, annotationRels)
+	allRels = mergeRels(allRels, objectRefRels)
 	return allRels
 } 
 
@@ -234,14 +266,29 @@ func parseRels(annotations map[string]string, rel, relType string) []string {
 func getResourceKinds() []string {
 	resourceKindSlice := make([]string, 0)
 	//resourceKindSlice = append(resourceKindSlice, "MysqlService")
-	for key, _ := range compositionMap {
+	compositionMapMux.RLock()
+	defer compositionMapMux.RUnlock()
+	for key := range compositionMap {
 		resourceKindSlice = append(resourceKindSlice, key)
 	}
 	return resourceKindSlice
 }
 
-func getResourceMetaData(resourceKindPlural, resourceGroup, resourceApiVersion,
-						 parentResKind, parentResName,
+func getCompositionChildKinds(kind string) ([]string, bool) {
+	compositionMapMux.RLock()
+	defer compositionMapMux.RUnlock()
+	childKinds, present := compositionMap[kind]
+	return childKinds, present
+}
+
+func setCompositionChildKinds(kind string, childKinds []string) {
+	compositionMapMux.Lock()
+	defer compositionMapMux.Unlock()
+	compositionMap[kind] = childKinds
+}
+
+func getResourceMetaData(resourceKind, resourceKindPlural, resourceGroup, resourceApiVersion,
+						 parentResUID,
 						 namespace string) []MetaDataAndOwnerReferences {
 
 	metaDataAndOwnerReferenceList := []MetaDataAndOwnerReferences{}
@@ -252,41 +299,17 @@ func getResourceMetaData(resourceKindPlural, resourceGroup, resourceApiVersion,
 		return metaDataAndOwnerReferenceList
 	}
 
-	dynamicClient, err := getDynamicClient()
-	if err != nil {
-		return metaDataAndOwnerReferenceList
-	}
-
 	res := schema.GroupVersionResource{Group: resourceGroup,
 									   Version: resourceApiVersion,
 									   Resource: resourceKindPlural}
 
-	list, err := dynamicClient.Resource(res).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+	list, err := listResources(resourceKind, namespace, res)
 	if err != nil {
 		return metaDataAndOwnerReferenceList
 	}
 
-	for _, unstructuredObj := range list.Items {
-		metaDataRef := MetaDataAndOwnerReferences{}
-		metaDataRef.OwnerReferenceKind = ""
-		metaDataRef.OwnerReferenceName = ""
-		ownerReferences := unstructuredObj.GetOwnerReferences()
-		for _, ownerReference := range ownerReferences {
-			if ownerReference.Kind == parentResKind && ownerReference.Name == parentResName {
-				metaDataRef.OwnerReferenceKind = ownerReference.Kind
-				metaDataRef.OwnerReferenceName = ownerReference.Name
-				metaDataRef.OwnerReferenceAPIVersion = ownerReference.APIVersion
-			}
-		}
-		metaDataRef.Namespace = unstructuredObj.GetNamespace()
-		metaDataRef.MetaDataName = unstructuredObj.GetName()
-
-		content := unstructuredObj.UnstructuredContent()
-		phase, found, _ := unstructured.NestedString(content, "status", "phase")
-		if found {
-			metaDataRef.Status = phase
-		}
-
+	for _, unstructuredObj := range list {
+		metaDataRef := newMetaDataAndOwnerReferences(unstructuredObj, parentResUID)
 		//if metaDataRef.OwnerReferenceKind != "" && metaDataRef.OwnerReferenceName != "" {
 			metaDataAndOwnerReferenceList = append(metaDataAndOwnerReferenceList, metaDataRef)
 		//}
@@ -295,67 +318,91 @@ func getResourceMetaData(resourceKindPlural, resourceGroup, resourceApiVersion,
 	return metaDataAndOwnerReferenceList
 }
 
+// Owners are matched by UID so that same-named parents of different kinds
+// or namespaces do not collide. Every owner reference is recorded.
+func newMetaDataAndOwnerReferences(unstructuredObj unstructured.Unstructured,
+								   parentResUID string) MetaDataAndOwnerReferences {
+	metaDataRef := MetaDataAndOwnerReferences{}
+	metaDataRef.OwnerReferenceKind = ""
+	metaDataRef.OwnerReferenceName = ""
+	metaDataRef.OwnerReferences = findOwners(unstructuredObj)
+	for _, ownerReference := range metaDataRef.OwnerReferences {
+		if parentResUID != "" && ownerReference.UID == parentResUID {
+			metaDataRef.OwnerReferenceKind = ownerReference.Kind
+			metaDataRef.OwnerReferenceName = ownerReference.Name
+			metaDataRef.OwnerReferenceAPIVersion = ownerReference.APIVersion
+			metaDataRef.OwnerReferenceUID = ownerReference.UID
+		}
+	}
+	metaDataRef.Namespace = unstructuredObj.GetNamespace()
+	metaDataRef.MetaDataName = unstructuredObj.GetName()
+	metaDataRef.UID = string(unstructuredObj.GetUID())
+
+	content := unstructuredObj.UnstructuredContent()
+	phase, found, _ := unstructured.NestedString(content, "status", "phase")
+	if found {
+		metaDataRef.Status = phase
+	}
+	metaDataRef.Health = computeHealth(unstructuredObj.GetKind(), content)
+	return metaDataRef
+}
+
 func getTopLevelResourceMetaData(resourceKind, namespace string) []MetaDataAndOwnerReferences {
 	resourceKindPlural, _, resourceApiVersion, resourceGroup := getKindAPIDetails(resourceKind)
 
-	parentResKind := ""
-	parentResName := ""
-	metaDataAndOwnerReferenceList := getResourceMetaData(resourceKindPlural,
+	parentResUID := ""
+	metaDataAndOwnerReferenceList := getResourceMetaData(resourceKind,
+														 resourceKindPlural,
 														 resourceGroup,
 														 resourceApiVersion,
-														 parentResKind,
-														 parentResName,
+														 parentResUID,
 														 namespace)
 	return metaDataAndOwnerReferenceList
 }
 
-func processed(processedList *[]CompositionTreeNode, nodeToCheck CompositionTreeNode) bool {
-	var result bool = false
-	for _, compositionTreeNode1 := range *processedList {
-		if compositionTreeNode1.Level == nodeToCheck.Level && compositionTreeNode1.ChildKind == nodeToCheck.ChildKind {
-			result = true
-		}
+// Groups the nodes of a composition tree by the UID of their parent.
+// A child owned by several resources of the tree is listed under each of them.
+func indexCompositionTree(compositionTree *[]CompositionTreeNode) map[string][]CompositionTreeNode {
+	childrenIndex := make(map[string][]CompositionTreeNode)
+	if compositionTree == nil {
+		return childrenIndex
 	}
-	return result
+	for _, compositionTreeNode := range *compositionTree {
+		parentUID := compositionTreeNode.ParentUID
+		childrenIndex[parentUID] = append(childrenIndex[parentUID], compositionTreeNode)
+	}
+	return childrenIndex
 }
 
-func getComposition(kind, name, namespace, status string, level int, compositionTree *[]CompositionTreeNode,
-	processedList *[]CompositionTreeNode) Composition {
+// Children of a resource are the nodes recorded under its UID. The visited set holds
+// the UIDs on the current path so that an ownership cycle does not recurse forever.
+func getComposition(kind, name, namespace, uid, status string, health Health, ownerReferences []OwnerReference, level int,
+	childrenIndex map[string][]CompositionTreeNode, visited map[string]bool) Composition {
 	parentComposition := Composition{}
 	parentComposition.Level = level
 	parentComposition.Kind = kind
 	parentComposition.Name = name
 	parentComposition.Namespace = namespace
+	parentComposition.UID = uid
 	parentComposition.Status = status
+	parentComposition.OwnerReferences = ownerReferences
 	parentComposition.Children = []Composition{}
 
-	for _, compositionTreeNode := range *compositionTree {
-		if processed(processedList, compositionTreeNode) {
-			continue
-		}
-		level := compositionTreeNode.Level
-		childKind := compositionTreeNode.ChildKind
-		metaDataAndOwnerReferences := compositionTreeNode.Children
-
-		for _, metaDataNode := range metaDataAndOwnerReferences {
-			//compositionsString = compositionsString + " " + string(level) + " " + childKind + " " + childName + "\n"
-			childName := metaDataNode.MetaDataName
-			childNamespace := metaDataNode.Namespace
-
-			// childNamespace := metaDataNode.MetaDataNamespace
-			childStatus := metaDataNode.Status
-			trimmedTree := []CompositionTreeNode{}
-			for _, compositionTreeNode1 := range *compositionTree {
-				if compositionTreeNode1.Level != level && compositionTreeNode1.ChildKind != childKind {
-					trimmedTree = append(trimmedTree, compositionTreeNode1)
-				}
+	if uid != "" && !visited[uid] {
+		visited[uid] = true
+		for _, compositionTreeNode := range childrenIndex[uid] {
+			level := compositionTreeNode.Level
+			childKind := compositionTreeNode.ChildKind
+			for _, metaDataNode := range compositionTreeNode.Children {
+				child := getComposition(childKind, metaDataNode.MetaDataName, metaDataNode.Namespace, metaDataNode.UID,
+										metaDataNode.Status, metaDataNode.Health, metaDataNode.OwnerReferences, level,
+										childrenIndex, visited)
+				parentComposition.Children = append(parentComposition.Children, child)
 			}
-			*processedList = append(*processedList, compositionTreeNode)
-			child := getComposition(childKind, childName, childNamespace, childStatus, level, &trimmedTree, processedList)
-			parentComposition.Children = append(parentComposition.Children, child)
-			compositionTree = &[]CompositionTreeNode{}
 		}
+		delete(visited, uid)
 	}
+	parentComposition.Health = aggregateHealth(health, parentComposition.Children)
 	return parentComposition
 }
 
@@ -365,14 +412,17 @@ func (cp *ClusterCompositions) GetCompositions(resourceKind, resourceName, names
 	//var compositionBytes []byte
 	//var compositionString string
 	compositions := []Composition{}
-	resourceKindPlural := KindPluralMap[resourceKind]
+	resourceKindPlural, _, _, _ := getKindAPIDetails(resourceKind)
 	//fmt.Println("Compositions of different Kinds in this Cluster")
 	//fmt.Printf("Kind:%s, Name:%s\n", resourceKindPlural, resourceName)
 	for _, compositionItem := range cp.clusterCompositions {
 		kind := strings.ToLower(compositionItem.Kind)
 		name := strings.ToLower(compositionItem.Name)
 		nmspace := strings.ToLower(compositionItem.Namespace)
+		uid := compositionItem.UID
 		status := compositionItem.Status
+		health := compositionItem.Health
+		ownerReferences := compositionItem.OwnerReferences
 		compositionTree := compositionItem.CompositionTree
 		resourceKindPlural := strings.ToLower(resourceKindPlural)
 		//TODO(devdattakulkarni): Make route registration and compositions keyed info
@@ -380,30 +430,36 @@ func (cp *ClusterCompositions) GetCompositions(resourceKind, resourceName, names
 		//singular kind names. For now, trimming the 's' at the end
 		//resourceKind = strings.TrimSuffix(resourceKind, "s")
 		var resourceKind string
+		kindMapsMux.RLock()
 		for key, value := range KindPluralMap {
 			if strings.ToLower(value) == strings.ToLower(resourceKindPlural) {
 				resourceKind = strings.ToLower(key)
 				break
 			}
 		}
+		kindMapsMux.RUnlock()
 		resourceName := strings.ToLower(resourceName)
 		//fmt.Printf("Kind:%s, Kind:%s, Name:%s, Name:%s\n", kind, resourceKind, name, resourceName)
 
+		// An empty namespace matches compositions in all namespaces.
+		// Compositions of cluster-scoped resources match any namespace.
+		namespaceMatches := namespace == "" || nmspace == "" || namespace == nmspace
+
 		//TODO(devdattakulkarni): Below case statements look suspect as actions of two
 		//cases are similar. Can these be combined?
 		switch {
-		case namespace != nmspace:
+		case !namespaceMatches:
 			break
-		case resourceName == "*" && resourceKind == kind && namespace == nmspace:
-			processedList := []CompositionTreeNode{}
+		case resourceName == "*" && resourceKind == kind:
 			level := 1
-			composition := getComposition(kind, name, namespace, status, level, compositionTree, &processedList)
+			composition := getComposition(kind, name, compositionItem.Namespace, uid, status, health, ownerReferences, level,
+										  indexCompositionTree(compositionTree), map[string]bool{})
 			compositions = append(compositions, composition)
 			break
-		case resourceName == name && resourceKind == kind && namespace == nmspace:
-			processedList := []CompositionTreeNode{}
+		case resourceName == name && resourceKind == kind:
 			level := 1
-			composition := getComposition(kind, name, namespace, status, level, compositionTree, &processedList)
+			composition := getComposition(kind, name, compositionItem.Namespace, uid, status, health, ownerReferences, level,
+										  indexCompositionTree(compositionTree), map[string]bool{})
 			compositions = append(compositions, composition)
 			break
 		}
@@ -425,17 +481,48 @@ func (cp *ClusterCompositions) GetCompositionsString(resourceKind, resourceName,
 	return compositionString
 }
 
-func (cp *ClusterCompositions) purgeCompositionOfDeletedItems(topLevelMetaDataOwnerRefList []MetaDataAndOwnerReferences) {
+// Renders the compositions as a dot or mermaid graph.
+func (cp *ClusterCompositions) GetCompositionsGraphString(resourceKind, resourceName, namespace, format string) string {
+	compositions := cp.GetCompositions(resourceKind,
+									resourceName,
+									namespace)
+	nodes, edges := compositionsGraph(compositions)
+	return getGraphString(format, nodes, edges)
+}
+
+// Compositions of other Kinds than the given ones are kept.
+func (cp *ClusterCompositions) purgeCompositionOfDeletedItems(resourceKindList []string,
+	topLevelMetaDataOwnerRefList []MetaDataAndOwnerReferences) {
+	cp.mux.Lock()
+	defer cp.mux.Unlock()
 	presentList := []Compositions{}
 	//fmt.Println("ClusterCompositions:%v\n", cp.clusterCompositions)
 	//fmt.Println("ToplevelMetaDataOwnerList:%v\n", topLevelMetaDataOwnerRefList)
+	presentUIDs := make(map[string]bool, len(topLevelMetaDataOwnerRefList))
+	for _, topLevelObject := range topLevelMetaDataOwnerRefList {
+		presentUIDs[topLevelObject.UID] = true
+	}
 	for _, compositionItem := range cp.clusterCompositions {
-		for _, topLevelObject := range topLevelMetaDataOwnerRefList {
-			resourceName := topLevelObject.MetaDataName
-			if resourceName == compositionItem.Name {
-				presentList = append(presentList, compositionItem)
-			}
+		if presentUIDs[compositionItem.UID] || !containsString(resourceKindList, compositionItem.Kind) {
+			presentList = append(presentList, compositionItem)
+		}
+	}
+	cp.clusterCompositions = presentList
+}
+
+// Removes the stored Compositions of a deleted resource.
+// Used by the composition index instead of purgeCompositionOfDeletedItems.
+// Compositions are matched by UID, as the delete event of a resource can arrive
+// after a new resource with the same name has been added.
+func (cp *ClusterCompositions) removeCompositions(resourceUID string) {
+	cp.mux.Lock()
+	defer cp.mux.Unlock()
+	presentList := []Compositions{}
+	for _, comp := range cp.clusterCompositions {
+		if comp.UID == resourceUID {
+			continue
 		}
+		presentList = append(presentList, comp)
 	}
 	cp.clusterCompositions = presentList
 }
@@ -451,7 +538,10 @@ func (cp *ClusterCompositions) storeCompositions(topLevelObject MetaDataAndOwner
 		Kind:            resourceKind,
 		Name:            resourceName,
 		Namespace:       namespace,
+		UID:             topLevelObject.UID,
 		Status:          topLevelObject.Status,
+		Health:          topLevelObject.Health,
+		OwnerReferences: topLevelObject.OwnerReferences,
 		CompositionTree: compositionTree,
 	}
 	present := false
@@ -462,7 +552,10 @@ func (cp *ClusterCompositions) storeCompositions(topLevelObject MetaDataAndOwner
 			p := &comp
 			//fmt.Printf("CompositionTree:%v\n", compositionTree)
 			p.CompositionTree = compositionTree
+			p.UID = topLevelObject.UID
 			p.Status = topLevelObject.Status
+			p.Health = topLevelObject.Health
+			p.OwnerReferences = topLevelObject.OwnerReferences
 			cp.clusterCompositions[i] = *p
 			//fmt.Printf("11 CP:%v\n", cp.clusterCompositions)
 		}
@@ -472,9 +565,11 @@ func (cp *ClusterCompositions) storeCompositions(topLevelObject MetaDataAndOwner
 	}
 }
 
-func buildCompositions(parentResourceKind string, parentResourceName string, parentNamespace string, level int,
+// Children are discovered by the owner UID regardless of their namespace,
+// so that cluster-scoped children and children in other namespaces are found.
+func buildCompositions(parentResourceKind, parentResourceName, parentResourceUID string, level int,
 	compositionTree *[]CompositionTreeNode) {
-	childResourceKindList, present := compositionMap[parentResourceKind]
+	childResourceKindList, present := getCompositionChildKinds(parentResourceKind)
 	if present {
 		level = level + 1
 
@@ -485,16 +580,20 @@ func buildCompositions(parentResourceKind string, parentResourceName string, par
 			//var content []byte
 			var metaDataAndOwnerReferenceList []MetaDataAndOwnerReferences
 
-			metaDataAndOwnerReferenceList = getResourceMetaData(childKindPlural,
+			metaDataAndOwnerReferenceList = getResourceMetaData(childResourceKind,
+																childKindPlural,
 																childResourceGroup,
 																childResourceApiVersion,
-																parentResourceKind,
-																parentResourceName,
-																parentNamespace)
+																parentResourceUID,
+																metav1.NamespaceAll)
 
-			childrenList := filterChildren(&metaDataAndOwnerReferenceList, parentResourceName)
+			childrenList := filterChildren(&metaDataAndOwnerReferenceList, parentResourceUID)
+			if parentResourceKind == STATEFULSET && childResourceKind == PVCLAIM {
+				childrenList = appendClaimTemplateChildren(childrenList, &metaDataAndOwnerReferenceList, parentResourceName, parentResourceUID)
+			}
 			compTreeNode := CompositionTreeNode{
 				Level:     level,
+				ParentUID: parentResourceUID,
 				ChildKind: childResourceKind,
 				Children:  childrenList,
 			}
@@ -503,8 +602,13 @@ func buildCompositions(parentResourceKind string, parentResourceName string, par
 
 			for _, metaDataRef := range childrenList {
 				resourceName := metaDataRef.MetaDataName
+				resourceUID := metaDataRef.UID
 				resourceKind := childResourceKind
-				buildCompositions(resourceKind, resourceName, parentNamespace, level, compositionTree)
+				// A child with several owners in this tree is expanded only once.
+				if compositionTreeHasParent(compositionTree, resourceUID) {
+					continue
+				}
+				buildCompositions(resourceKind, resourceName, resourceUID, level, compositionTree)
 			}
 		}
 	} else {
@@ -512,6 +616,15 @@ func buildCompositions(parentResourceKind string, parentResourceName string, par
 	}
 }
 
+func compositionTreeHasParent(compositionTree *[]CompositionTreeNode, parentUID string) bool {
+	for _, compositionTreeNode := range *compositionTree {
+		if compositionTreeNode.ParentUID == parentUID {
+			return true
+		}
+	}
+	return false
+}
+
 func getAllNamespaces() []string {
 	var url1 string
 	url1 = fmt.Sprintf("https://%s:%s/%s/namespaces", serviceHost, servicePort, "api/v1")
@@ -521,8 +634,7 @@ func getAllNamespaces() []string {
 }
 
 func (cp *ClusterCompositions) QueryResource(resourceKind, resourceName, namespace string) []byte {
-	resourceApiVersion := kindVersionMap[resourceKind]
-	resourceKindPlural := KindPluralMap[resourceKind]
+	resourceKindPlural, resourceApiVersion, _, _ := getKindAPIDetails(resourceKind)
 	url1 := fmt.Sprintf("https://%s:%s/%s/namespaces/%s/%s/%s", serviceHost, servicePort, resourceApiVersion, namespace, resourceKindPlural, resourceName)
 	fmt.Printf("Resource Query URL:%s\n", url1)
 	contents := queryAPIServer(url1)
@@ -580,14 +692,14 @@ func queryAPIServer(url1 string) []byte {
 	return resp_body
 }
 
-func filterChildren(metaDataSlice *[]MetaDataAndOwnerReferences, parentResourceName string) []MetaDataAndOwnerReferences {
+func filterChildren(metaDataSlice *[]MetaDataAndOwnerReferences, parentResourceUID string) []MetaDataAndOwnerReferences {
 	metaDataSliceToReturn := []MetaDataAndOwnerReferences{}
 	for _, metaDataRef := range *metaDataSlice {
-		if metaDataRef.OwnerReferenceName == parentResourceName {
+		if parentResourceUID != "" && metaDataRef.OwnerReferenceUID == parentResourceUID {
 			// Prevent duplicates
 			present := false
 			for _, node := range metaDataSliceToReturn {
-				if node.MetaDataName == metaDataRef.MetaDataName {
+				if node.UID == metaDataRef.UID {
 					present = true
 				}
 			}
@@ -599,6 +711,47 @@ func filterChildren(metaDataSlice *[]MetaDataAndOwnerReferences, parentResourceN
 	return metaDataSliceToReturn
 }
 
+// PVCs created from the volumeClaimTemplates of a StatefulSet are not owned by it.
+// They are named <template>-<statefulset>-<ordinal> in the namespace of the StatefulSet.
+func appendClaimTemplateChildren(childrenList []MetaDataAndOwnerReferences, metaDataSlice *[]MetaDataAndOwnerReferences,
+								 parentResourceName, parentResourceUID string) []MetaDataAndOwnerReferences {
+	ssetKindPlural, _, ssetApiVersion, ssetGroup := getKindAPIDetails(STATEFULSET)
+	res := schema.GroupVersionResource{Group: ssetGroup,
+									   Version: ssetApiVersion,
+									   Resource: ssetKindPlural}
+	ssetList, err := listResources(STATEFULSET, metav1.NamespaceAll, res)
+	if err != nil {
+		return childrenList
+	}
+	for _, sset := range ssetList {
+		if string(sset.GetUID()) != parentResourceUID {
+			continue
+		}
+		templates := getFieldPathValues(sset.UnstructuredContent(), "spec.volumeClaimTemplates.metadata.name")
+		for _, metaDataRef := range *metaDataSlice {
+			if metaDataRef.Namespace != sset.GetNamespace() {
+				continue
+			}
+			present := false
+			for _, node := range childrenList {
+				if node.UID == metaDataRef.UID {
+					present = true
+				}
+			}
+			if present {
+				continue
+			}
+			for _, template := range templates {
+				if specPropertyValueMatches(template, []string{metaDataRef.MetaDataName}, parentResourceName, matchModeClaimTemplate) {
+					childrenList = append(childrenList, metaDataRef)
+					break
+				}
+			}
+		}
+	}
+	return childrenList
+}
+
 func parseNamespacesResponse(content []byte) []string {
 	var result map[string]interface{}
 	json.Unmarshal([]byte(content), &result)
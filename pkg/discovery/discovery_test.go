@@ -0,0 +1,456 @@
+package discovery
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Owners are given as "Kind/name/uid".
+func newTestObject(kind, name, namespace, uid string, owners ...string) unstructured.Unstructured {
+	obj := unstructured.Unstructured{Object: map[string]interface{}{}}
+	obj.SetKind(kind)
+	obj.SetName(name)
+	obj.SetNamespace(namespace)
+	obj.SetUID(types.UID(uid))
+	ownerReferences := make([]metav1.OwnerReference, 0)
+	for _, owner := range owners {
+		parts := strings.Split(owner, "/")
+		ownerReferences = append(ownerReferences, metav1.OwnerReference{
+			APIVersion: "v1",
+			Kind:       parts[0],
+			Name:       parts[1],
+			UID:        types.UID(parts[2]),
+		})
+	}
+	if len(ownerReferences) > 0 {
+		obj.SetOwnerReferences(ownerReferences)
+	}
+	return obj
+}
+
+// Serves the lists done while building compositions from the given objects, so that
+// no API server is needed. Kinds without objects have empty lists.
+func setTestCompositionLists(t *testing.T, objects ...unstructured.Unstructured) {
+	startCompositionListCache()
+	t.Cleanup(stopCompositionListCache)
+	for kind := range KindPluralMap {
+		compositionListCache[kind+"/"] = []unstructured.Unstructured{}
+	}
+	for _, obj := range objects {
+		cacheKey := obj.GetKind() + "/"
+		compositionListCache[cacheKey] = append(compositionListCache[cacheKey], obj)
+	}
+}
+
+// Overrides the compositions of the given kinds for the duration of a test.
+func setTestCompositions(t *testing.T, compositions map[string][]string) {
+	saved := make(map[string][]string)
+	present := make(map[string]bool)
+	for kind, childKinds := range compositions {
+		saved[kind], present[kind] = compositionMap[kind]
+		compositionMap[kind] = childKinds
+	}
+	t.Cleanup(func() {
+		for kind := range compositions {
+			if present[kind] {
+				compositionMap[kind] = saved[kind]
+			} else {
+				delete(compositionMap, kind)
+			}
+		}
+	})
+}
+
+func buildTestComposition(kind string, obj unstructured.Unstructured) (Composition, []CompositionTreeNode) {
+	topLevelObject := newMetaDataAndOwnerReferences(obj, "")
+	compositionTree := []CompositionTreeNode{}
+	buildCompositions(kind, obj.GetName(), topLevelObject.UID, 1, &compositionTree)
+	composition := getComposition(kind, obj.GetName(), obj.GetNamespace(), topLevelObject.UID, topLevelObject.Status,
+		topLevelObject.Health, topLevelObject.OwnerReferences, 1, indexCompositionTree(&compositionTree), map[string]bool{})
+	return composition, compositionTree
+}
+
+// Compositions as Kind/name[children...]
+func describeComposition(composition Composition) string {
+	description := composition.Kind + "/" + composition.Name
+	if len(composition.Children) == 0 {
+		return description
+	}
+	children := make([]string, 0)
+	for _, child := range composition.Children {
+		children = append(children, describeComposition(child))
+	}
+	return description + "[" + strings.Join(children, " ") + "]"
+}
+
+func TestBuildCompositions(t *testing.T) {
+	statefulSet := newTestObject(STATEFULSET, "db", "default", "s1")
+	_ = unstructured.SetNestedSlice(statefulSet.Object, []interface{}{
+		map[string]interface{}{"metadata": map[string]interface{}{"name": "data"}},
+	}, "spec", "volumeClaimTemplates")
+
+	testCases := []struct {
+		name         string
+		kind         string
+		top          unstructured.Unstructured
+		compositions map[string][]string
+		objects      []unstructured.Unstructured
+		expected     string
+	}{
+		{
+			name: "Deployment to ReplicaSet to Pod",
+			kind: DEPLOYMENT,
+			top:  newTestObject(DEPLOYMENT, "web", "default", "d1"),
+			objects: []unstructured.Unstructured{
+				newTestObject(REPLICA_SET, "web-1", "default", "r1", "Deployment/web/d1"),
+				// Same name in another namespace, owned by another Deployment
+				newTestObject(REPLICA_SET, "web-1", "other", "r9", "Deployment/web/d9"),
+				newTestObject(POD, "web-1-a", "default", "p1", "ReplicaSet/web-1/r1"),
+				newTestObject(POD, "web-1-b", "default", "p2", "ReplicaSet/web-1/r1"),
+				newTestObject(POD, "web-1-a", "other", "p9", "ReplicaSet/web-1/r9"),
+			},
+			expected: "Deployment/web[ReplicaSet/web-1[Pod/web-1-a Pod/web-1-b]]",
+		},
+		{
+			name: "StatefulSet with Pods, ControllerRevisions and claim template PVCs",
+			kind: STATEFULSET,
+			top:  statefulSet,
+			objects: []unstructured.Unstructured{
+				statefulSet,
+				newTestObject(POD, "db-0", "default", "p1", "StatefulSet/db/s1"),
+				newTestObject(POD, "db-1", "default", "p2", "StatefulSet/db/s1"),
+				newTestObject(CONTROLLER_REVISION, "db-7d4b9c", "default", "c1", "StatefulSet/db/s1"),
+				// Owned with a persistentVolumeClaimRetentionPolicy
+				newTestObject(PVCLAIM, "scratch-db-0", "default", "v1", "StatefulSet/db/s1"),
+				newTestObject(PVCLAIM, "data-db-0", "default", "v2"),
+				newTestObject(PVCLAIM, "data-db-1", "default", "v3"),
+				newTestObject(PVCLAIM, "data-db-0", "other", "v4"),
+				newTestObject(PVCLAIM, "data-dbx-0", "default", "v5"),
+				newTestObject(PVCLAIM, "logs-db-0", "default", "v6"),
+				newTestObject(PVCLAIM, "data-db-backup", "default", "v7"),
+			},
+			expected: "StatefulSet/db[Pod/db-0 Pod/db-1 ControllerRevision/db-7d4b9c " +
+				"PersistentVolumeClaim/scratch-db-0 PersistentVolumeClaim/data-db-0 PersistentVolumeClaim/data-db-1]",
+		},
+		{
+			name: "CronJob to Job to Pod",
+			kind: CRONJOB,
+			top:  newTestObject(CRONJOB, "backup", "default", "cj1"),
+			objects: []unstructured.Unstructured{
+				newTestObject(JOB, "backup-28000", "default", "j1", "CronJob/backup/cj1"),
+				newTestObject(JOB, "backup-28005", "default", "j2", "CronJob/backup/cj1"),
+				newTestObject(JOB, "migrate", "default", "j3"),
+				newTestObject(POD, "backup-28000-a", "default", "p1", "Job/backup-28000/j1"),
+				newTestObject(POD, "backup-28005-a", "default", "p2", "Job/backup-28005/j2"),
+				newTestObject(POD, "backup-28005-b", "default", "p3", "Job/backup-28005/j2"),
+				newTestObject(POD, "migrate-a", "default", "p4", "Job/migrate/j3"),
+			},
+			expected: "CronJob/backup[Job/backup-28000[Pod/backup-28000-a] " +
+				"Job/backup-28005[Pod/backup-28005-a Pod/backup-28005-b]]",
+		},
+		{
+			name: "DaemonSet with Pods and ControllerRevisions",
+			kind: DAEMONSET,
+			top:  newTestObject(DAEMONSET, "agent", "default", "ds1"),
+			objects: []unstructured.Unstructured{
+				newTestObject(POD, "agent-x1", "default", "p1", "DaemonSet/agent/ds1"),
+				newTestObject(CONTROLLER_REVISION, "agent-5f6c", "default", "c1", "DaemonSet/agent/ds1"),
+				newTestObject(CONTROLLER_REVISION, "agent-7a8b", "default", "c2", "DaemonSet/agent/ds1"),
+				newTestObject(CONTROLLER_REVISION, "db-7d4b9c", "default", "c3", "StatefulSet/db/s1"),
+			},
+			expected: "DaemonSet/agent[Pod/agent-x1 ControllerRevision/agent-5f6c ControllerRevision/agent-7a8b]",
+		},
+		{
+			name:         "custom resource with several child kinds",
+			kind:         "Moodle",
+			top:          newTestObject("Moodle", "moodle1", "default", "m1"),
+			compositions: map[string][]string{"Moodle": {DEPLOYMENT, SERVICE, SECRET}},
+			objects: []unstructured.Unstructured{
+				newTestObject(DEPLOYMENT, "moodle1", "default", "d1", "Moodle/moodle1/m1"),
+				newTestObject(REPLICA_SET, "moodle1-1", "default", "r1", "Deployment/moodle1/d1"),
+				newTestObject(POD, "moodle1-1-a", "default", "p1", "ReplicaSet/moodle1-1/r1"),
+				newTestObject(SERVICE, "moodle1", "default", "sv1", "Moodle/moodle1/m1"),
+				newTestObject(SECRET, "moodle1-admin", "default", "se1", "Moodle/moodle1/m1"),
+				newTestObject(SECRET, "unrelated", "default", "se2"),
+			},
+			expected: "Moodle/moodle1[Deployment/moodle1[ReplicaSet/moodle1-1[Pod/moodle1-1-a]] " +
+				"Service/moodle1 Secret/moodle1-admin]",
+		},
+		{
+			name:         "child with two owners",
+			kind:         "Moodle",
+			top:          newTestObject("Moodle", "moodle1", "default", "m1"),
+			compositions: map[string][]string{"Moodle": {DEPLOYMENT, REPLICA_SET}},
+			objects: []unstructured.Unstructured{
+				newTestObject(DEPLOYMENT, "moodle1", "default", "d1", "Moodle/moodle1/m1"),
+				newTestObject(REPLICA_SET, "moodle1-1", "default", "r1", "Deployment/moodle1/d1", "Moodle/moodle1/m1"),
+				newTestObject(POD, "moodle1-1-a", "default", "p1", "ReplicaSet/moodle1-1/r1"),
+			},
+			expected: "Moodle/moodle1[Deployment/moodle1[ReplicaSet/moodle1-1[Pod/moodle1-1-a]] " +
+				"ReplicaSet/moodle1-1[Pod/moodle1-1-a]]",
+		},
+		{
+			name:         "owner cycle",
+			kind:         CONFIG_MAP,
+			top:          newTestObject(CONFIG_MAP, "a", "default", "a1", "Secret/b/b1"),
+			compositions: map[string][]string{CONFIG_MAP: {SECRET}, SECRET: {CONFIG_MAP}},
+			objects: []unstructured.Unstructured{
+				newTestObject(CONFIG_MAP, "a", "default", "a1", "Secret/b/b1"),
+				newTestObject(SECRET, "b", "default", "b1", "ConfigMap/a/a1"),
+			},
+			expected: "ConfigMap/a[Secret/b[ConfigMap/a]]",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			setTestCompositions(t, testCase.compositions)
+			setTestCompositionLists(t, testCase.objects...)
+			composition, compositionTree := buildTestComposition(testCase.kind, testCase.top)
+			if description := describeComposition(composition); description != testCase.expected {
+				t.Errorf("composition is %s, expected %s", description, testCase.expected)
+			}
+			// Every resource of the tree is expanded only once
+			expanded := make(map[string]bool)
+			for _, compositionTreeNode := range compositionTree {
+				key := compositionTreeNode.ParentUID + "/" + compositionTreeNode.ChildKind
+				if expanded[key] {
+					t.Errorf("children of kind %s of %s are listed more than once", compositionTreeNode.ChildKind, compositionTreeNode.ParentUID)
+				}
+				expanded[key] = true
+			}
+		})
+	}
+}
+
+func TestGetComposition(t *testing.T) {
+	child := func(name, uid string) MetaDataAndOwnerReferences {
+		return MetaDataAndOwnerReferences{MetaDataName: name, UID: uid, Namespace: "default"}
+	}
+	testCases := []struct {
+		name            string
+		kind            string
+		uid             string
+		compositionTree []CompositionTreeNode
+		expected        string
+	}{
+		{
+			name: "Deployment to ReplicaSet to Pod",
+			kind: DEPLOYMENT,
+			uid:  "d1",
+			compositionTree: []CompositionTreeNode{
+				{Level: 2, ParentUID: "d1", ChildKind: REPLICA_SET, Children: []MetaDataAndOwnerReferences{child("web-1", "r1")}},
+				{Level: 3, ParentUID: "r1", ChildKind: POD, Children: []MetaDataAndOwnerReferences{child("web-1-a", "p1"), child("web-1-b", "p2")}},
+			},
+			expected: "Deployment/top[ReplicaSet/web-1[Pod/web-1-a Pod/web-1-b]]",
+		},
+		{
+			name: "StatefulSet with several child kinds",
+			kind: STATEFULSET,
+			uid:  "s1",
+			compositionTree: []CompositionTreeNode{
+				{Level: 2, ParentUID: "s1", ChildKind: POD, Children: []MetaDataAndOwnerReferences{child("db-0", "p1")}},
+				{Level: 2, ParentUID: "s1", ChildKind: CONTROLLER_REVISION, Children: []MetaDataAndOwnerReferences{child("db-7d4b9c", "c1")}},
+				{Level: 2, ParentUID: "s1", ChildKind: PVCLAIM, Children: []MetaDataAndOwnerReferences{child("data-db-0", "v1")}},
+			},
+			expected: "StatefulSet/top[Pod/db-0 ControllerRevision/db-7d4b9c PersistentVolumeClaim/data-db-0]",
+		},
+		{
+			name: "child with two owners",
+			kind: "Moodle",
+			uid:  "m1",
+			compositionTree: []CompositionTreeNode{
+				{Level: 2, ParentUID: "m1", ChildKind: DEPLOYMENT, Children: []MetaDataAndOwnerReferences{child("moodle1", "d1")}},
+				{Level: 3, ParentUID: "d1", ChildKind: REPLICA_SET, Children: []MetaDataAndOwnerReferences{child("moodle1-1", "r1")}},
+				{Level: 2, ParentUID: "m1", ChildKind: REPLICA_SET, Children: []MetaDataAndOwnerReferences{child("moodle1-1", "r1")}},
+				{Level: 4, ParentUID: "r1", ChildKind: POD, Children: []MetaDataAndOwnerReferences{child("moodle1-1-a", "p1")}},
+			},
+			expected: "Moodle/top[Deployment/moodle1[ReplicaSet/moodle1-1[Pod/moodle1-1-a]] ReplicaSet/moodle1-1[Pod/moodle1-1-a]]",
+		},
+		{
+			name: "owner cycle",
+			kind: CONFIG_MAP,
+			uid:  "a1",
+			compositionTree: []CompositionTreeNode{
+				{Level: 2, ParentUID: "a1", ChildKind: SECRET, Children: []MetaDataAndOwnerReferences{child("b", "b1")}},
+				{Level: 3, ParentUID: "b1", ChildKind: CONFIG_MAP, Children: []MetaDataAndOwnerReferences{child("top", "a1")}},
+			},
+			expected: "ConfigMap/top[Secret/b[ConfigMap/top]]",
+		},
+		{
+			name:     "no children",
+			kind:     POD,
+			uid:      "p1",
+			expected: "Pod/top",
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			composition := getComposition(testCase.kind, "top", "default", testCase.uid, "", Health{Status: healthReady}, nil, 1,
+				indexCompositionTree(&testCase.compositionTree), map[string]bool{})
+			if description := describeComposition(composition); description != testCase.expected {
+				t.Errorf("composition is %s, expected %s", description, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestFilterChildren(t *testing.T) {
+	metaDataSlice := []MetaDataAndOwnerReferences{
+		{MetaDataName: "a", UID: "1", OwnerReferenceUID: "parent"},
+		{MetaDataName: "a", UID: "1", OwnerReferenceUID: "parent"},
+		{MetaDataName: "b", UID: "2", OwnerReferenceUID: "other"},
+		{MetaDataName: "c", UID: "3"},
+	}
+	children := filterChildren(&metaDataSlice, "parent")
+	if len(children) != 1 || children[0].UID != "1" {
+		t.Errorf("children are %v, expected only a", children)
+	}
+	if children := filterChildren(&metaDataSlice, ""); len(children) != 0 {
+		t.Errorf("children of an empty UID are %v, expected none", children)
+	}
+}
+
+// Healthy built-in workloads roll up to Ready with their ControllerRevisions,
+// claim template PVCs and finished Jobs.
+func TestCompositionHealth(t *testing.T) {
+	withStatus := func(obj unstructured.Unstructured, value interface{}, fields ...string) unstructured.Unstructured {
+		_ = unstructured.SetNestedField(obj.Object, value, fields...)
+		return obj
+	}
+	statefulSet := newTestObject(STATEFULSET, "db", "default", "s1")
+	_ = unstructured.SetNestedSlice(statefulSet.Object, []interface{}{
+		map[string]interface{}{"metadata": map[string]interface{}{"name": "data"}},
+	}, "spec", "volumeClaimTemplates")
+	_ = unstructured.SetNestedField(statefulSet.Object, int64(1), "spec", "replicas")
+	_ = unstructured.SetNestedField(statefulSet.Object, int64(1), "status", "readyReplicas")
+	job := newTestObject(JOB, "backup-28000", "default", "j1", "CronJob/backup/cj1")
+	_ = unstructured.SetNestedField(job.Object, int64(1), "status", "succeeded")
+
+	testCases := []struct {
+		name     string
+		kind     string
+		top      unstructured.Unstructured
+		objects  []unstructured.Unstructured
+		expected Health
+	}{
+		{
+			name: "StatefulSet with a bound claim template PVC",
+			kind: STATEFULSET,
+			top:  statefulSet,
+			objects: []unstructured.Unstructured{
+				statefulSet,
+				withStatus(newTestObject(POD, "db-0", "default", "p1", "StatefulSet/db/s1"), "Running", "status", "phase"),
+				newTestObject(CONTROLLER_REVISION, "db-7d4b9c", "default", "c1", "StatefulSet/db/s1"),
+				withStatus(newTestObject(PVCLAIM, "data-db-0", "default", "v1"), "Bound", "status", "phase"),
+			},
+			expected: Health{Status: healthReady, Reason: "1/1 replicas ready"},
+		},
+		{
+			name: "StatefulSet with a pending claim template PVC",
+			kind: STATEFULSET,
+			top:  statefulSet,
+			objects: []unstructured.Unstructured{
+				statefulSet,
+				withStatus(newTestObject(POD, "db-0", "default", "p1", "StatefulSet/db/s1"), "Running", "status", "phase"),
+				newTestObject(CONTROLLER_REVISION, "db-7d4b9c", "default", "c1", "StatefulSet/db/s1"),
+				withStatus(newTestObject(PVCLAIM, "data-db-0", "default", "v1"), "Pending", "status", "phase"),
+			},
+			expected: Health{Status: healthProgressing, Reason: "PersistentVolumeClaim/data-db-0: Pending"},
+		},
+		{
+			name: "DaemonSet with a ControllerRevision",
+			kind: DAEMONSET,
+			top: withStatus(withStatus(newTestObject(DAEMONSET, "agent", "default", "ds1"),
+				int64(1), "status", "desiredNumberScheduled"), int64(1), "status", "numberReady"),
+			objects: []unstructured.Unstructured{
+				withStatus(newTestObject(POD, "agent-x1", "default", "p1", "DaemonSet/agent/ds1"), "Running", "status", "phase"),
+				newTestObject(CONTROLLER_REVISION, "agent-5f6c", "default", "c1", "DaemonSet/agent/ds1"),
+			},
+			expected: Health{Status: healthReady, Reason: "1/1 pods ready"},
+		},
+		{
+			name: "CronJob with a complete Job",
+			kind: CRONJOB,
+			top:  newTestObject(CRONJOB, "backup", "default", "cj1"),
+			objects: []unstructured.Unstructured{
+				job,
+				withStatus(newTestObject(POD, "backup-28000-a", "default", "p1", "Job/backup-28000/j1"), "Succeeded", "status", "phase"),
+			},
+			expected: Health{Status: healthReady},
+		},
+		{
+			name: "CronJob with a running Job",
+			kind: CRONJOB,
+			top:  newTestObject(CRONJOB, "backup", "default", "cj1"),
+			objects: []unstructured.Unstructured{
+				withStatus(newTestObject(JOB, "backup-28005", "default", "j2", "CronJob/backup/cj1"), int64(1), "status", "active"),
+				withStatus(newTestObject(POD, "backup-28005-a", "default", "p2", "Job/backup-28005/j2"), "Running", "status", "phase"),
+			},
+			expected: Health{Status: healthProgressing, Reason: "Job/backup-28005: 1 active, 0/1 succeeded"},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			setTestCompositionLists(t, testCase.objects...)
+			topLevelObject := newMetaDataAndOwnerReferences(testCase.top, "")
+			compositionTree := []CompositionTreeNode{}
+			buildCompositions(testCase.kind, testCase.top.GetName(), topLevelObject.UID, 1, &compositionTree)
+			cp := &ClusterCompositions{}
+			cp.storeCompositions(topLevelObject, testCase.kind, testCase.top.GetName(), "default", &compositionTree)
+			compositions := cp.GetCompositions(testCase.kind, testCase.top.GetName(), "default")
+			if len(compositions) != 1 {
+				t.Fatalf("compositions are %v, expected one", compositions)
+			}
+			if compositions[0].Health != testCase.expected {
+				t.Errorf("health is %+v, expected %+v", compositions[0].Health, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestGetCompositionsString(t *testing.T) {
+	deployment := newTestObject(DEPLOYMENT, "web", "default", "d1")
+	_ = unstructured.SetNestedField(deployment.Object, int64(1), "spec", "replicas")
+	_ = unstructured.SetNestedField(deployment.Object, int64(1), "status", "readyReplicas")
+	replicaSet := newTestObject(REPLICA_SET, "web-1", "default", "r1", "Deployment/web/d1")
+	_ = unstructured.SetNestedField(replicaSet.Object, int64(1), "spec", "replicas")
+	_ = unstructured.SetNestedField(replicaSet.Object, int64(1), "status", "readyReplicas")
+	pod := newTestObject(POD, "web-1-a", "default", "p1", "ReplicaSet/web-1/r1")
+	_ = unstructured.SetNestedField(pod.Object, "Pending", "status", "phase")
+	setTestCompositionLists(t, deployment, replicaSet, pod)
+
+	topLevelObject := newMetaDataAndOwnerReferences(deployment, "")
+	compositionTree := []CompositionTreeNode{}
+	buildCompositions(DEPLOYMENT, "web", topLevelObject.UID, 1, &compositionTree)
+	cp := &ClusterCompositions{}
+	cp.storeCompositions(topLevelObject, DEPLOYMENT, "web", "default", &compositionTree)
+
+	expected := `[{"Level":1,"Kind":"deployment","Name":"web","Namespace":"default","UID":"d1","Status":"",` +
+		`"Health":{"Status":"Progressing","Reason":"ReplicaSet/web-1: Pod/web-1-a: Pending"},"OwnerReferences":[],"Children":[` +
+		`{"Level":2,"Kind":"ReplicaSet","Name":"web-1","Namespace":"default","UID":"r1","Status":"",` +
+		`"Health":{"Status":"Progressing","Reason":"Pod/web-1-a: Pending"},` +
+		`"OwnerReferences":[{"APIVersion":"v1","Kind":"Deployment","Name":"web","UID":"d1","Controller":false,"BlockOwnerDeletion":false}],` +
+		`"Children":[{"Level":3,"Kind":"Pod","Name":"web-1-a","Namespace":"default","UID":"p1","Status":"Pending",` +
+		`"Health":{"Status":"Progressing","Reason":"Pending"},` +
+		`"OwnerReferences":[{"APIVersion":"v1","Kind":"ReplicaSet","Name":"web-1","UID":"r1","Controller":false,"BlockOwnerDeletion":false}],` +
+		`"Children":[]}]}]}]`
+	compositionString := cp.GetCompositionsString(DEPLOYMENT, "web", "default")
+	if compositionString != expected {
+		t.Errorf("compositions are\n%s\nexpected\n%s", compositionString, expected)
+	}
+	var compositions []Composition
+	if err := json.Unmarshal([]byte(compositionString), &compositions); err != nil {
+		t.Fatalf("compositions are not valid JSON: %s", err.Error())
+	}
+	if len(compositions) != 1 || describeComposition(compositions[0]) != "deployment/web[ReplicaSet/web-1[Pod/web-1-a]]" {
+		t.Errorf("compositions are %v", compositions)
+	}
+	if compositions := cp.GetCompositions(DEPLOYMENT, "web", "other"); len(compositions) != 0 {
+		t.Errorf("compositions of another namespace are %v, expected none", compositions)
+	}
+}
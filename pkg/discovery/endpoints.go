@@ -0,0 +1,208 @@
+package discovery
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Service membership from EndpointSlices (Endpoints when a Service has no
+// EndpointSlices) instead of the selector of the Service. This gives the Pods
+// that actually receive traffic, with their readiness and ports, and also
+// covers Services without a selector. ExternalName Services and endpoints
+// that are not Pods are represented as external nodes.
+
+const endpointSliceServiceLabel = "kubernetes.io/service-name"
+
+// A Pod or external node that a Service sends traffic to
+type endpointTarget struct {
+	Kind    string
+	Name    string
+	Details []string
+}
+
+// EnableEndpointsRelationships replaces the selector based Service -> Pod
+// relationship by the endpoints relationship. Calling it again has no effect.
+// It is enabled with --endpoints, and with ENDPOINTS_RELATIONSHIPS=true in server mode.
+func EnableEndpointsRelationships() {
+	relationshipMapMux.Lock()
+	defer relationshipMapMux.Unlock()
+	serviceRelationships := make([]RelationshipRule, 0)
+	for _, relRule := range relationshipMap[SERVICE] {
+		if relRule.Type == relTypeLabel && relRule.hasTargetKind(POD) {
+			continue
+		}
+		if relRule.Type == relTypeEndpoints {
+			continue
+		}
+		serviceRelationships = append(serviceRelationships, relRule)
+	}
+	serviceRel := "endpoints, on:Pod; ExternalName; ExternalEndpoint"
+	serviceRelationships = append(serviceRelationships, mustParseRelationshipRule(serviceRel))
+	relationshipMap[SERVICE] = serviceRelationships
+}
+
+func searchEndpoints(level int, serviceInstance, namespace, targetKind, targetInstance string) ([]Connection, string) {
+	relativesNames := make([]Connection, 0)
+	relDetail := ""
+	for _, service := range listKindObjects(SERVICE, namespace) {
+		if serviceInstance != "*" && service.GetName() != serviceInstance {
+			continue
+		}
+		for _, target := range getServiceEndpointTargets(service) {
+			if target.Kind != targetKind || (targetInstance != "*" && target.Name != targetInstance) {
+				continue
+			}
+			relDetail = strings.Join(target.Details, "; ")
+			serviceConn := Connection{Name: service.GetName(), Kind: SERVICE, Namespace: service.GetNamespace()}
+			targetConn := Connection{Name: target.Name, Kind: target.Kind, Namespace: service.GetNamespace()}
+			if target.Kind != POD {
+				targetConn.Namespace = ""
+			}
+			conn, peer := targetConn, serviceConn
+			if serviceInstance == "*" {
+				conn, peer = serviceConn, targetConn
+			}
+			conn.Level = level
+			conn.RelationType = relTypeEndpoints
+			conn.RelationDetails = relDetail
+			conn.Peer = &peer
+			relativesNames = append(relativesNames, conn)
+		}
+	}
+	return relativesNames, relDetail
+}
+
+func getServiceEndpointTargets(service unstructured.Unstructured) []endpointTarget {
+	targets := make(map[string]*endpointTarget)
+	addTarget := func(kind, name, detail string) {
+		key := kind + "/" + name
+		target, ok := targets[key]
+		if !ok {
+			target = &endpointTarget{Kind: kind, Name: name}
+			targets[key] = target
+		}
+		if !containsString(target.Details, detail) {
+			target.Details = append(target.Details, detail)
+		}
+	}
+
+	serviceType, _, _ := unstructured.NestedString(service.UnstructuredContent(), "spec", "type")
+	if serviceType == "ExternalName" {
+		externalName, _, _ := unstructured.NestedString(service.UnstructuredContent(), "spec", "externalName")
+		if externalName != "" {
+			addTarget(EXTERNAL_NAME, externalName, "externalName")
+		}
+	}
+
+	foundSlice := false
+	for _, slice := range listKindObjects(ENDPOINT_SLICE, service.GetNamespace()) {
+		if slice.GetLabels()[endpointSliceServiceLabel] != service.GetName() {
+			continue
+		}
+		foundSlice = true
+		content := slice.UnstructuredContent()
+		ports := describeEndpointPorts(content["ports"])
+		endpoints, _, _ := unstructured.NestedSlice(content, "endpoints")
+		for _, e := range endpoints {
+			endpoint, ok := e.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			// A nil ready condition means ready
+			ready, found, _ := unstructured.NestedBool(endpoint, "conditions", "ready")
+			readiness := "ready"
+			if found && !ready {
+				readiness = "not-ready"
+			}
+			addresses, _, _ := unstructured.NestedStringSlice(endpoint, "addresses")
+			kind, name := getEndpointTarget(endpoint, addresses)
+			if name != "" {
+				addTarget(kind, name, readiness+" ports:"+ports)
+			}
+		}
+	}
+	if !foundSlice {
+		for _, endpointsObj := range listKindObjects(ENDPOINTS, service.GetNamespace()) {
+			if endpointsObj.GetName() != service.GetName() {
+				continue
+			}
+			subsets, _, _ := unstructured.NestedSlice(endpointsObj.UnstructuredContent(), "subsets")
+			for _, s := range subsets {
+				subset, ok := s.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				ports := describeEndpointPorts(subset["ports"])
+				for _, addressField := range []string{"addresses", "notReadyAddresses"} {
+					readiness := "ready"
+					if addressField == "notReadyAddresses" {
+						readiness = "not-ready"
+					}
+					addresses, _, _ := unstructured.NestedSlice(subset, addressField)
+					for _, a := range addresses {
+						address, ok := a.(map[string]interface{})
+						if !ok {
+							continue
+						}
+						ip, _, _ := unstructured.NestedString(address, "ip")
+						kind, name := getEndpointTarget(address, []string{ip})
+						if name != "" {
+							addTarget(kind, name, readiness+" ports:"+ports)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	targetList := make([]endpointTarget, 0)
+	for _, target := range targets {
+		targetList = append(targetList, *target)
+	}
+	sort.Slice(targetList, func(i, j int) bool {
+		return targetList[i].Kind+"/"+targetList[i].Name < targetList[j].Kind+"/"+targetList[j].Name
+	})
+	return targetList
+}
+
+// Endpoints with a Pod targetRef are Pods; the others (manually managed
+// endpoints) are external nodes named by their address.
+func getEndpointTarget(endpoint map[string]interface{}, addresses []string) (string, string) {
+	refKind, _, _ := unstructured.NestedString(endpoint, "targetRef", "kind")
+	refName, _, _ := unstructured.NestedString(endpoint, "targetRef", "name")
+	if refKind == POD && refName != "" {
+		return POD, refName
+	}
+	if len(addresses) > 0 && addresses[0] != "" {
+		return EXTERNAL_ENDPOINT, strings.Join(addresses, ",")
+	}
+	return "", ""
+}
+
+// Ports as name/protocol/port
+func describeEndpointPorts(value interface{}) string {
+	ports, ok := value.([]interface{})
+	if !ok || len(ports) == 0 {
+		return "none"
+	}
+	portList := make([]string, 0)
+	for _, p := range ports {
+		port, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		protocol, found, _ := unstructured.NestedString(port, "protocol")
+		if !found {
+			protocol = "TCP"
+		}
+		portString := protocol + "/" + fmt.Sprintf("%v", port["port"])
+		if name, _, _ := unstructured.NestedString(port, "name"); name != "" {
+			portString = name + "/" + portString
+		}
+		portList = append(portList, portString)
+	}
+	return strings.Join(portList, ",")
+}
@@ -0,0 +1,50 @@
+package discovery
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestEnableEndpointsRelationships(t *testing.T) {
+	saved := getRelationshipRules(SERVICE)
+	t.Cleanup(func() { setRelationshipRules(SERVICE, saved) })
+
+	// Connections can be searched while the relationship is enabled
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			EnableEndpointsRelationships()
+		}()
+		go func() {
+			defer wg.Done()
+			_ = getRelationshipRules(SERVICE)
+			_ = findRelatedKinds(POD)
+		}()
+	}
+	wg.Wait()
+
+	endpointsRules := 0
+	for _, relRule := range getRelationshipRules(SERVICE) {
+		if relRule.Type == relTypeLabel && relRule.hasTargetKind(POD) {
+			t.Errorf("selector relationship to Pods not replaced: %s", relRule.String())
+		}
+		if relRule.Type == relTypeEndpoints {
+			endpointsRules++
+		}
+	}
+	if endpointsRules != 1 {
+		t.Errorf("found %d endpoints relationships, expected 1", endpointsRules)
+	}
+	// The other relationships of Services are kept
+	expected := 1
+	for _, relRule := range saved {
+		if relRule.Type != relTypeLabel || !relRule.hasTargetKind(POD) {
+			expected++
+		}
+	}
+	if len(getRelationshipRules(SERVICE)) != expected {
+		t.Errorf("Service relationships = %v, expected %d relationships", getRelationshipRules(SERVICE), expected)
+	}
+}
@@ -0,0 +1,144 @@
+package discovery
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Field paths used in specproperty relationships, such as
+// INSTANCE.spec.containers[*].envFrom[*].secretRef.name
+// "[*]" selects all the elements of a list and "[n]" the n-th one. Lists
+// without a selector are traversed as well, so INSTANCE.spec.volumes.persistentVolumeClaim.claimName
+// gives the claimName of every volume. A segment with a malformed selector, such as
+// containers[* or containers[-1], is kept whole and matches no field.
+
+type fieldPathSegment struct {
+	Name string
+	// -1 for all the elements, -2 when no element is selected
+	Index int
+}
+
+const (
+	fieldPathAllElements = -1
+	fieldPathNoIndex     = -2
+)
+
+func trimInstancePrefix(fieldPath string) string {
+	if fieldPath == "INSTANCE" {
+		return ""
+	}
+	return strings.TrimPrefix(fieldPath, "INSTANCE.")
+}
+
+func parseFieldPath(fieldPath string) []fieldPathSegment {
+	segments := make([]fieldPathSegment, 0)
+	for _, part := range strings.Split(fieldPath, ".") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		segment := fieldPathSegment{Name: part, Index: fieldPathNoIndex}
+		open := strings.Index(part, "[")
+		if open >= 0 && strings.HasSuffix(part, "]") {
+			selector := part[open+1 : len(part)-1]
+			if selector == "*" {
+				segment.Name = part[:open]
+				segment.Index = fieldPathAllElements
+			} else if index, err := strconv.Atoi(selector); err == nil && index >= 0 {
+				segment.Name = part[:open]
+				segment.Index = index
+			}
+		}
+		segments = append(segments, segment)
+	}
+	return segments
+}
+
+// Returns all the values found at the path, in the order of the object.
+func getFieldPathValues(content interface{}, fieldPath string) []string {
+	values := make([]string, 0)
+	return collectFieldPathValues(content, parseFieldPath(fieldPath), values)
+}
+
+func collectFieldPathValues(value interface{}, segments []fieldPathSegment, values []string) []string {
+	if listValue, ok := value.([]interface{}); ok {
+		for _, element := range listValue {
+			values = collectFieldPathValues(element, segments, values)
+		}
+		return values
+	}
+	if len(segments) == 0 {
+		switch leaf := value.(type) {
+		case string:
+			values = append(values, leaf)
+		case bool, int64, float64:
+			values = append(values, fmt.Sprintf("%v", leaf))
+		}
+		return values
+	}
+	mapValue, ok := value.(map[string]interface{})
+	if !ok {
+		return values
+	}
+	segment := segments[0]
+	child, ok := mapValue[segment.Name]
+	if !ok {
+		return values
+	}
+	if segment.Index >= 0 {
+		listValue, ok := child.([]interface{})
+		if !ok || segment.Index >= len(listValue) {
+			return values
+		}
+		child = listValue[segment.Index]
+	}
+	return collectFieldPathValues(child, segments[1:], values)
+}
+
+// Returns the objects (maps) found at the path, e.g. all the object references
+// at INSTANCE.spec.scaleTargetRef.
+func getFieldPathObjects(content interface{}, fieldPath string) []map[string]interface{} {
+	objects := make([]map[string]interface{}, 0)
+	return collectFieldPathObjects(content, parseFieldPath(fieldPath), objects)
+}
+
+func collectFieldPathObjects(value interface{}, segments []fieldPathSegment, objects []map[string]interface{}) []map[string]interface{} {
+	if listValue, ok := value.([]interface{}); ok {
+		for _, element := range listValue {
+			objects = collectFieldPathObjects(element, segments, objects)
+		}
+		return objects
+	}
+	mapValue, ok := value.(map[string]interface{})
+	if !ok {
+		return objects
+	}
+	if len(segments) == 0 {
+		return append(objects, mapValue)
+	}
+	segment := segments[0]
+	child, ok := mapValue[segment.Name]
+	if !ok {
+		return objects
+	}
+	if segment.Index >= 0 {
+		listValue, ok := child.([]interface{})
+		if !ok || segment.Index >= len(listValue) {
+			return objects
+		}
+		child = listValue[segment.Index]
+	}
+	return collectFieldPathObjects(child, segments[1:], objects)
+}
+
+// Target paths ending in metadata.name (also the older spec.metadata.name form)
+// refer to the name of the target object.
+func getTargetPathValues(targetObj unstructured.Unstructured, targetPath string) []string {
+	if targetPath == "name" || strings.HasSuffix(targetPath, "metadata.name") {
+		return []string{targetObj.GetName()}
+	}
+	return getFieldPathValues(targetObj.UnstructuredContent(), trimInstancePrefix(targetPath))
+}
@@ -0,0 +1,142 @@
+package discovery
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFieldPath(t *testing.T) {
+	testCases := []struct {
+		fieldPath string
+		expected  []fieldPathSegment
+	}{
+		{
+			fieldPath: "spec.serviceAccountName",
+			expected: []fieldPathSegment{{Name: "spec", Index: fieldPathNoIndex},
+				{Name: "serviceAccountName", Index: fieldPathNoIndex}},
+		},
+		{
+			fieldPath: "spec.containers[*].env[2].value",
+			expected: []fieldPathSegment{{Name: "spec", Index: fieldPathNoIndex},
+				{Name: "containers", Index: fieldPathAllElements}, {Name: "env", Index: 2},
+				{Name: "value", Index: fieldPathNoIndex}},
+		},
+		{
+			fieldPath: " spec..volumes. ",
+			expected: []fieldPathSegment{{Name: "spec", Index: fieldPathNoIndex},
+				{Name: "volumes", Index: fieldPathNoIndex}},
+		},
+		{
+			fieldPath: "spec.containers[*",
+			expected: []fieldPathSegment{{Name: "spec", Index: fieldPathNoIndex},
+				{Name: "containers[*", Index: fieldPathNoIndex}},
+		},
+		{
+			fieldPath: "spec.containers[-1].name",
+			expected: []fieldPathSegment{{Name: "spec", Index: fieldPathNoIndex},
+				{Name: "containers[-1]", Index: fieldPathNoIndex}, {Name: "name", Index: fieldPathNoIndex}},
+		},
+		{
+			fieldPath: "[]",
+			expected:  []fieldPathSegment{{Name: "[]", Index: fieldPathNoIndex}},
+		},
+		{
+			fieldPath: "",
+			expected:  []fieldPathSegment{},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.fieldPath, func(t *testing.T) {
+			segments := parseFieldPath(testCase.fieldPath)
+			if !reflect.DeepEqual(segments, testCase.expected) {
+				t.Errorf("parseFieldPath = %+v, expected %+v", segments, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestGetFieldPathValues(t *testing.T) {
+	content := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"replicas": int64(3),
+			"paused":   false,
+			"ratio":    0.5,
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name": "app",
+							"env": []interface{}{
+								map[string]interface{}{"name": "DB_HOST", "value": "mysql.db"},
+								map[string]interface{}{"name": "DB_PORT", "value": int64(3306)},
+								map[string]interface{}{"name": "DB_PASSWORD"},
+							},
+						},
+						map[string]interface{}{
+							"name": "sidecar",
+							"env": []interface{}{
+								map[string]interface{}{"name": "PROXY", "value": "proxy:8080"},
+							},
+						},
+						"not a container",
+					},
+					"volumes": []interface{}{
+						[]interface{}{
+							map[string]interface{}{"persistentVolumeClaim": map[string]interface{}{"claimName": "nested"}},
+						},
+						map[string]interface{}{"persistentVolumeClaim": map[string]interface{}{"claimName": "data"}},
+						map[string]interface{}{"secret": map[string]interface{}{"secretName": "tls"}},
+					},
+				},
+			},
+		},
+	}
+	testCases := []struct {
+		name      string
+		fieldPath string
+		expected  []string
+	}{
+		{"string leaf", "metadata.name", []string{}},
+		{"all elements", "spec.template.spec.containers[*].name", []string{"app", "sidecar"}},
+		{"nested lists", "spec.template.spec.containers[*].env[*].value", []string{"mysql.db", "3306", "proxy:8080"}},
+		{"list without selector", "spec.template.spec.containers.env.name", []string{"DB_HOST", "DB_PORT", "DB_PASSWORD", "PROXY"}},
+		{"list element", "spec.template.spec.containers[1].name", []string{"sidecar"}},
+		{"list element out of range", "spec.template.spec.containers[7].name", []string{}},
+		{"index on a map", "spec.template[0].spec", []string{}},
+		{"list of lists", "spec.template.spec.volumes.persistentVolumeClaim.claimName", []string{"nested", "data"}},
+		{"missing field", "spec.template.spec.serviceAccountName", []string{}},
+		{"missing field in some elements", "spec.template.spec.volumes[*].secret.secretName", []string{"tls"}},
+		{"field of a string", "spec.template.spec.containers[*].name.first", []string{}},
+		{"integer leaf", "spec.replicas", []string{"3"}},
+		{"boolean leaf", "spec.paused", []string{"false"}},
+		{"float leaf", "spec.ratio", []string{"0.5"}},
+		{"map leaf", "spec.template", []string{}},
+		{"malformed selector", "spec.template.spec.containers[*", []string{}},
+		{"malformed selector in the middle", "spec.template.spec.containers[*.name", []string{}},
+		{"unterminated selector", "spec.template.spec.containers[", []string{}},
+		{"empty selector", "spec.template.spec.containers[].name", []string{}},
+		{"negative index", "spec.template.spec.containers[-1].name", []string{}},
+		{"empty path", "", []string{}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			values := getFieldPathValues(content, testCase.fieldPath)
+			if !reflect.DeepEqual(values, testCase.expected) {
+				t.Errorf("getFieldPathValues(%q) = %v, expected %v", testCase.fieldPath, values, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestGetFieldPathValuesOfUnexpectedContent(t *testing.T) {
+	for _, content := range []interface{}{nil, "spec", int64(1), []interface{}{nil, "x"}, map[string]interface{}{"spec": nil}} {
+		values := getFieldPathValues(content, "spec.containers[*].name")
+		if len(values) != 0 {
+			t.Errorf("getFieldPathValues(%v) = %v, expected no values", content, values)
+		}
+		objects := getFieldPathObjects(content, "spec.scaleTargetRef")
+		if len(objects) != 0 {
+			t.Errorf("getFieldPathObjects(%v) = %v, expected no objects", content, objects)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package discovery
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Graphviz DOT and Mermaid rendering of connections and compositions.
+// Nodes are grouped by namespace and both nodes and edges are sorted,
+// so that the same cluster state always renders to the same text.
+
+func isGraphOutput(format string) bool {
+	return format == "dot" || format == "mermaid"
+}
+
+func getGraphString(format string, nodes []graphNode, edges []graphEdge) string {
+	switch format {
+	case "dot":
+		return getDotGraph(nodes, edges)
+	case "mermaid":
+		return getMermaidGraph(nodes, edges)
+	}
+	return ""
+}
+
+// Every Connection other than the root is an edge from its Peer.
+func connectionsGraph(connections []Connection) ([]graphNode, []graphEdge) {
+	nodeSet := make(map[graphNode]bool)
+	edgeSet := make(map[graphEdge]bool)
+	for _, connection := range connections {
+		node := graphNode{Kind: connection.Kind, Name: connection.Name, Namespace: connection.Namespace}
+		nodeSet[node] = true
+		if connection.Peer == nil || connection.Peer.Kind == "" {
+			continue
+		}
+		peer := graphNode{Kind: connection.Peer.Kind, Name: connection.Peer.Name, Namespace: connection.Peer.Namespace}
+		nodeSet[peer] = true
+		label := connection.RelationType
+		if connection.RelationDetails != "" {
+			label = label + ": " + connection.RelationDetails
+		}
+		edgeSet[graphEdge{From: peer, To: node, Label: label}] = true
+	}
+	return sortGraph(nodeSet, edgeSet)
+}
+
+func compositionsGraph(compositions []Composition) ([]graphNode, []graphEdge) {
+	nodeSet := make(map[graphNode]bool)
+	edgeSet := make(map[graphEdge]bool)
+	for _, composition := range compositions {
+		addCompositionToGraph(composition, nodeSet, edgeSet)
+	}
+	return sortGraph(nodeSet, edgeSet)
+}
+
+func addCompositionToGraph(composition Composition, nodeSet map[graphNode]bool, edgeSet map[graphEdge]bool) {
+	parent := graphNode{Kind: composition.Kind, Name: composition.Name, Namespace: composition.Namespace}
+	nodeSet[parent] = true
+	for _, child := range composition.Children {
+		childNode := graphNode{Kind: child.Kind, Name: child.Name, Namespace: child.Namespace}
+		edgeSet[graphEdge{From: parent, To: childNode, Label: relTypeOwnerReference}] = true
+		addCompositionToGraph(child, nodeSet, edgeSet)
+	}
+}
+
+func sortGraph(nodeSet map[graphNode]bool, edgeSet map[graphEdge]bool) ([]graphNode, []graphEdge) {
+	nodes := make([]graphNode, 0)
+	for node := range nodeSet {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return lessGraphNode(nodes[i], nodes[j])
+	})
+	edges := make([]graphEdge, 0)
+	for edge := range edgeSet {
+		edges = append(edges, edge)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return lessGraphNode(edges[i].From, edges[j].From)
+		}
+		if edges[i].To != edges[j].To {
+			return lessGraphNode(edges[i].To, edges[j].To)
+		}
+		return edges[i].Label < edges[j].Label
+	})
+	return nodes, edges
+}
+
+func lessGraphNode(lhs, rhs graphNode) bool {
+	if lhs.Namespace != rhs.Namespace {
+		return lhs.Namespace < rhs.Namespace
+	}
+	if lhs.Kind != rhs.Kind {
+		return lhs.Kind < rhs.Kind
+	}
+	return lhs.Name < rhs.Name
+}
+
+// Nodes are sorted by namespace, so the nodes of a namespace are contiguous.
+// Cluster-scoped nodes (empty namespace) come first and are not grouped.
+func groupByNamespace(nodes []graphNode) ([]string, map[string][]graphNode) {
+	namespaces := make([]string, 0)
+	groups := make(map[string][]graphNode)
+	for _, node := range nodes {
+		if _, ok := groups[node.Namespace]; !ok {
+			namespaces = append(namespaces, node.Namespace)
+		}
+		groups[node.Namespace] = append(groups[node.Namespace], node)
+	}
+	return namespaces, groups
+}
+
+func getDotGraph(nodes []graphNode, edges []graphEdge) string {
+	ids := make(map[graphNode]string)
+	for i, node := range nodes {
+		ids[node] = fmt.Sprintf("n%d", i)
+	}
+	var sb strings.Builder
+	sb.WriteString("digraph kubediscovery {\n")
+	sb.WriteString("  node [shape=box];\n")
+	namespaces, groups := groupByNamespace(nodes)
+	for i, namespace := range namespaces {
+		indent := "  "
+		if namespace != "" {
+			sb.WriteString(fmt.Sprintf("  subgraph cluster_%d {\n", i))
+			sb.WriteString(fmt.Sprintf("    label=\"%s\";\n", dotEscape(namespace)))
+			indent = "    "
+		}
+		for _, node := range groups[namespace] {
+			sb.WriteString(fmt.Sprintf("%s%s [label=\"%s/%s\"];\n", indent, ids[node], dotEscape(node.Kind), dotEscape(node.Name)))
+		}
+		if namespace != "" {
+			sb.WriteString("  }\n")
+		}
+	}
+	for _, edge := range edges {
+		sb.WriteString(fmt.Sprintf("  %s -> %s [label=\"%s\"];\n", ids[edge.From], ids[edge.To], dotEscape(edge.Label)))
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+func getMermaidGraph(nodes []graphNode, edges []graphEdge) string {
+	ids := make(map[graphNode]string)
+	for i, node := range nodes {
+		ids[node] = fmt.Sprintf("n%d", i)
+	}
+	var sb strings.Builder
+	sb.WriteString("graph LR\n")
+	namespaces, groups := groupByNamespace(nodes)
+	for i, namespace := range namespaces {
+		indent := "  "
+		if namespace != "" {
+			sb.WriteString(fmt.Sprintf("  subgraph ns%d[\"%s\"]\n", i, mermaidEscape(namespace)))
+			indent = "    "
+		}
+		for _, node := range groups[namespace] {
+			sb.WriteString(fmt.Sprintf("%s%s[\"%s/%s\"]\n", indent, ids[node], mermaidEscape(node.Kind), mermaidEscape(node.Name)))
+		}
+		if namespace != "" {
+			sb.WriteString("  end\n")
+		}
+	}
+	for _, edge := range edges {
+		if edge.Label == "" {
+			sb.WriteString(fmt.Sprintf("  %s --> %s\n", ids[edge.From], ids[edge.To]))
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("  %s -->|\"%s\"| %s\n", ids[edge.From], mermaidEscape(edge.Label), ids[edge.To]))
+	}
+	return sb.String()
+}
+
+func dotEscape(value string) string {
+	value = strings.Replace(value, "\\", "\\\\", -1)
+	value = strings.Replace(value, "\"", "\\\"", -1)
+	return strings.Replace(value, "\n", "\\n", -1)
+}
+
+func mermaidEscape(value string) string {
+	value = strings.Replace(value, "\"", "#quot;", -1)
+	return strings.Replace(value, "\n", " ", -1)
+}
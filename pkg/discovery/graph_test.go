@@ -0,0 +1,101 @@
+package discovery
+
+import (
+	"testing"
+)
+
+func testConnections() []Connection {
+	root := &Connection{Level: 0, Kind: "Moodle", Name: "moodle1", Namespace: "default"}
+	service := &Connection{Level: 1, Kind: SERVICE, Name: "moodle1", Namespace: "default",
+		RelationType: relTypeOwnerReference, Peer: root}
+	return []Connection{
+		*root,
+		*service,
+		{Level: 1, Kind: SECRET, Name: "moodle1-admin", Namespace: "default",
+			RelationType: relTypeOwnerReference, Peer: root},
+		{Level: 2, Kind: POD, Name: "moodle1-a", Namespace: "default",
+			RelationType: "label", RelationDetails: "app=moodle1", Peer: service},
+		{Level: 1, Kind: NODE, Name: "node1",
+			RelationType: "specproperty", RelationDetails: "spec.nodeName", Peer: root},
+		{Level: 1, Kind: CONFIG_MAP, Name: "shared", Namespace: "other",
+			RelationType: "annotation", Peer: root},
+	}
+}
+
+// Permutations of the input and the iteration order of the node and edge sets
+// must not change the rendered text.
+func TestGraphOutputIsDeterministic(t *testing.T) {
+	connections := testConnections()
+	reversed := make([]Connection, 0)
+	for i := len(connections) - 1; i >= 0; i-- {
+		reversed = append(reversed, connections[i])
+	}
+	rotated := append(append([]Connection{}, connections[3:]...), connections[:3]...)
+
+	for _, format := range []string{"dot", "mermaid"} {
+		nodes, edges := connectionsGraph(connections)
+		expected := getGraphString(format, nodes, edges)
+		for i := 0; i < 20; i++ {
+			for _, input := range [][]Connection{connections, reversed, rotated} {
+				nodes, edges := connectionsGraph(input)
+				if output := getGraphString(format, nodes, edges); output != expected {
+					t.Fatalf("%s output differs between runs:\n%s\nexpected:\n%s", format, output, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestGetDotGraph(t *testing.T) {
+	nodes, edges := connectionsGraph(testConnections())
+	expected := `digraph kubediscovery {
+  node [shape=box];
+  n0 [label="Node/node1"];
+  subgraph cluster_1 {
+    label="default";
+    n1 [label="Moodle/moodle1"];
+    n2 [label="Pod/moodle1-a"];
+    n3 [label="Secret/moodle1-admin"];
+    n4 [label="Service/moodle1"];
+  }
+  subgraph cluster_2 {
+    label="other";
+    n5 [label="ConfigMap/shared"];
+  }
+  n1 -> n0 [label="specproperty: spec.nodeName"];
+  n1 -> n3 [label="owner reference"];
+  n1 -> n4 [label="owner reference"];
+  n1 -> n5 [label="annotation"];
+  n4 -> n2 [label="label: app=moodle1"];
+}
+`
+	if output := getDotGraph(nodes, edges); output != expected {
+		t.Errorf("getDotGraph =\n%s\nexpected:\n%s", output, expected)
+	}
+}
+
+func TestGetMermaidGraph(t *testing.T) {
+	composition := Composition{Kind: DEPLOYMENT, Name: "web", Namespace: "default",
+		Children: []Composition{
+			{Kind: REPLICA_SET, Name: "web-1", Namespace: "default",
+				Children: []Composition{
+					{Kind: POD, Name: "web-1-b", Namespace: "default"},
+					{Kind: POD, Name: "web-1-a", Namespace: "default"},
+				}},
+		}}
+	nodes, edges := compositionsGraph([]Composition{composition})
+	expected := `graph LR
+  subgraph ns0["default"]
+    n0["Deployment/web"]
+    n1["Pod/web-1-a"]
+    n2["Pod/web-1-b"]
+    n3["ReplicaSet/web-1"]
+  end
+  n0 -->|"owner reference"| n3
+  n3 -->|"owner reference"| n1
+  n3 -->|"owner reference"| n2
+`
+	if output := getMermaidGraph(nodes, edges); output != expected {
+		t.Errorf("getMermaidGraph =\n%s\nexpected:\n%s", output, expected)
+	}
+}
@@ -0,0 +1,314 @@
+package discovery
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Health is derived per kind from status conditions, replica counts and
+// Pod container states. Kinds without runtime state are considered Ready.
+func computeHealth(kind string, content map[string]interface{}) Health {
+	switch kind {
+	case POD:
+		return podHealth(content)
+	case DEPLOYMENT, REPLICA_SET, STATEFULSET, RC:
+		return replicaHealth(content)
+	case DAEMONSET:
+		return daemonSetHealth(content)
+	case JOB:
+		return jobHealth(content)
+	case CRONJOB:
+		return cronJobHealth(content)
+	case PDB:
+		return pdbHealth(content)
+	case HPA:
+		return autoscalerHealth(content)
+	case PVCLAIM, PV:
+		return volumeHealth(content)
+	case VOLUME_SNAPSHOT:
+		return volumeSnapshotHealth(content)
+	}
+	if !hasRuntimeState(kind) {
+		return Health{Status: healthReady}
+	}
+	if health, found := conditionsHealth(content); found {
+		return health
+	}
+	phase, found, _ := unstructured.NestedString(content, "status", "phase")
+	if found {
+		return phaseHealth(phase)
+	}
+	return Health{Status: healthUnknown, Reason: "No status conditions"}
+}
+
+// Built-in kinds whose objects take effect as they are written; they have no
+// status to wait for.
+func hasRuntimeState(kind string) bool {
+	switch kind {
+	case CONFIG_MAP, SECRET, SERVICE, SERVICE_ACCOUNT, NAMESPACE, CONTROLLER_REVISION,
+		ROLE, CLUSTER_ROLE, ROLE_BINDING, CLUSTER_ROLE_BINDING, NETWORK_POLICY,
+		ENDPOINTS, ENDPOINT_SLICE, INGRESS, INGRESS_CLASS, STORAGE_CLASS, CSI_DRIVER,
+		VOLUME_SNAPSHOT_CLASS, PRIORITY_CLASS, RUNTIME_CLASS,
+		VALIDATING_WEBHOOK_CONFIGURATION, MUTATING_WEBHOOK_CONFIGURATION:
+		return false
+	}
+	return true
+}
+
+func podHealth(content map[string]interface{}) Health {
+	phase, _, _ := unstructured.NestedString(content, "status", "phase")
+	switch phase {
+	case "Succeeded":
+		return Health{Status: healthReady, Reason: "Completed"}
+	case "Failed":
+		reason, _, _ := unstructured.NestedString(content, "status", "reason")
+		return Health{Status: healthDegraded, Reason: "Failed " + reason}
+	case "Pending", "Running":
+	default:
+		return Health{Status: healthUnknown, Reason: "Phase " + phase}
+	}
+
+	notReady := make([]string, 0)
+	for _, field := range []string{"initContainerStatuses", "containerStatuses"} {
+		containerStatuses, _, _ := unstructured.NestedSlice(content, "status", field)
+		for _, cs := range containerStatuses {
+			containerStatus, ok := cs.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _, _ := unstructured.NestedString(containerStatus, "name")
+			waitingReason, found, _ := unstructured.NestedString(containerStatus, "state", "waiting", "reason")
+			if found && isFailedWaitingReason(waitingReason) {
+				return Health{Status: healthDegraded, Reason: "Container " + name + " " + waitingReason}
+			}
+			terminatedReason, found, _ := unstructured.NestedString(containerStatus, "state", "terminated", "reason")
+			if found && field == "containerStatuses" && terminatedReason == "Error" {
+				return Health{Status: healthDegraded, Reason: "Container " + name + " " + terminatedReason}
+			}
+			ready, _, _ := unstructured.NestedBool(containerStatus, "ready")
+			if !ready && field == "containerStatuses" {
+				notReady = append(notReady, name)
+			}
+		}
+	}
+	if phase == "Pending" {
+		return Health{Status: healthProgressing, Reason: "Pending"}
+	}
+	if len(notReady) > 0 {
+		return Health{Status: healthProgressing, Reason: "Containers not ready: " + strings.Join(notReady, ",")}
+	}
+	return Health{Status: healthReady}
+}
+
+func isFailedWaitingReason(reason string) bool {
+	switch reason {
+	case "CrashLoopBackOff", "ImagePullBackOff", "ErrImagePull", "InvalidImageName",
+		"CreateContainerConfigError", "CreateContainerError", "RunContainerError":
+		return true
+	}
+	return false
+}
+
+func replicaHealth(content map[string]interface{}) Health {
+	// A Deployment that exceeded its progress deadline will not become ready by itself.
+	if condition, found := getCondition(content, "Progressing"); found {
+		if condition["reason"] == "ProgressDeadlineExceeded" {
+			return Health{Status: healthDegraded, Reason: "ProgressDeadlineExceeded"}
+		}
+	}
+	if condition, found := getCondition(content, "ReplicaFailure"); found && condition["status"] == "True" {
+		return Health{Status: healthDegraded, Reason: fmt.Sprintf("ReplicaFailure %v", condition["message"])}
+	}
+	replicas, found, _ := unstructured.NestedInt64(content, "spec", "replicas")
+	if !found {
+		replicas = 1
+	}
+	readyReplicas, _, _ := unstructured.NestedInt64(content, "status", "readyReplicas")
+	generation, _, _ := unstructured.NestedInt64(content, "metadata", "generation")
+	observedGeneration, found, _ := unstructured.NestedInt64(content, "status", "observedGeneration")
+	if found && observedGeneration < generation {
+		return Health{Status: healthProgressing, Reason: "Spec update not yet observed"}
+	}
+	if readyReplicas < replicas {
+		return Health{Status: healthProgressing, Reason: fmt.Sprintf("%d/%d replicas ready", readyReplicas, replicas)}
+	}
+	return Health{Status: healthReady, Reason: fmt.Sprintf("%d/%d replicas ready", readyReplicas, replicas)}
+}
+
+func daemonSetHealth(content map[string]interface{}) Health {
+	desired, _, _ := unstructured.NestedInt64(content, "status", "desiredNumberScheduled")
+	ready, _, _ := unstructured.NestedInt64(content, "status", "numberReady")
+	if ready < desired {
+		return Health{Status: healthProgressing, Reason: fmt.Sprintf("%d/%d pods ready", ready, desired)}
+	}
+	return Health{Status: healthReady, Reason: fmt.Sprintf("%d/%d pods ready", ready, desired)}
+}
+
+// A Job is done when it has the Complete condition; until then it is Progressing,
+// with its Pods showing whether they are failing.
+func jobHealth(content map[string]interface{}) Health {
+	if condition, found := getCondition(content, "Failed"); found && condition["status"] == "True" {
+		return Health{Status: healthDegraded, Reason: conditionReason(condition)}
+	}
+	if condition, found := getCondition(content, "Complete"); found && condition["status"] == "True" {
+		return Health{Status: healthReady, Reason: "Complete"}
+	}
+	if condition, found := getCondition(content, "Suspended"); found && condition["status"] == "True" {
+		return Health{Status: healthReady, Reason: "Suspended"}
+	}
+	completions, found, _ := unstructured.NestedInt64(content, "spec", "completions")
+	if !found {
+		completions = 1
+	}
+	active, _, _ := unstructured.NestedInt64(content, "status", "active")
+	succeeded, _, _ := unstructured.NestedInt64(content, "status", "succeeded")
+	if active == 0 && succeeded >= completions {
+		return Health{Status: healthReady, Reason: fmt.Sprintf("%d/%d succeeded", succeeded, completions)}
+	}
+	return Health{Status: healthProgressing, Reason: fmt.Sprintf("%d active, %d/%d succeeded", active, succeeded, completions)}
+}
+
+// The health of a CronJob is the one of its Jobs
+func cronJobHealth(content map[string]interface{}) Health {
+	suspend, _, _ := unstructured.NestedBool(content, "spec", "suspend")
+	if suspend {
+		return Health{Status: healthReady, Reason: "Suspended"}
+	}
+	return Health{Status: healthReady}
+}
+
+func pdbHealth(content map[string]interface{}) Health {
+	currentHealthy, _, _ := unstructured.NestedInt64(content, "status", "currentHealthy")
+	desiredHealthy, _, _ := unstructured.NestedInt64(content, "status", "desiredHealthy")
+	if currentHealthy < desiredHealthy {
+		return Health{Status: healthProgressing, Reason: fmt.Sprintf("%d/%d healthy pods", currentHealthy, desiredHealthy)}
+	}
+	return Health{Status: healthReady, Reason: fmt.Sprintf("%d/%d healthy pods", currentHealthy, desiredHealthy)}
+}
+
+// A HorizontalPodAutoscaler that cannot read its metrics or scale its target is Degraded.
+func autoscalerHealth(content map[string]interface{}) Health {
+	for _, conditionType := range []string{"AbleToScale", "ScalingActive"} {
+		condition, found := getCondition(content, conditionType)
+		if found && condition["status"] == "False" && condition["reason"] != "ScalingDisabled" {
+			return Health{Status: healthDegraded, Reason: conditionReason(condition)}
+		}
+	}
+	return Health{Status: healthReady}
+}
+
+func volumeSnapshotHealth(content map[string]interface{}) Health {
+	if message, found, _ := unstructured.NestedString(content, "status", "error", "message"); found {
+		return Health{Status: healthDegraded, Reason: message}
+	}
+	readyToUse, _, _ := unstructured.NestedBool(content, "status", "readyToUse")
+	if readyToUse {
+		return Health{Status: healthReady}
+	}
+	return Health{Status: healthProgressing, Reason: "Not ready to use"}
+}
+
+func volumeHealth(content map[string]interface{}) Health {
+	phase, _, _ := unstructured.NestedString(content, "status", "phase")
+	switch phase {
+	case "Bound":
+		return Health{Status: healthReady, Reason: phase}
+	case "Available", "Pending", "Released":
+		return Health{Status: healthProgressing, Reason: phase}
+	case "Lost", "Failed":
+		return Health{Status: healthDegraded, Reason: phase}
+	}
+	return Health{Status: healthUnknown, Reason: "Phase " + phase}
+}
+
+func phaseHealth(phase string) Health {
+	switch strings.ToLower(phase) {
+	case "running", "ready", "succeeded", "active", "bound", "available", "healthy":
+		return Health{Status: healthReady, Reason: phase}
+	case "pending", "creating", "initializing", "progressing", "updating":
+		return Health{Status: healthProgressing, Reason: phase}
+	case "failed", "error", "degraded", "unhealthy", "lost":
+		return Health{Status: healthDegraded, Reason: phase}
+	}
+	return Health{Status: healthUnknown, Reason: phase}
+}
+
+// Generic health from status.conditions as used by most built-in kinds and CRs.
+func conditionsHealth(content map[string]interface{}) (Health, bool) {
+	for _, conditionType := range []string{"Degraded", "Failed"} {
+		if condition, found := getCondition(content, conditionType); found && condition["status"] == "True" {
+			return Health{Status: healthDegraded, Reason: conditionReason(condition)}, true
+		}
+	}
+	for _, conditionType := range []string{"Ready", "Available", "Established"} {
+		condition, found := getCondition(content, conditionType)
+		if !found {
+			continue
+		}
+		switch condition["status"] {
+		case "True":
+			return Health{Status: healthReady}, true
+		case "False":
+			return Health{Status: healthDegraded, Reason: conditionReason(condition)}, true
+		default:
+			return Health{Status: healthUnknown, Reason: conditionReason(condition)}, true
+		}
+	}
+	if condition, found := getCondition(content, "Progressing"); found && condition["status"] == "True" {
+		return Health{Status: healthProgressing, Reason: conditionReason(condition)}, true
+	}
+	return Health{}, false
+}
+
+func getCondition(content map[string]interface{}, conditionType string) (map[string]interface{}, bool) {
+	conditions, found, _ := unstructured.NestedSlice(content, "status", "conditions")
+	if !found {
+		return nil, false
+	}
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if ok && condition["type"] == conditionType {
+			return condition, true
+		}
+	}
+	return nil, false
+}
+
+func conditionReason(condition map[string]interface{}) string {
+	reason := fmt.Sprintf("%v", condition["type"])
+	if r, ok := condition["reason"].(string); ok && r != "" {
+		reason = reason + " " + r
+	}
+	if m, ok := condition["message"].(string); ok && m != "" {
+		reason = reason + ": " + m
+	}
+	return reason
+}
+
+func healthSeverity(status string) int {
+	switch status {
+	case healthReady:
+		return 0
+	case healthProgressing:
+		return 2
+	case healthDegraded:
+		return 3
+	}
+	return 1
+}
+
+// Returns the parent's health aggregated with the worst health of its children.
+func aggregateHealth(health Health, children []Composition) Health {
+	aggregated := health
+	for _, child := range children {
+		if healthSeverity(child.Health.Status) > healthSeverity(aggregated.Status) {
+			aggregated = Health{
+				Status: child.Health.Status,
+				Reason: child.Kind + "/" + child.Name + ": " + child.Health.Reason,
+			}
+		}
+	}
+	return aggregated
+}
@@ -0,0 +1,363 @@
+package discovery
+
+import (
+	"testing"
+)
+
+func TestComputeHealth(t *testing.T) {
+	testCases := []struct {
+		name     string
+		kind     string
+		content  map[string]interface{}
+		expected Health
+	}{
+		{
+			name: "Pod in CrashLoopBackOff",
+			kind: POD,
+			content: map[string]interface{}{
+				"status": map[string]interface{}{
+					"phase": "Running",
+					"containerStatuses": []interface{}{
+						map[string]interface{}{"name": "sidecar", "ready": true,
+							"state": map[string]interface{}{"running": map[string]interface{}{}}},
+						map[string]interface{}{"name": "app", "ready": false,
+							"state": map[string]interface{}{"waiting": map[string]interface{}{"reason": "CrashLoopBackOff"}}},
+					},
+				},
+			},
+			expected: Health{Status: healthDegraded, Reason: "Container app CrashLoopBackOff"},
+		},
+		{
+			name: "running Pod with all containers ready",
+			kind: POD,
+			content: map[string]interface{}{
+				"status": map[string]interface{}{
+					"phase": "Running",
+					"containerStatuses": []interface{}{
+						map[string]interface{}{"name": "app", "ready": true},
+					},
+				},
+			},
+			expected: Health{Status: healthReady},
+		},
+		{
+			name: "Deployment with too few available replicas",
+			kind: DEPLOYMENT,
+			content: map[string]interface{}{
+				"metadata": map[string]interface{}{"generation": int64(2)},
+				"spec":     map[string]interface{}{"replicas": int64(3)},
+				"status": map[string]interface{}{
+					"observedGeneration": int64(2),
+					"replicas":           int64(3),
+					"readyReplicas":      int64(1),
+					"availableReplicas":  int64(1),
+				},
+			},
+			expected: Health{Status: healthProgressing, Reason: "1/3 replicas ready"},
+		},
+		{
+			name: "Deployment past its progress deadline",
+			kind: DEPLOYMENT,
+			content: map[string]interface{}{
+				"spec": map[string]interface{}{"replicas": int64(1)},
+				"status": map[string]interface{}{
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "Progressing", "status": "False", "reason": "ProgressDeadlineExceeded"},
+					},
+				},
+			},
+			expected: Health{Status: healthDegraded, Reason: "ProgressDeadlineExceeded"},
+		},
+		{
+			name: "Deployment with all replicas ready",
+			kind: DEPLOYMENT,
+			content: map[string]interface{}{
+				"spec":   map[string]interface{}{"replicas": int64(2)},
+				"status": map[string]interface{}{"readyReplicas": int64(2), "availableReplicas": int64(2)},
+			},
+			expected: Health{Status: healthReady, Reason: "2/2 replicas ready"},
+		},
+		{
+			name:     "Bound PVC",
+			kind:     PVCLAIM,
+			content:  map[string]interface{}{"status": map[string]interface{}{"phase": "Bound"}},
+			expected: Health{Status: healthReady, Reason: "Bound"},
+		},
+		{
+			name:     "Pending PVC",
+			kind:     PVCLAIM,
+			content:  map[string]interface{}{"status": map[string]interface{}{"phase": "Pending"}},
+			expected: Health{Status: healthProgressing, Reason: "Pending"},
+		},
+		{
+			name: "custom resource with Ready=False",
+			kind: "Moodle",
+			content: map[string]interface{}{
+				"status": map[string]interface{}{
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "Ready", "status": "False",
+							"reason": "DatabaseUnavailable", "message": "cannot connect to mysql"},
+					},
+				},
+			},
+			expected: Health{Status: healthDegraded, Reason: "Ready DatabaseUnavailable: cannot connect to mysql"},
+		},
+		{
+			name: "custom resource with Ready=True",
+			kind: "Moodle",
+			content: map[string]interface{}{
+				"status": map[string]interface{}{
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "Ready", "status": "True"},
+					},
+				},
+			},
+			expected: Health{Status: healthReady},
+		},
+		{
+			name:     "custom resource without status",
+			kind:     "Moodle",
+			content:  map[string]interface{}{},
+			expected: Health{Status: healthUnknown, Reason: "No status conditions"},
+		},
+		{
+			name:     "kind without runtime state",
+			kind:     CONFIG_MAP,
+			content:  map[string]interface{}{},
+			expected: Health{Status: healthReady},
+		},
+		{
+			name:     "ControllerRevision",
+			kind:     CONTROLLER_REVISION,
+			content:  map[string]interface{}{"revision": int64(3)},
+			expected: Health{Status: healthReady},
+		},
+		{
+			name:     "Role",
+			kind:     ROLE,
+			content:  map[string]interface{}{"rules": []interface{}{}},
+			expected: Health{Status: healthReady},
+		},
+		{
+			name:     "RoleBinding",
+			kind:     ROLE_BINDING,
+			content:  map[string]interface{}{"roleRef": map[string]interface{}{"kind": "Role", "name": "reader"}},
+			expected: Health{Status: healthReady},
+		},
+		{
+			name: "PodDisruptionBudget with enough healthy Pods",
+			kind: PDB,
+			content: map[string]interface{}{
+				"status": map[string]interface{}{"currentHealthy": int64(3), "desiredHealthy": int64(2)},
+			},
+			expected: Health{Status: healthReady, Reason: "3/2 healthy pods"},
+		},
+		{
+			name: "PodDisruptionBudget with too few healthy Pods",
+			kind: PDB,
+			content: map[string]interface{}{
+				"status": map[string]interface{}{"currentHealthy": int64(1), "desiredHealthy": int64(2)},
+			},
+			expected: Health{Status: healthProgressing, Reason: "1/2 healthy pods"},
+		},
+		{
+			name:     "CronJob",
+			kind:     CRONJOB,
+			content:  map[string]interface{}{"spec": map[string]interface{}{"schedule": "*/5 * * * *"}},
+			expected: Health{Status: healthReady},
+		},
+		{
+			name:     "suspended CronJob",
+			kind:     CRONJOB,
+			content:  map[string]interface{}{"spec": map[string]interface{}{"suspend": true}},
+			expected: Health{Status: healthReady, Reason: "Suspended"},
+		},
+		{
+			name:     "running Job without conditions",
+			kind:     JOB,
+			content:  map[string]interface{}{"status": map[string]interface{}{"active": int64(1)}},
+			expected: Health{Status: healthProgressing, Reason: "1 active, 0/1 succeeded"},
+		},
+		{
+			name: "complete Job",
+			kind: JOB,
+			content: map[string]interface{}{
+				"spec": map[string]interface{}{"completions": int64(3)},
+				"status": map[string]interface{}{
+					"succeeded":  int64(3),
+					"conditions": []interface{}{map[string]interface{}{"type": "Complete", "status": "True"}},
+				},
+			},
+			expected: Health{Status: healthReady, Reason: "Complete"},
+		},
+		{
+			name: "Job with its Pods succeeded before the Complete condition",
+			kind: JOB,
+			content: map[string]interface{}{
+				"spec":   map[string]interface{}{"completions": int64(2)},
+				"status": map[string]interface{}{"succeeded": int64(2)},
+			},
+			expected: Health{Status: healthReady, Reason: "2/2 succeeded"},
+		},
+		{
+			name: "failed Job",
+			kind: JOB,
+			content: map[string]interface{}{
+				"status": map[string]interface{}{
+					"failed": int64(6),
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "Failed", "status": "True", "reason": "BackoffLimitExceeded",
+							"message": "Job has reached the specified backoff limit"},
+					},
+				},
+			},
+			expected: Health{Status: healthDegraded, Reason: "Failed BackoffLimitExceeded: Job has reached the specified backoff limit"},
+		},
+		{
+			name: "HorizontalPodAutoscaler without metrics",
+			kind: HPA,
+			content: map[string]interface{}{
+				"status": map[string]interface{}{
+					"conditions": []interface{}{
+						map[string]interface{}{"type": "AbleToScale", "status": "True"},
+						map[string]interface{}{"type": "ScalingActive", "status": "False", "reason": "FailedGetResourceMetric"},
+					},
+				},
+			},
+			expected: Health{Status: healthDegraded, Reason: "ScalingActive FailedGetResourceMetric"},
+		},
+		{
+			name:     "HorizontalPodAutoscaler without status",
+			kind:     HPA,
+			content:  map[string]interface{}{},
+			expected: Health{Status: healthReady},
+		},
+		{
+			name: "established CustomResourceDefinition",
+			kind: CRD,
+			content: map[string]interface{}{
+				"status": map[string]interface{}{
+					"conditions": []interface{}{map[string]interface{}{"type": "Established", "status": "True"}},
+				},
+			},
+			expected: Health{Status: healthReady},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			health := computeHealth(testCase.kind, testCase.content)
+			if health != testCase.expected {
+				t.Errorf("computeHealth = %+v, expected %+v", health, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestAggregateHealth(t *testing.T) {
+	pendingPVC := Composition{Kind: PVCLAIM, Name: "data",
+		Health: computeHealth(PVCLAIM, map[string]interface{}{"status": map[string]interface{}{"phase": "Pending"}})}
+	boundPVC := Composition{Kind: PVCLAIM, Name: "logs",
+		Health: computeHealth(PVCLAIM, map[string]interface{}{"status": map[string]interface{}{"phase": "Bound"}})}
+	crashingPod := Composition{Kind: POD, Name: "web-1-a", Health: Health{Status: healthDegraded, Reason: "Container app CrashLoopBackOff"}}
+	unknownChild := Composition{Kind: "Moodle", Name: "moodle1", Health: Health{Status: healthUnknown, Reason: "No status conditions"}}
+
+	testCases := []struct {
+		name     string
+		health   Health
+		children []Composition
+		expected Health
+	}{
+		{
+			name:     "no children",
+			health:   Health{Status: healthReady},
+			expected: Health{Status: healthReady},
+		},
+		{
+			name:     "healthy children keep the parent health",
+			health:   Health{Status: healthReady, Reason: "1/1 replicas ready"},
+			children: []Composition{boundPVC},
+			expected: Health{Status: healthReady, Reason: "1/1 replicas ready"},
+		},
+		{
+			name:     "worst child wins",
+			health:   Health{Status: healthReady},
+			children: []Composition{boundPVC, pendingPVC, crashingPod},
+			expected: Health{Status: healthDegraded, Reason: "Pod/web-1-a: Container app CrashLoopBackOff"},
+		},
+		{
+			name:     "first of equally bad children",
+			health:   Health{Status: healthReady},
+			children: []Composition{pendingPVC, {Kind: POD, Name: "web-1-b", Health: Health{Status: healthProgressing, Reason: "Pending"}}},
+			expected: Health{Status: healthProgressing, Reason: "PersistentVolumeClaim/data: Pending"},
+		},
+		{
+			name:     "parent worse than children",
+			health:   Health{Status: healthDegraded, Reason: "ProgressDeadlineExceeded"},
+			children: []Composition{pendingPVC},
+			expected: Health{Status: healthDegraded, Reason: "ProgressDeadlineExceeded"},
+		},
+		{
+			name:     "unknown is worse than ready but better than progressing",
+			health:   Health{Status: healthReady},
+			children: []Composition{unknownChild, boundPVC},
+			expected: Health{Status: healthUnknown, Reason: "Moodle/moodle1: No status conditions"},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			health := aggregateHealth(testCase.health, testCase.children)
+			if health != testCase.expected {
+				t.Errorf("aggregateHealth = %+v, expected %+v", health, testCase.expected)
+			}
+		})
+	}
+}
+
+// Healthy workloads stay Ready with all their children, including the ones
+// without runtime state.
+func TestAggregateHealthOfHealthyWorkloads(t *testing.T) {
+	readyPod := Composition{Kind: POD, Name: "db-0",
+		Health: computeHealth(POD, map[string]interface{}{"status": map[string]interface{}{"phase": "Running"}})}
+	revision := Composition{Kind: CONTROLLER_REVISION, Name: "db-7d4b9c",
+		Health: computeHealth(CONTROLLER_REVISION, map[string]interface{}{})}
+	boundPVC := Composition{Kind: PVCLAIM, Name: "data-db-0",
+		Health: computeHealth(PVCLAIM, map[string]interface{}{"status": map[string]interface{}{"phase": "Bound"}})}
+	completeJob := Composition{Kind: JOB, Name: "backup-1",
+		Health: computeHealth(JOB, map[string]interface{}{"status": map[string]interface{}{"succeeded": int64(1)}}),
+		Children: []Composition{{Kind: POD, Name: "backup-1-a",
+			Health: computeHealth(POD, map[string]interface{}{"status": map[string]interface{}{"phase": "Succeeded"}})}}}
+	completeJob.Health = aggregateHealth(completeJob.Health, completeJob.Children)
+
+	statefulSet := computeHealth(STATEFULSET, map[string]interface{}{
+		"spec":   map[string]interface{}{"replicas": int64(1)},
+		"status": map[string]interface{}{"readyReplicas": int64(1)},
+	})
+	health := aggregateHealth(statefulSet, []Composition{readyPod, revision, boundPVC})
+	if health.Status != healthReady {
+		t.Errorf("StatefulSet health = %+v, expected Ready", health)
+	}
+	daemonSet := computeHealth(DAEMONSET, map[string]interface{}{
+		"status": map[string]interface{}{"desiredNumberScheduled": int64(1), "numberReady": int64(1)},
+	})
+	health = aggregateHealth(daemonSet, []Composition{readyPod, revision})
+	if health.Status != healthReady {
+		t.Errorf("DaemonSet health = %+v, expected Ready", health)
+	}
+	health = aggregateHealth(computeHealth(CRONJOB, map[string]interface{}{}), []Composition{completeJob})
+	if health.Status != healthReady {
+		t.Errorf("CronJob health = %+v, expected Ready", health)
+	}
+	moodle := computeHealth("Moodle", map[string]interface{}{
+		"status": map[string]interface{}{
+			"conditions": []interface{}{map[string]interface{}{"type": "Ready", "status": "True"}},
+		},
+	})
+	health = aggregateHealth(moodle, []Composition{
+		{Kind: STATEFULSET, Name: "db", Health: statefulSet},
+		{Kind: SECRET, Name: "db-password", Health: computeHealth(SECRET, map[string]interface{}{})},
+		{Kind: PDB, Name: "db", Health: computeHealth(PDB, map[string]interface{}{})},
+	})
+	if health.Status != healthReady {
+		t.Errorf("custom resource health = %+v, expected Ready", health)
+	}
+}
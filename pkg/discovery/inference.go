@@ -0,0 +1,238 @@
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+)
+
+// Composition inference for operators that do not follow the KubePlus guidelines.
+// Objects of every listable API resource are scanned for ownerReferences and the
+// child Kinds that actually occur are recorded per owner Kind. The result is used
+// instead of the default composition list for CRDs without the composition annotation,
+// and merged into compositionMap for all the other owner Kinds.
+// The cluster is scanned on the first lookup and again after CRDs change; only object
+// metadata is listed, in pages of inferenceListLimit objects.
+var (
+	InferCompositions bool
+
+	// Owner group and Kind -> child Kinds seen in the cluster. Nil until the cluster is scanned.
+	inferredCompositionMap map[schema.GroupKind][]string
+	inferredCompositionMux sync.Mutex
+)
+
+const inferenceListLimit = 500
+
+func init() {
+	value, ok := os.LookupEnv("INFER_COMPOSITIONS")
+	if ok {
+		InferCompositions = strings.EqualFold(value, "true")
+	}
+}
+
+func getInferredCompositionKinds(kind, group string) []string {
+	return getInferredCompositions()[schema.GroupKind{Group: group, Kind: kind}]
+}
+
+// Drops the scan result so that the next lookup sees Kinds added since.
+func resetInferredCompositions() {
+	inferredCompositionMux.Lock()
+	defer inferredCompositionMux.Unlock()
+	inferredCompositionMap = nil
+}
+
+func getInferredCompositions() map[schema.GroupKind][]string {
+	inferredCompositionMux.Lock()
+	defer inferredCompositionMux.Unlock()
+	if inferredCompositionMap != nil {
+		return inferredCompositionMap
+	}
+	inferredMap, err := inferCompositions()
+	if err != nil {
+		fmt.Printf("Cannot infer compositions: %s\n", err.Error())
+	}
+	inferredCompositionMap = inferredMap
+	return inferredCompositionMap
+}
+
+func inferCompositions() (map[schema.GroupKind][]string, error) {
+	inferredMap := make(map[schema.GroupKind][]string)
+	if cfg == nil {
+		return inferredMap, fmt.Errorf("Kubernetes config not initialized")
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return inferredMap, err
+	}
+	metadataClient, err := metadata.NewForConfig(cfg)
+	if err != nil {
+		return inferredMap, err
+	}
+	// Discovery returns whatever it could get along with an error for the failed groups.
+	resourceLists, err := clientset.Discovery().ServerPreferredResources()
+	if err != nil {
+		fmt.Printf("Partial API discovery: %s\n", err.Error())
+	}
+
+	// Child Kinds that cannot be looked up by their name are kept as false
+	childKindSet := make(map[schema.GroupKind]map[schema.GroupKind]bool)
+	for _, resourceList := range resourceLists {
+		gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, resource := range resourceList.APIResources {
+			if strings.Contains(resource.Name, "/") || !isListable(resource.Verbs) {
+				continue
+			}
+			res := schema.GroupVersionResource{Group: gv.Group,
+				Version:  gv.Version,
+				Resource: resource.Name}
+			listOptions := metav1.ListOptions{Limit: inferenceListLimit}
+			for {
+				list, err := metadataClient.Resource(res).List(context.TODO(), listOptions)
+				if err != nil {
+					//fmt.Printf("Cannot list %s: %s\n", resource.Name, err.Error())
+					break
+				}
+				childKey := schema.GroupKind{Group: gv.Group, Kind: resource.Kind}
+				for _, item := range list.Items {
+					for _, ownerReference := range item.GetOwnerReferences() {
+						ownerGV, err := schema.ParseGroupVersion(ownerReference.APIVersion)
+						if err != nil {
+							continue
+						}
+						ownerKey := schema.GroupKind{Group: ownerGV.Group, Kind: ownerReference.Kind}
+						if _, ok := childKindSet[ownerKey]; !ok {
+							childKindSet[ownerKey] = make(map[schema.GroupKind]bool)
+						}
+						if _, ok := childKindSet[ownerKey][childKey]; !ok {
+							childKindSet[ownerKey][childKey] = registerInferredKind(resource.Kind, resource.Name, gv, resource.Namespaced)
+							discoverKind(ownerReference.Kind, ownerReference.APIVersion)
+						}
+					}
+				}
+				if list.GetContinue() == "" {
+					break
+				}
+				listOptions.Continue = list.GetContinue()
+			}
+		}
+	}
+
+	for ownerKey, childKinds := range childKindSet {
+		inferredMap[ownerKey] = collectInferredKinds(childKinds)
+	}
+	return inferredMap, nil
+}
+
+func collectInferredKinds(childKinds map[schema.GroupKind]bool) []string {
+	kinds := make([]string, 0)
+	for childKey, registered := range childKinds {
+		if registered && !containsString(kinds, childKey.Kind) {
+			kinds = append(kinds, childKey.Kind)
+		}
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func isListable(verbs metav1.Verbs) bool {
+	for _, verb := range verbs {
+		if verb == "list" {
+			return true
+		}
+	}
+	return false
+}
+
+// Child Kinds found by the scan may not be known yet; record their API details
+// so that composition queries can list them. Kinds are listed by their name, so
+// false is returned when the name is already taken by a Kind of another group.
+func registerInferredKind(kind, plural string, gv schema.GroupVersion, namespaced bool) bool {
+	kindMapsMux.Lock()
+	defer kindMapsMux.Unlock()
+	if _, ok := KindPluralMap[kind]; ok {
+		group, ok := kindGroupMap[kind]
+		return !ok || group == gv.Group
+	}
+	KindPluralMap[kind] = plural
+	kindVersionMap[kind] = getAPIEndpoint(gv)
+	kindGroupMap[kind] = gv.Group
+	if !namespaced {
+		clusterScopedKinds[kind] = true
+	}
+	return true
+}
+
+// Kinds registered without a group, e.g. from the composition file, match any group.
+func isKindOfGroup(kind, group string) bool {
+	kindMapsMux.RLock()
+	defer kindMapsMux.RUnlock()
+	registeredGroup, ok := kindGroupMap[kind]
+	return !ok || registeredGroup == group
+}
+
+// Adds the child Kinds seen in the cluster to compositionMap.
+// Kinds already present in compositionMap are kept. Owners whose Kind name
+// belongs to another group are left out as compositionMap is keyed by Kind.
+func mergeInferredCompositions() {
+	inferredMap := make(map[string][]string)
+	for ownerKey, childKinds := range getInferredCompositions() {
+		if isKindOfGroup(ownerKey.Kind, ownerKey.Group) {
+			inferredMap[ownerKey.Kind] = append(inferredMap[ownerKey.Kind], childKinds...)
+		}
+	}
+	compositionMapMux.Lock()
+	defer compositionMapMux.Unlock()
+	for ownerKind, childKinds := range inferredMap {
+		existingKinds := make(map[string]bool)
+		// Copied so that slices handed out to readers are never modified
+		mergedKinds := make([]string, 0)
+		for _, childKind := range compositionMap[ownerKind] {
+			existingKinds[strings.TrimSpace(childKind)] = true
+			mergedKinds = append(mergedKinds, childKind)
+		}
+		for _, childKind := range childKinds {
+			if !existingKinds[childKind] {
+				mergedKinds = append(mergedKinds, childKind)
+			}
+		}
+		compositionMap[ownerKind] = mergedKinds
+	}
+}
+
+// GetCompositionKinds returns the child Kinds that compositions of each Kind are built from.
+func GetCompositionKinds() map[string][]string {
+	compositionKinds := make(map[string][]string)
+	compositionMapMux.RLock()
+	defer compositionMapMux.RUnlock()
+	for kind, childKinds := range compositionMap {
+		compositionKinds[kind] = []string{}
+		for _, childKind := range childKinds {
+			childKind = strings.TrimSpace(childKind)
+			if childKind != "" {
+				compositionKinds[kind] = append(compositionKinds[kind], childKind)
+			}
+		}
+	}
+	return compositionKinds
+}
+
+func GetCompositionKindsString() string {
+	compositionKinds := GetCompositionKinds()
+	compositionKindsBytes, err := json.Marshal(compositionKinds)
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+	return string(compositionKindsBytes)
+}
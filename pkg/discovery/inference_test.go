@@ -0,0 +1,111 @@
+package discovery
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestMergeInferredCompositions(t *testing.T) {
+	savedInferred := inferredCompositionMap
+	inferredCompositionMap = map[schema.GroupKind][]string{
+		{Group: testCRGroup, Kind: "Moodle"}:           {DEPLOYMENT, SECRET},
+		{Group: "postgres.kubeplus", Kind: "Postgres"}: {STATEFULSET},
+		// Same Kind name as the Moodle CRD
+		{Group: "other.kubeplus", Kind: "Moodle"}: {CONFIG_MAP},
+	}
+	t.Cleanup(func() { inferredCompositionMap = savedInferred })
+	setTestCustomResourceKind(t)
+	setTestCompositions(t, map[string][]string{"Moodle": {DEPLOYMENT, " Service"}})
+	t.Cleanup(func() { delete(compositionMap, "Postgres") })
+
+	// Readers such as the informer handlers run while the composition file is read
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			mergeInferredCompositions()
+		}()
+		go func() {
+			defer wg.Done()
+			_, _ = getCompositionChildKinds("Moodle")
+			_ = GetCompositionKinds()
+		}()
+	}
+	wg.Wait()
+
+	expected := map[string][]string{
+		"Moodle":   {DEPLOYMENT, " Service", SECRET},
+		"Postgres": {STATEFULSET},
+	}
+	for kind, expectedKinds := range expected {
+		childKinds, _ := getCompositionChildKinds(kind)
+		if !reflect.DeepEqual(childKinds, expectedKinds) {
+			t.Errorf("compositionMap[%s] = %v, expected %v", kind, childKinds, expectedKinds)
+		}
+	}
+}
+
+func TestRegisterInferredKind(t *testing.T) {
+	setTestCustomResourceKind(t)
+	t.Cleanup(func() {
+		delete(KindPluralMap, "Backup")
+		delete(kindVersionMap, "Backup")
+		delete(kindGroupMap, "Backup")
+		delete(clusterScopedKinds, "Backup")
+	})
+	testCases := []struct {
+		kind     string
+		gv       schema.GroupVersion
+		expected bool
+	}{
+		{"Backup", schema.GroupVersion{Group: "backup.kubeplus", Version: "v1"}, true},
+		{"Backup", schema.GroupVersion{Group: "backup.kubeplus", Version: "v1"}, true},
+		{"Backup", schema.GroupVersion{Group: "velero.io", Version: "v1"}, false},
+		{"Moodle", schema.GroupVersion{Group: testCRGroup, Version: "v1"}, true},
+		{"Moodle", schema.GroupVersion{Group: "other.kubeplus", Version: "v1"}, false},
+		{DEPLOYMENT, schema.GroupVersion{Group: "apps", Version: "v1"}, true},
+	}
+	for _, testCase := range testCases {
+		if registered := registerInferredKind(testCase.kind, "backups", testCase.gv, false); registered != testCase.expected {
+			t.Errorf("registerInferredKind(%s, %s) = %v, expected %v", testCase.kind, testCase.gv, registered, testCase.expected)
+		}
+	}
+	plural, apiVersionPath, _, group := getKindAPIDetails("Backup")
+	if plural != "backups" || apiVersionPath != "apis/backup.kubeplus/v1" || group != "backup.kubeplus" || !IsClusterScoped("Backup") {
+		t.Errorf("Backup is registered as %s %s %s", plural, apiVersionPath, group)
+	}
+}
+
+func TestCollectInferredKinds(t *testing.T) {
+	childKinds := map[schema.GroupKind]bool{
+		{Group: "apps", Kind: DEPLOYMENT}:          true,
+		{Group: "", Kind: SECRET}:                  true,
+		{Group: "backup.kubeplus", Kind: "Backup"}: true,
+		{Group: "velero.io", Kind: "Backup"}:       false,
+		{Group: "other.kubeplus", Kind: "Moodle"}:  false,
+	}
+	expected := []string{"Backup", DEPLOYMENT, SECRET}
+	if kinds := collectInferredKinds(childKinds); !reflect.DeepEqual(kinds, expected) {
+		t.Errorf("inferred Kinds are %v, expected %v", kinds, expected)
+	}
+}
+
+func TestGetInferredCompositionKinds(t *testing.T) {
+	savedInferred := inferredCompositionMap
+	inferredCompositionMap = map[schema.GroupKind][]string{
+		{Group: testCRGroup, Kind: "Moodle"}:      {DEPLOYMENT},
+		{Group: "other.kubeplus", Kind: "Moodle"}: {CONFIG_MAP},
+	}
+	t.Cleanup(func() { inferredCompositionMap = savedInferred })
+	if kinds := getInferredCompositionKinds("Moodle", "other.kubeplus"); !reflect.DeepEqual(kinds, []string{CONFIG_MAP}) {
+		t.Errorf("inferred Kinds of other.kubeplus Moodle are %v", kinds)
+	}
+	resetInferredCompositions()
+	if inferredCompositionMap != nil {
+		t.Errorf("inferred compositions are kept after the reset")
+	}
+}
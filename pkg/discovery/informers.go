@@ -0,0 +1,551 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Composition index maintained by shared informers in server mode.
+// Each Kind with a composition in compositionMap, and each of their child Kinds,
+// gets an informer. Kinds without runtime state get a metadata-only informer, as
+// their compositions need nothing else; Secret data in particular is not cached.
+// The index is started in the background. Once all the informers have synced,
+// the compositions are built once from their stores; informers that do not sync
+// within compositionSyncTimeout are stopped and queries keep building compositions
+// from the API server. After that, add/update/delete events rebuild only the stored
+// compositions that include the changed object, found through its owner references,
+// reading only from the stores, so that composition queries become in-memory lookups.
+var (
+	compositionInformers map[string]cache.SharedIndexInformer
+	// Guards compositionInformers and compositionIndexOn, which are set while queries are served.
+	compositionInformersMux sync.RWMutex
+	compositionIndexMux     sync.Mutex
+	// Set when the informers have synced; lists of the indexed Kinds are then served by their stores.
+	compositionIndexOn bool
+	// Set once the initial build from the synced stores is done; events are
+	// dropped before that as the initial build covers them.
+	compositionIndexReady bool
+
+	// Informers of the Kinds of new CRDs are added to these factories
+	compositionInformerFactory         dynamicinformer.DynamicSharedInformerFactory
+	compositionMetadataInformerFactory metadatainformer.SharedInformerFactory
+	compositionMetadataClient          metadata.Interface
+	compositionInformerStopCh          chan struct{}
+
+	compositionListCache    map[string][]unstructured.Unstructured
+	compositionListCacheMux sync.Mutex
+
+	// Owner chains deeper than this are not followed when refreshing compositions.
+	maxOwnerDepth int
+	// Time the informers have to sync before the index is given up.
+	compositionSyncTimeout time.Duration
+)
+
+func init() {
+	compositionInformers = make(map[string]cache.SharedIndexInformer)
+	maxOwnerDepth = 10
+	compositionSyncTimeout = 5 * time.Minute
+}
+
+// StartCompositionIndex starts the informers and builds the compositions once they have
+// synced. It is meant to run in the background; until it returns, CompositionIndexed is
+// false for all Kinds. CRDs are watched so that the Kinds of new CRDs get indexed and
+// changed composition annotations are picked up.
+func StartCompositionIndex(stopCh <-chan struct{}) error {
+	err := readKindCompositionFile("")
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := getDynamicClient()
+	if err != nil {
+		return err
+	}
+	metadataClient, err := metadata.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+	compositionInformerFactory = dynamicinformer.NewDynamicSharedInformerFactory(dynamicClient, 0)
+	compositionMetadataInformerFactory = metadatainformer.NewSharedInformerFactory(metadataClient, 0)
+	compositionMetadataClient = metadataClient
+
+	informers := newCompositionInformers(getIndexedKinds())
+	crdRes := schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+	_, err = metadataClient.Resource(crdRes).List(context.TODO(), metav1.ListOptions{Limit: 1})
+	if err != nil {
+		fmt.Printf("Not watching CRDs for new compositions: %s\n", err.Error())
+	} else {
+		compositionMetadataInformerFactory.ForResource(crdRes).Informer().AddEventHandler(crdEventHandler())
+	}
+
+	// The informers are stopped with stopCh, or when they do not sync in time
+	compositionInformerStopCh = make(chan struct{})
+	var stopInformers sync.Once
+	go func() {
+		select {
+		case <-stopCh:
+			stopInformers.Do(func() { close(compositionInformerStopCh) })
+		case <-compositionInformerStopCh:
+		}
+	}()
+	compositionInformerFactory.Start(compositionInformerStopCh)
+	compositionMetadataInformerFactory.Start(compositionInformerStopCh)
+
+	hasSyncedFuncs := make([]cache.InformerSynced, 0)
+	for _, informer := range informers {
+		hasSyncedFuncs = append(hasSyncedFuncs, informer.HasSynced)
+	}
+	if !waitForCompositionInformers(stopCh, compositionSyncTimeout, hasSyncedFuncs...) {
+		stopInformers.Do(func() { close(compositionInformerStopCh) })
+		return fmt.Errorf("composition informers did not sync within %s", compositionSyncTimeout)
+	}
+
+	compositionIndexMux.Lock()
+	defer compositionIndexMux.Unlock()
+	compositionInformersMux.Lock()
+	compositionInformers = informers
+	compositionIndexOn = true
+	compositionInformersMux.Unlock()
+	buildAllCompositions(getCompositionParentKinds(), metav1.NamespaceAll)
+	compositionIndexReady = true
+	return nil
+}
+
+// Creates the informers of the given Kinds that are not indexed yet.
+func newCompositionInformers(kinds []string) map[string]cache.SharedIndexInformer {
+	informers := make(map[string]cache.SharedIndexInformer)
+	for _, kind := range kinds {
+		compositionInformersMux.RLock()
+		_, indexed := compositionInformers[kind]
+		compositionInformersMux.RUnlock()
+		if indexed {
+			continue
+		}
+		resourceKindPlural, _, resourceApiVersion, resourceGroup := getKindAPIDetails(kind)
+		if resourceKindPlural == "" || resourceApiVersion == "" {
+			continue
+		}
+		res := schema.GroupVersionResource{Group: resourceGroup,
+			Version:  resourceApiVersion,
+			Resource: resourceKindPlural}
+		// Skip resources that are not served by this cluster; their informers would never sync.
+		_, err := compositionMetadataClient.Resource(res).List(context.TODO(), metav1.ListOptions{Limit: 1})
+		if err != nil {
+			fmt.Printf("Not indexing compositions of %s: %s\n", kind, err.Error())
+			continue
+		}
+		var informer cache.SharedIndexInformer
+		if hasRuntimeState(kind) {
+			informer = compositionInformerFactory.ForResource(res).Informer()
+		} else {
+			informer = compositionMetadataInformerFactory.ForResource(res).Informer()
+		}
+		informer.AddEventHandler(compositionEventHandler(kind))
+		informers[kind] = informer
+	}
+	return informers
+}
+
+// Adds the informers of the Kinds of new CRDs, and rebuilds all the compositions
+// as the composition annotations may have changed. Kinds whose informers do not
+// sync in time keep being listed from the API server.
+func updateCompositionIndex() {
+	if InferCompositions {
+		resetInferredCompositions()
+	}
+	err := readKindCompositionFile("")
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	informers := newCompositionInformers(getIndexedKinds())
+	compositionInformersMux.Lock()
+	for kind, informer := range informers {
+		compositionInformers[kind] = informer
+	}
+	compositionInformersMux.Unlock()
+	compositionInformerFactory.Start(compositionInformerStopCh)
+	compositionMetadataInformerFactory.Start(compositionInformerStopCh)
+
+	hasSyncedFuncs := make([]cache.InformerSynced, 0)
+	for _, informer := range informers {
+		hasSyncedFuncs = append(hasSyncedFuncs, informer.HasSynced)
+	}
+	if !waitForCompositionInformers(compositionInformerStopCh, compositionSyncTimeout, hasSyncedFuncs...) {
+		fmt.Printf("Composition informers of new Kinds did not sync within %s\n", compositionSyncTimeout)
+	}
+
+	compositionIndexMux.Lock()
+	defer compositionIndexMux.Unlock()
+	buildAllCompositions(getCompositionParentKinds(), metav1.NamespaceAll)
+}
+
+func crdEventHandler() cache.ResourceEventHandlerFuncs {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			// CRDs read when the index started are already known
+			crd, ok := toUnstructured(CRD, obj)
+			if ok && !isKnownCRD(crd.GetName()) && CompositionIndexReady() {
+				updateCompositionIndex()
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldCRD, ok1 := toUnstructured(CRD, oldObj)
+			newCRD, ok2 := toUnstructured(CRD, newObj)
+			if !ok1 || !ok2 || !CompositionIndexReady() {
+				return
+			}
+			// Status updates change neither the generation nor the annotations
+			if oldCRD.GetGeneration() != newCRD.GetGeneration() ||
+				!reflect.DeepEqual(oldCRD.GetAnnotations(), newCRD.GetAnnotations()) {
+				updateCompositionIndex()
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if CompositionIndexReady() {
+				updateCompositionIndex()
+			}
+		},
+	}
+}
+
+// CRDs are named <plural>.<group>
+func isKnownCRD(name string) bool {
+	parts := strings.SplitN(name, ".", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	kindMapsMux.RLock()
+	defer kindMapsMux.RUnlock()
+	for kind, plural := range KindPluralMap {
+		if plural == parts[0] && kindGroupMap[kind] == parts[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// Like cache.WaitForCacheSync, but gives up after the timeout.
+func waitForCompositionInformers(stopCh <-chan struct{}, timeout time.Duration, hasSyncedFuncs ...cache.InformerSynced) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		synced := true
+		for _, hasSynced := range hasSyncedFuncs {
+			if !hasSynced() {
+				synced = false
+				break
+			}
+		}
+		if synced {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		select {
+		case <-stopCh:
+			return false
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// Informer of the Kind when the composition index is on and the informer has synced
+func getCompositionInformer(kind string) (cache.SharedIndexInformer, bool) {
+	compositionInformersMux.RLock()
+	defer compositionInformersMux.RUnlock()
+	if !compositionIndexOn {
+		return nil, false
+	}
+	informer, ok := compositionInformers[kind]
+	if !ok || !informer.HasSynced() {
+		return nil, false
+	}
+	return informer, true
+}
+
+// CompositionIndexReady returns true once the compositions have been built from
+// the synced informers. Until then queries should fall back to BuildCompositionTree.
+func CompositionIndexReady() bool {
+	compositionIndexMux.Lock()
+	defer compositionIndexMux.Unlock()
+	return compositionIndexReady
+}
+
+// CompositionIndexed returns true when the compositions of the Kind are maintained
+// by the composition index. Compositions of other Kinds are built on each query
+// with BuildKindCompositions.
+func CompositionIndexed(kind string) bool {
+	if !CompositionIndexReady() {
+		return false
+	}
+	_, ok := getCompositionInformer(kind)
+	return ok && hasCompositionChildKinds(kind)
+}
+
+// BuildKindCompositions builds the compositions of the resources of the Kind in the
+// namespace, unless they are maintained by the composition index. This covers Kinds
+// without compositions and Kinds of CRDs that are not indexed yet. Lists of the
+// indexed Kinds are served by the informer stores.
+func BuildKindCompositions(kind, namespace string) {
+	if CompositionIndexed(kind) {
+		return
+	}
+	err := readKindCompositionFile("")
+	if err != nil {
+		fmt.Printf("Error: %s\n", err.Error())
+		return
+	}
+	compositionIndexMux.Lock()
+	defer compositionIndexMux.Unlock()
+	startCompositionListCache()
+	defer stopCompositionListCache()
+	buildAllCompositions([]string{kind}, namespace)
+}
+
+// Kinds with compositions and their child Kinds
+func getIndexedKinds() []string {
+	kinds := make([]string, 0)
+	for _, kind := range getCompositionParentKinds() {
+		if !containsString(kinds, kind) {
+			kinds = append(kinds, kind)
+		}
+		childKinds, _ := getCompositionChildKinds(kind)
+		for _, childKind := range childKinds {
+			childKind = strings.TrimSpace(childKind)
+			if !containsString(kinds, childKind) {
+				kinds = append(kinds, childKind)
+			}
+		}
+	}
+	return kinds
+}
+
+// Kinds with child Kinds in compositionMap. Only their compositions are kept by the
+// index; the compositions of the other Kinds are single objects.
+func getCompositionParentKinds() []string {
+	kinds := make([]string, 0)
+	for _, kind := range getResourceKinds() {
+		if hasCompositionChildKinds(kind) {
+			kinds = append(kinds, strings.TrimSpace(kind))
+		}
+	}
+	return kinds
+}
+
+func hasCompositionChildKinds(kind string) bool {
+	childKinds, _ := getCompositionChildKinds(kind)
+	for _, childKind := range childKinds {
+		if strings.TrimSpace(childKind) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func compositionEventHandler(kind string) cache.ResourceEventHandlerFuncs {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			onCompositionObjectChanged(kind, obj)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldUnstructured, ok1 := toUnstructured(kind, oldObj)
+			newUnstructured, ok2 := toUnstructured(kind, newObj)
+			if ok1 && ok2 && !compositionObjectChanged(oldUnstructured, newUnstructured) {
+				return
+			}
+			onCompositionObjectChanged(kind, newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			onCompositionObjectDeleted(kind, obj)
+		},
+	}
+}
+
+// Compositions hold the owners, phase and health of the objects, so updates that
+// change none of them (e.g. heartbeats and condition timestamps) are skipped.
+func compositionObjectChanged(oldObj, newObj *unstructured.Unstructured) bool {
+	if oldObj.GetResourceVersion() == newObj.GetResourceVersion() {
+		return false
+	}
+	oldMetaData := newMetaDataAndOwnerReferences(*oldObj, "")
+	newMetaData := newMetaDataAndOwnerReferences(*newObj, "")
+	return !reflect.DeepEqual(oldMetaData, newMetaData)
+}
+
+func onCompositionObjectChanged(kind string, obj interface{}) {
+	unstructuredObj, ok := toUnstructured(kind, obj)
+	if !ok {
+		return
+	}
+	compositionIndexMux.Lock()
+	defer compositionIndexMux.Unlock()
+	if !compositionIndexReady {
+		return
+	}
+	refreshComposition(kind, unstructuredObj.GetName(), unstructuredObj.GetNamespace(), 0, map[string]bool{})
+}
+
+func onCompositionObjectDeleted(kind string, obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	unstructuredObj, ok := toUnstructured(kind, obj)
+	if !ok {
+		return
+	}
+	compositionIndexMux.Lock()
+	defer compositionIndexMux.Unlock()
+	if !compositionIndexReady {
+		return
+	}
+	namespace := unstructuredObj.GetNamespace()
+	if hasCompositionChildKinds(kind) {
+		TotalClusterCompositions.removeCompositions(string(unstructuredObj.GetUID()))
+	}
+	visited := map[string]bool{string(unstructuredObj.GetUID()): true}
+	for _, ownerReference := range unstructuredObj.GetOwnerReferences() {
+		refreshComposition(ownerReference.Kind, ownerReference.Name, namespace, 1, visited)
+	}
+}
+
+// Rebuilds the stored compositions that include the given object: its own, when
+// its Kind has a composition, and those of the owners above it in its owner chain.
+// Owners are looked up in the informer stores. Objects of Kinds without compositions
+// have no stored composition of their own. The visited set holds the UIDs already
+// refreshed, so that an owner reached through several children is rebuilt only once.
+func refreshComposition(kind, name, namespace string, depth int, visited map[string]bool) {
+	if depth > maxOwnerDepth {
+		return
+	}
+	// Compositions of deleted objects are removed by their delete events
+	obj, found := getIndexedObject(kind, name, namespace)
+	if !found || visited[string(obj.GetUID())] {
+		return
+	}
+	visited[string(obj.GetUID())] = true
+	if hasCompositionChildKinds(kind) {
+		topLevelObject := newMetaDataAndOwnerReferences(*obj, "")
+		level := 1
+		compositionTree := []CompositionTreeNode{}
+		buildCompositions(kind, name, topLevelObject.UID, level, &compositionTree)
+		TotalClusterCompositions.storeCompositions(topLevelObject, kind, name, topLevelObject.Namespace, &compositionTree)
+	}
+
+	for _, ownerReference := range obj.GetOwnerReferences() {
+		refreshComposition(ownerReference.Kind, ownerReference.Name, namespace, depth+1, visited)
+	}
+}
+
+func getIndexedObject(kind, name, namespace string) (*unstructured.Unstructured, bool) {
+	informer, ok := getCompositionInformer(kind)
+	if !ok {
+		return nil, false
+	}
+	keys := []string{name}
+	if namespace != "" {
+		keys = []string{namespace + "/" + name, name}
+	}
+	for _, key := range keys {
+		item, exists, err := informer.GetStore().GetByKey(key)
+		if err != nil || !exists {
+			continue
+		}
+		if obj, ok := toUnstructured(kind, item); ok {
+			return obj, true
+		}
+	}
+	return nil, false
+}
+
+// Objects of the metadata-only informers are converted to unstructured objects of their Kind.
+func toUnstructured(kind string, item interface{}) (*unstructured.Unstructured, bool) {
+	switch obj := item.(type) {
+	case *unstructured.Unstructured:
+		return obj, true
+	case *metav1.PartialObjectMetadata:
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return nil, false
+		}
+		unstructuredObj := &unstructured.Unstructured{Object: content}
+		unstructuredObj.SetKind(kind)
+		return unstructuredObj, true
+	}
+	return nil, false
+}
+
+// listResources returns objects of the given resource in the namespace.
+// When the composition index is on, objects of the indexed Kinds are read from the
+// informer stores. The event handlers list only the indexed Kinds, so the API server
+// is queried only for the compositions of Kinds that are not indexed.
+func listResources(kind, namespace string, res schema.GroupVersionResource) ([]unstructured.Unstructured, error) {
+	objects := make([]unstructured.Unstructured, 0)
+	if IsClusterScoped(kind) {
+		namespace = metav1.NamespaceAll
+	}
+	if informer, ok := getCompositionInformer(kind); ok {
+		items := informer.GetStore().List()
+		if namespace != "" {
+			namespacedItems, err := informer.GetIndexer().ByIndex(cache.NamespaceIndex, namespace)
+			if err != nil {
+				return objects, err
+			}
+			items = namespacedItems
+		}
+		for _, item := range items {
+			if obj, ok := toUnstructured(kind, item); ok {
+				objects = append(objects, *obj)
+			}
+		}
+		return objects, nil
+	}
+
+	compositionListCacheMux.Lock()
+	defer compositionListCacheMux.Unlock()
+	cacheKey := kind + "/" + namespace
+	if compositionListCache != nil {
+		if items, ok := compositionListCache[cacheKey]; ok {
+			return items, nil
+		}
+	}
+
+	dynamicClient, err := getDynamicClient()
+	if err != nil {
+		return objects, err
+	}
+	list, err := dynamicClient.Resource(res).Namespace(namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return objects, err
+	}
+	if compositionListCache != nil {
+		compositionListCache[cacheKey] = list.Items
+	}
+	return list.Items, nil
+}
+
+// While a composition build is in progress, lists that are not served by the
+// composition index are remembered so that each Kind is queried only once.
+func startCompositionListCache() {
+	compositionListCacheMux.Lock()
+	defer compositionListCacheMux.Unlock()
+	compositionListCache = make(map[string][]unstructured.Unstructured)
+}
+
+func stopCompositionListCache() {
+	compositionListCacheMux.Lock()
+	defer compositionListCacheMux.Unlock()
+	compositionListCache = nil
+}
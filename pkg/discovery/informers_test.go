@@ -0,0 +1,182 @@
+package discovery
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestGetIndexedKinds(t *testing.T) {
+	setTestCompositions(t, map[string][]string{"Moodle": {DEPLOYMENT, " " + SECRET}})
+	indexedKinds := getIndexedKinds()
+	for _, kind := range []string{"Moodle", DEPLOYMENT, REPLICA_SET, POD, SECRET, STATEFULSET, PVCLAIM, CONTROLLER_REVISION, CRONJOB, JOB} {
+		if !containsString(indexedKinds, kind) {
+			t.Errorf("%s is not indexed, indexed Kinds are %v", kind, indexedKinds)
+		}
+	}
+	// Kinds that are neither composed of nor part of a composition
+	for _, kind := range []string{CONFIG_MAP, NODE, CRD, CLUSTER_ROLE_BINDING, ENDPOINT_SLICE} {
+		if containsString(indexedKinds, kind) {
+			t.Errorf("%s is indexed, indexed Kinds are %v", kind, indexedKinds)
+		}
+	}
+	parentKinds := getCompositionParentKinds()
+	if containsString(parentKinds, POD) || !containsString(parentKinds, "Moodle") {
+		t.Errorf("parent Kinds are %v", parentKinds)
+	}
+}
+
+func TestToUnstructured(t *testing.T) {
+	secret := &metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{
+		Name:      "moodle1-admin",
+		Namespace: "default",
+		UID:       types.UID("se1"),
+		OwnerReferences: []metav1.OwnerReference{
+			{APIVersion: "moodlecontroller.kubeplus/v1", Kind: "Moodle", Name: "moodle1", UID: types.UID("m1")},
+		},
+	}}
+	obj, ok := toUnstructured(SECRET, secret)
+	if !ok {
+		t.Fatalf("metadata of %s is not converted", secret.Name)
+	}
+	if obj.GetKind() != SECRET || obj.GetName() != "moodle1-admin" || obj.GetNamespace() != "default" {
+		t.Errorf("converted object is %v", obj.Object)
+	}
+	metaData := newMetaDataAndOwnerReferences(*obj, "m1")
+	if metaData.OwnerReferenceKind != "Moodle" || metaData.Health.Status != healthReady {
+		t.Errorf("metadata of the converted object is %+v", metaData)
+	}
+
+	pod := newTestObject(POD, "web-1-a", "default", "p1")
+	if converted, ok := toUnstructured(POD, &pod); !ok || converted != &pod {
+		t.Errorf("unstructured object is not returned as is")
+	}
+	if _, ok := toUnstructured(POD, "web-1-a"); ok {
+		t.Errorf("unexpected object is converted")
+	}
+}
+
+func TestWaitForCompositionInformers(t *testing.T) {
+	synced := func() bool { return true }
+	notSynced := func() bool { return false }
+	stopCh := make(chan struct{})
+	if !waitForCompositionInformers(stopCh, time.Second, synced, synced) {
+		t.Errorf("synced informers are reported as not synced")
+	}
+	if waitForCompositionInformers(stopCh, 200*time.Millisecond, synced, notSynced) {
+		t.Errorf("informer that never syncs is reported as synced")
+	}
+	close(stopCh)
+	if waitForCompositionInformers(stopCh, time.Minute, notSynced) {
+		t.Errorf("informer is reported as synced after the stop")
+	}
+}
+
+func TestIsKnownCRD(t *testing.T) {
+	setTestCustomResourceKind(t)
+	testCases := map[string]bool{
+		"moodles." + testCRGroup:     true,
+		"moodles.other.kubeplus":     false,
+		"wordpresses." + testCRGroup: false,
+		"deployments.apps":           true,
+		"moodles":                    false,
+	}
+	for name, expected := range testCases {
+		if known := isKnownCRD(name); known != expected {
+			t.Errorf("isKnownCRD(%s) = %v, expected %v", name, known, expected)
+		}
+	}
+}
+
+// Informer whose store is filled by the test instead of a watch
+type testCompositionInformer struct {
+	cache.SharedIndexInformer
+}
+
+func (testCompositionInformer) HasSynced() bool {
+	return true
+}
+
+// Serves the indexed Kinds from informer stores holding the given objects and
+// turns the composition index on for the duration of a test.
+func setTestCompositionIndex(t *testing.T, objects ...unstructured.Unstructured) {
+	informers := make(map[string]cache.SharedIndexInformer)
+	for _, kind := range getIndexedKinds() {
+		informers[kind] = testCompositionInformer{cache.NewSharedIndexInformer(&cache.ListWatch{},
+			&unstructured.Unstructured{}, 0, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})}
+	}
+	for i := range objects {
+		if err := informers[objects[i].GetKind()].GetStore().Add(&objects[i]); err != nil {
+			t.Fatalf("cannot add %s to the store: %s", objects[i].GetName(), err.Error())
+		}
+	}
+	savedCompositions := TotalClusterCompositions.clusterCompositions
+	compositionInformers = informers
+	compositionIndexOn = true
+	compositionIndexReady = true
+	TotalClusterCompositions.clusterCompositions = []Compositions{}
+	t.Cleanup(func() {
+		compositionInformers = make(map[string]cache.SharedIndexInformer)
+		compositionIndexOn = false
+		compositionIndexReady = false
+		TotalClusterCompositions.clusterCompositions = savedCompositions
+	})
+}
+
+func storedCompositionRoots() []string {
+	roots := make([]string, 0)
+	for _, compositionItem := range TotalClusterCompositions.clusterCompositions {
+		roots = append(roots, compositionItem.Kind+"/"+compositionItem.Name)
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+func TestRefreshCompositionOfOwners(t *testing.T) {
+	setTestCustomResourceKind(t)
+	setTestCompositions(t, map[string][]string{"Moodle": {DEPLOYMENT, SECRET}})
+	moodle := newTestObject("Moodle", "moodle1", "default", "m1")
+	deployment := newTestObject(DEPLOYMENT, "moodle1", "default", "d1", "Moodle/moodle1/m1")
+	replicaSet := newTestObject(REPLICA_SET, "moodle1-1", "default", "r1", "Deployment/moodle1/d1")
+	pod := newTestObject(POD, "moodle1-1-a", "default", "p1", "ReplicaSet/moodle1-1/r1")
+	_ = unstructured.SetNestedField(pod.Object, "Pending", "status", "phase")
+	secret := newTestObject(SECRET, "moodle1-admin", "default", "se1", "Moodle/moodle1/m1")
+	unrelatedPod := newTestObject(POD, "other", "default", "p2")
+	setTestCompositionIndex(t, moodle, deployment, replicaSet, pod, secret, unrelatedPod)
+
+	onCompositionObjectChanged(POD, &pod)
+	expected := "Deployment/moodle1 Moodle/moodle1 ReplicaSet/moodle1-1"
+	if roots := strings.Join(storedCompositionRoots(), " "); roots != expected {
+		t.Errorf("stored compositions are %s, expected %s", roots, expected)
+	}
+	compositions := TotalClusterCompositions.GetCompositions("Moodle", "moodle1", "default")
+	if len(compositions) != 1 || compositions[0].Health.Status != healthProgressing {
+		t.Errorf("compositions of moodle1 are %v", compositions)
+	}
+
+	// Secrets and Pods get no stored compositions of their own
+	onCompositionObjectChanged(SECRET, &secret)
+	onCompositionObjectChanged(POD, &unrelatedPod)
+	if roots := strings.Join(storedCompositionRoots(), " "); roots != expected {
+		t.Errorf("stored compositions are %s, expected %s", roots, expected)
+	}
+
+	if err := compositionInformers[REPLICA_SET].GetStore().Delete(&replicaSet); err != nil {
+		t.Fatalf("cannot delete %s from the store: %s", replicaSet.GetName(), err.Error())
+	}
+	onCompositionObjectDeleted(REPLICA_SET, &replicaSet)
+	expected = "Deployment/moodle1 Moodle/moodle1"
+	if roots := strings.Join(storedCompositionRoots(), " "); roots != expected {
+		t.Errorf("stored compositions are %s, expected %s", roots, expected)
+	}
+	compositions = TotalClusterCompositions.GetCompositions("Moodle", "moodle1", "default")
+	if len(compositions) != 1 || describeComposition(compositions[0]) != "moodle/moodle1[Deployment/moodle1 Secret/moodle1-admin]" {
+		t.Errorf("compositions of moodle1 are %v", compositions)
+	}
+}
@@ -0,0 +1,336 @@
+package discovery
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Managed-by relationships between custom resources and the workload (Deployment,
+// StatefulSet or DaemonSet) of the operator that reconciles them. The workload is
+// found from, in order:
+//   - the resource/managed-by annotation of the CRD (or managedBy in the kind
+//     composition file), e.g. "Deployment:moodle-operator/moodle-operator"
+//   - the app.kubernetes.io/managed-by label of the custom resource
+//   - the managers in the managedFields of the custom resource
+//   - the ServiceAccounts bound to Roles/ClusterRoles that grant write access to
+//     the resource of the kind in its API group
+// Operators usually run in their own namespace, so workloads are searched in all
+// the namespaces. The label and managedFields names are matched only by workloads
+// in the namespace of the custom resource, or by workloads whose ServiceAccount
+// can write the custom resource, since a name alone is ambiguous across namespaces.
+
+const managedByLabel = "app.kubernetes.io/managed-by"
+
+var (
+	// Kind -> operator workloads declared for the kind
+	managedByMap map[string][]objectReference
+	// UID of a custom resource -> its operator workloads, for the duration of one
+	// connections search (see startManagedBySearch)
+	managedByCache map[string][]managedByTarget
+	// <group>/<resource>/<namespace> -> ServiceAccounts that can write the resource,
+	// so that the RBAC objects are scanned once per search instead of once per custom resource
+	writingServiceAccountsCache map[string][]rbacTarget
+	// Connections searches in flight
+	managedBySearches int
+	// Guards the maps above and managedBySearches, as searches can run concurrently in server mode
+	managedByMux sync.Mutex
+
+	managedByWorkloadKinds = []string{"Deployment", "StatefulSet", "DaemonSet"}
+	writeVerbs             = []string{"*", "create", "update", "patch", "delete"}
+	// Field managers that are not operators
+	ignoredFieldManagers = []string{"kubectl", "kubectl-client-side-apply", "kubectl-create", "kubectl-edit",
+		"kubectl-patch", "kubectl-label", "kubectl-annotate", "kube-controller-manager", "helm", "before-first-apply"}
+)
+
+// An operator workload of a custom resource and how it was found
+type managedByTarget struct {
+	Kind      string
+	Name      string
+	Namespace string
+	Detail    string
+}
+
+func init() {
+	managedByMap = make(map[string][]objectReference)
+	resetManagedByCache()
+}
+
+// Called when a connections search starts, so that changes to the custom resources,
+// workloads and RBAC objects since the previous search are seen. The caches are
+// dropped only when no other search is in flight; overlapping searches share them.
+func startManagedBySearch() {
+	managedByMux.Lock()
+	defer managedByMux.Unlock()
+	if managedBySearches == 0 {
+		resetManagedByCacheLocked()
+	}
+	managedBySearches = managedBySearches + 1
+}
+
+func endManagedBySearch() {
+	managedByMux.Lock()
+	defer managedByMux.Unlock()
+	managedBySearches = managedBySearches - 1
+}
+
+func resetManagedByCache() {
+	managedByMux.Lock()
+	defer managedByMux.Unlock()
+	resetManagedByCacheLocked()
+}
+
+func resetManagedByCacheLocked() {
+	managedByCache = make(map[string][]managedByTarget)
+	writingServiceAccountsCache = make(map[string][]rbacTarget)
+}
+
+func getManagedByRelationship() RelationshipRule {
+	return mustParseRelationshipRule("managedby, on:" + strings.Join(managedByWorkloadKinds, "; "))
+}
+
+// Parses "<Kind>:<namespace>/<name>"; the kind defaults to Deployment.
+func parseManagedBy(kind, value string) {
+	refs := make([]objectReference, 0)
+	for _, workload := range strings.Split(value, ",") {
+		workload = strings.TrimSpace(workload)
+		if workload == "" {
+			continue
+		}
+		ref := objectReference{Kind: DEPLOYMENT}
+		if parts := strings.SplitN(workload, ":", 2); len(parts) == 2 {
+			ref.Kind = strings.TrimSpace(parts[0])
+			workload = strings.TrimSpace(parts[1])
+		}
+		parts := strings.SplitN(workload, "/", 2)
+		if len(parts) != 2 || !containsString(managedByWorkloadKinds, ref.Kind) {
+			fmt.Printf("Ignoring managed-by of %s: %q should be of the form <Kind>:<namespace>/<name>\n", kind, workload)
+			continue
+		}
+		ref.Namespace = parts[0]
+		ref.Name = parts[1]
+		refs = append(refs, ref)
+	}
+	managedByMux.Lock()
+	defer managedByMux.Unlock()
+	managedByMap[kind] = refs
+}
+
+func searchManagedBy(level int, kind, instance, namespace, targetKind, targetInstance string) ([]Connection, string) {
+	relativesNames := make([]Connection, 0)
+	relDetail := ""
+	crNamespace := namespace
+	if instance == "*" {
+		// Custom resources of all the namespaces can be managed by the workload
+		crNamespace = ""
+	}
+	for _, crObj := range listKindObjects(kind, crNamespace) {
+		if instance != "*" && crObj.GetName() != instance {
+			continue
+		}
+		for _, target := range getManagedByTargets(kind, crObj) {
+			if target.Kind != targetKind || (targetInstance != "*" && target.Name != targetInstance) {
+				continue
+			}
+			if targetInstance != "*" && target.Namespace != namespace {
+				continue
+			}
+			relDetail = target.Detail + " Logs:kubectl logs -n " + target.Namespace + " " + strings.ToLower(target.Kind) + "/" + target.Name
+			crConn := Connection{Name: crObj.GetName(), Kind: kind, Namespace: crObj.GetNamespace()}
+			targetConn := Connection{Name: target.Name, Kind: target.Kind, Namespace: target.Namespace}
+			conn, peer := targetConn, crConn
+			if instance == "*" {
+				conn, peer = crConn, targetConn
+			}
+			conn.Level = level
+			conn.RelationType = relTypeManagedBy
+			conn.RelationDetails = relDetail
+			conn.Peer = &peer
+			relativesNames = append(relativesNames, conn)
+		}
+	}
+	return relativesNames, relDetail
+}
+
+// The declared workloads are used when there are any; otherwise the heuristics.
+func getManagedByTargets(kind string, crObj unstructured.Unstructured) []managedByTarget {
+	cacheKey := string(crObj.GetUID())
+	managedByMux.Lock()
+	cachedTargets, cached := managedByCache[cacheKey]
+	refs := managedByMap[kind]
+	managedByMux.Unlock()
+	if cached {
+		return cachedTargets
+	}
+	targets := make([]managedByTarget, 0)
+	addTarget := func(workload unstructured.Unstructured, workloadKind, detail string) {
+		for _, target := range targets {
+			if target.Kind == workloadKind && target.Name == workload.GetName() && target.Namespace == workload.GetNamespace() {
+				return
+			}
+		}
+		targets = append(targets, managedByTarget{Kind: workloadKind, Name: workload.GetName(), Namespace: workload.GetNamespace(), Detail: detail})
+	}
+
+	for _, ref := range refs {
+		for _, workload := range listKindObjects(ref.Kind, ref.Namespace) {
+			if workload.GetName() == ref.Name && workload.GetNamespace() == ref.Namespace {
+				addTarget(workload, ref.Kind, "annotation:"+MANAGED_BY_ANNOTATION)
+			}
+		}
+	}
+	if len(targets) > 0 {
+		storeManagedByTargets(cacheKey, targets)
+		return targets
+	}
+
+	plural, _, _, group := getKindAPIDetails(kind)
+	writingServiceAccounts := getCachedWritingServiceAccounts(group, plural, crObj.GetNamespace())
+	canWrite := func(workload unstructured.Unstructured) bool {
+		serviceAccountName := getWorkloadServiceAccountName(workload)
+		for _, serviceAccount := range writingServiceAccounts {
+			if serviceAccount.Name == serviceAccountName && serviceAccount.Namespace == workload.GetNamespace() {
+				return true
+			}
+		}
+		return false
+	}
+	for _, workloadKind := range managedByWorkloadKinds {
+		for _, workload := range listKindObjects(workloadKind, "") {
+			if workload.GetNamespace() != crObj.GetNamespace() && !canWrite(workload) {
+				continue
+			}
+			managedBy := crObj.GetLabels()[managedByLabel]
+			if managedBy != "" && !isIgnoredFieldManager(managedBy) &&
+				(workload.GetName() == managedBy || workload.GetLabels()["app.kubernetes.io/name"] == managedBy) {
+				addTarget(workload, workloadKind, "label:"+managedByLabel+"="+managedBy)
+			}
+			for _, managedField := range crObj.GetManagedFields() {
+				manager := managedField.Manager
+				if manager != "" && !isIgnoredFieldManager(manager) && workload.GetName() == manager {
+					addTarget(workload, workloadKind, "managedFields manager:"+manager)
+				}
+			}
+		}
+	}
+
+	for _, serviceAccount := range writingServiceAccounts {
+		for _, workloadKind := range managedByWorkloadKinds {
+			for _, workload := range listKindObjects(workloadKind, serviceAccount.Namespace) {
+				if getWorkloadServiceAccountName(workload) == serviceAccount.Name {
+					addTarget(workload, workloadKind, "rbac ServiceAccount:"+serviceAccount.Namespace+"/"+serviceAccount.Name+" "+serviceAccount.Detail)
+				}
+			}
+		}
+	}
+	storeManagedByTargets(cacheKey, targets)
+	return targets
+}
+
+func storeManagedByTargets(cacheKey string, targets []managedByTarget) {
+	managedByMux.Lock()
+	defer managedByMux.Unlock()
+	managedByCache[cacheKey] = targets
+}
+
+func getCachedWritingServiceAccounts(group, resource, namespace string) []rbacTarget {
+	cacheKey := group + "/" + resource + "/" + namespace
+	managedByMux.Lock()
+	serviceAccounts, cached := writingServiceAccountsCache[cacheKey]
+	managedByMux.Unlock()
+	if cached {
+		return serviceAccounts
+	}
+	serviceAccounts = getWritingServiceAccounts(group, resource, namespace)
+	managedByMux.Lock()
+	defer managedByMux.Unlock()
+	writingServiceAccountsCache[cacheKey] = serviceAccounts
+	return serviceAccounts
+}
+
+// Pods without a serviceAccountName run as the default ServiceAccount
+func getWorkloadServiceAccountName(workload unstructured.Unstructured) string {
+	serviceAccountName, _, _ := unstructured.NestedString(workload.UnstructuredContent(), "spec", "template", "spec", "serviceAccountName")
+	if serviceAccountName == "" {
+		return "default"
+	}
+	return serviceAccountName
+}
+
+func isIgnoredFieldManager(manager string) bool {
+	return containsString(ignoredFieldManagers, strings.ToLower(manager))
+}
+
+// ServiceAccounts bound to a Role/ClusterRole that can write the resource of the
+// group in the namespace. Only rules that name the group are taken, as every custom
+// resource can be written with cluster-admin like wildcard rules. RoleBindings grant
+// access in their own namespace only, so those of other namespaces are skipped;
+// for cluster-scoped resources (empty namespace) only ClusterRoleBindings count.
+func getWritingServiceAccounts(group, resource, namespace string) []rbacTarget {
+	serviceAccounts := make([]rbacTarget, 0)
+	if resource == "" {
+		return serviceAccounts
+	}
+	writingRoles := make(map[string]bool)
+	for _, roleKind := range []string{ROLE, CLUSTER_ROLE} {
+		for _, role := range listKindObjects(roleKind, "") {
+			if grantsWrite(role, group, resource) {
+				writingRoles[roleKind+"/"+role.GetNamespace()+"/"+role.GetName()] = true
+			}
+		}
+	}
+	if len(writingRoles) == 0 {
+		return serviceAccounts
+	}
+	for _, bindingKind := range []string{ROLE_BINDING, CLUSTER_ROLE_BINDING} {
+		for _, binding := range listKindObjects(bindingKind, "") {
+			if bindingKind == ROLE_BINDING && binding.GetNamespace() != namespace {
+				continue
+			}
+			targets := getRoleBindingTargets(binding)
+			roleDetail := ""
+			for _, target := range targets {
+				if (target.Kind == ROLE || target.Kind == CLUSTER_ROLE) && writingRoles[target.Kind+"/"+target.Namespace+"/"+target.Name] {
+					roleDetail = "via " + bindingKind + ":" + binding.GetName() + " " + target.Kind + ":" + target.Name
+				}
+			}
+			if roleDetail == "" {
+				continue
+			}
+			for _, target := range targets {
+				if target.Kind == SERVICE_ACCOUNT {
+					target.Detail = roleDetail
+					serviceAccounts = append(serviceAccounts, target)
+				}
+			}
+		}
+	}
+	return serviceAccounts
+}
+
+func grantsWrite(role unstructured.Unstructured, group, resource string) bool {
+	rules, _, _ := unstructured.NestedSlice(role.UnstructuredContent(), "rules")
+	for _, r := range rules {
+		rule, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		apiGroups, _, _ := unstructured.NestedStringSlice(rule, "apiGroups")
+		resources, _, _ := unstructured.NestedStringSlice(rule, "resources")
+		verbs, _, _ := unstructured.NestedStringSlice(rule, "verbs")
+		if !containsString(apiGroups, group) {
+			continue
+		}
+		if !containsString(resources, resource) && !containsString(resources, "*") {
+			continue
+		}
+		for _, verb := range verbs {
+			if containsString(writeVerbs, verb) {
+				return true
+			}
+		}
+	}
+	return false
+}
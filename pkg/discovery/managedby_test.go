@@ -0,0 +1,286 @@
+package discovery
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const testCRGroup = "moodlecontroller.kubeplus"
+
+// Registers the Moodle custom resource kind for the duration of a test.
+func setTestCustomResourceKind(t *testing.T) {
+	KindPluralMap["Moodle"] = "moodles"
+	kindVersionMap["Moodle"] = "apis/" + testCRGroup + "/v1"
+	kindGroupMap["Moodle"] = testCRGroup
+	t.Cleanup(func() {
+		delete(KindPluralMap, "Moodle")
+		delete(kindVersionMap, "Moodle")
+		delete(kindGroupMap, "Moodle")
+		delete(managedByMap, "Moodle")
+		resetManagedByCache()
+	})
+	resetManagedByCache()
+}
+
+func newTestCustomResource(namespace string, labels map[string]string, managers ...string) unstructured.Unstructured {
+	crObj := newTestObject("Moodle", "moodle1", namespace, "m1")
+	crObj.SetLabels(labels)
+	managedFields := make([]metav1.ManagedFieldsEntry, 0)
+	for _, manager := range managers {
+		managedFields = append(managedFields, metav1.ManagedFieldsEntry{Manager: manager, Operation: metav1.ManagedFieldsOperationUpdate})
+	}
+	crObj.SetManagedFields(managedFields)
+	return crObj
+}
+
+func newTestWorkload(kind, name, namespace, serviceAccountName string, labels map[string]string) unstructured.Unstructured {
+	workload := newTestObject(kind, name, namespace, kind+"-"+namespace+"-"+name)
+	workload.SetLabels(labels)
+	if serviceAccountName != "" {
+		_ = unstructured.SetNestedField(workload.Object, serviceAccountName, "spec", "template", "spec", "serviceAccountName")
+	}
+	return workload
+}
+
+// A role that can update Moodles
+func newTestWritingRole(kind, name, namespace string) unstructured.Unstructured {
+	role := newTestObject(kind, name, namespace, kind+"-"+namespace+"-"+name)
+	_ = unstructured.SetNestedSlice(role.Object, []interface{}{
+		map[string]interface{}{
+			"apiGroups": []interface{}{testCRGroup},
+			"resources": []interface{}{"moodles"},
+			"verbs":     []interface{}{"get", "update"},
+		},
+	}, "rules")
+	return role
+}
+
+func newTestServiceAccountBinding(kind, name, namespace, roleKind, roleName, serviceAccountNamespace, serviceAccountName string) unstructured.Unstructured {
+	binding := newTestObject(kind, name, namespace, kind+"-"+namespace+"-"+name)
+	_ = unstructured.SetNestedSlice(binding.Object, []interface{}{
+		map[string]interface{}{"kind": SERVICE_ACCOUNT, "name": serviceAccountName, "namespace": serviceAccountNamespace},
+	}, "subjects")
+	_ = unstructured.SetNestedMap(binding.Object, map[string]interface{}{"kind": roleKind, "name": roleName}, "roleRef")
+	return binding
+}
+
+func TestGetManagedByTargets(t *testing.T) {
+	managedByOperator := map[string]string{managedByLabel: "moodle-operator"}
+	clusterWideOperator := []unstructured.Unstructured{
+		newTestWritingRole(CLUSTER_ROLE, "moodle-writer", ""),
+		newTestServiceAccountBinding(CLUSTER_ROLE_BINDING, "moodle-writer", "", CLUSTER_ROLE, "moodle-writer", "operators", "moodle-operator"),
+		newTestWorkload(DEPLOYMENT, "moodle-operator", "operators", "moodle-operator", nil),
+	}
+
+	testCases := []struct {
+		name      string
+		crObj     unstructured.Unstructured
+		managedBy string
+		objects   []unstructured.Unstructured
+		expected  []managedByTarget
+	}{
+		{
+			name:  "label matched in the namespace of the custom resource only",
+			crObj: newTestCustomResource("default", managedByOperator),
+			objects: []unstructured.Unstructured{
+				newTestWorkload(DEPLOYMENT, "moodle-operator", "default", "", nil),
+				newTestWorkload(DEPLOYMENT, "moodle-operator", "other", "", nil),
+			},
+			expected: []managedByTarget{
+				{Kind: DEPLOYMENT, Name: "moodle-operator", Namespace: "default", Detail: "label:" + managedByLabel + "=moodle-operator"},
+			},
+		},
+		{
+			name:  "label matched by app.kubernetes.io/name",
+			crObj: newTestCustomResource("default", managedByOperator),
+			objects: []unstructured.Unstructured{
+				newTestWorkload(STATEFULSET, "operator", "default", "", map[string]string{"app.kubernetes.io/name": "moodle-operator"}),
+				newTestWorkload(STATEFULSET, "operator", "other", "", map[string]string{"app.kubernetes.io/name": "moodle-operator"}),
+			},
+			expected: []managedByTarget{
+				{Kind: STATEFULSET, Name: "operator", Namespace: "default", Detail: "label:" + managedByLabel + "=moodle-operator"},
+			},
+		},
+		{
+			name:  "managedFields manager matched in the namespace of the custom resource only",
+			crObj: newTestCustomResource("default", nil, "kubectl-client-side-apply", "moodle-operator"),
+			objects: []unstructured.Unstructured{
+				newTestWorkload(DAEMONSET, "moodle-operator", "default", "", nil),
+				newTestWorkload(DEPLOYMENT, "moodle-operator", "other", "", nil),
+				newTestWorkload(DEPLOYMENT, "kubectl-client-side-apply", "default", "", nil),
+			},
+			expected: []managedByTarget{
+				{Kind: DAEMONSET, Name: "moodle-operator", Namespace: "default", Detail: "managedFields manager:moodle-operator"},
+			},
+		},
+		{
+			name:  "ignored field managers",
+			crObj: newTestCustomResource("default", map[string]string{managedByLabel: "Helm"}, "kubectl", "kube-controller-manager"),
+			objects: []unstructured.Unstructured{
+				newTestWorkload(DEPLOYMENT, "helm", "default", "", nil),
+				newTestWorkload(DEPLOYMENT, "kubectl", "default", "", nil),
+				newTestWorkload(DEPLOYMENT, "kube-controller-manager", "default", "", nil),
+			},
+			expected: []managedByTarget{},
+		},
+		{
+			name:  "operator of another namespace verified through its ServiceAccount",
+			crObj: newTestCustomResource("default", managedByOperator),
+			objects: append([]unstructured.Unstructured{
+				newTestWorkload(DEPLOYMENT, "moodle-operator", "other", "moodle-operator", nil),
+			}, clusterWideOperator...),
+			expected: []managedByTarget{
+				{Kind: DEPLOYMENT, Name: "moodle-operator", Namespace: "operators", Detail: "label:" + managedByLabel + "=moodle-operator"},
+			},
+		},
+		{
+			name:    "ServiceAccount that can write the custom resource",
+			crObj:   newTestCustomResource("default", nil),
+			objects: clusterWideOperator,
+			expected: []managedByTarget{
+				{Kind: DEPLOYMENT, Name: "moodle-operator", Namespace: "operators",
+					Detail: "rbac ServiceAccount:operators/moodle-operator via ClusterRoleBinding:moodle-writer ClusterRole:moodle-writer"},
+			},
+		},
+		{
+			name:  "RoleBinding in the namespace of the custom resource",
+			crObj: newTestCustomResource("default", nil),
+			objects: []unstructured.Unstructured{
+				newTestWritingRole(CLUSTER_ROLE, "moodle-writer", ""),
+				newTestServiceAccountBinding(ROLE_BINDING, "moodle-writer", "default", CLUSTER_ROLE, "moodle-writer", "operators", "moodle-operator"),
+				newTestWorkload(DEPLOYMENT, "moodle-operator", "operators", "moodle-operator", nil),
+			},
+			expected: []managedByTarget{
+				{Kind: DEPLOYMENT, Name: "moodle-operator", Namespace: "operators",
+					Detail: "rbac ServiceAccount:operators/moodle-operator via RoleBinding:moodle-writer ClusterRole:moodle-writer"},
+			},
+		},
+		{
+			name:  "RoleBinding of another namespace",
+			crObj: newTestCustomResource("default", managedByOperator),
+			objects: []unstructured.Unstructured{
+				newTestWritingRole(ROLE, "moodle-writer", "other"),
+				newTestServiceAccountBinding(ROLE_BINDING, "moodle-writer", "other", ROLE, "moodle-writer", "other", "moodle-operator"),
+				newTestWorkload(DEPLOYMENT, "moodle-operator", "other", "moodle-operator", nil),
+			},
+			expected: []managedByTarget{},
+		},
+		{
+			name:  "default ServiceAccount",
+			crObj: newTestCustomResource("default", nil),
+			objects: []unstructured.Unstructured{
+				newTestWritingRole(CLUSTER_ROLE, "moodle-writer", ""),
+				newTestServiceAccountBinding(CLUSTER_ROLE_BINDING, "moodle-writer", "", CLUSTER_ROLE, "moodle-writer", "operators", "default"),
+				newTestWorkload(DEPLOYMENT, "moodle-operator", "operators", "", nil),
+			},
+			expected: []managedByTarget{
+				{Kind: DEPLOYMENT, Name: "moodle-operator", Namespace: "operators",
+					Detail: "rbac ServiceAccount:operators/default via ClusterRoleBinding:moodle-writer ClusterRole:moodle-writer"},
+			},
+		},
+		{
+			name:  "cluster-scoped custom resource",
+			crObj: newTestCustomResource("", managedByOperator),
+			objects: append([]unstructured.Unstructured{
+				newTestWorkload(DEPLOYMENT, "moodle-operator", "default", "", nil),
+			}, clusterWideOperator...),
+			expected: []managedByTarget{
+				{Kind: DEPLOYMENT, Name: "moodle-operator", Namespace: "operators", Detail: "label:" + managedByLabel + "=moodle-operator"},
+			},
+		},
+		{
+			name:      "declared workload",
+			crObj:     newTestCustomResource("default", managedByOperator),
+			managedBy: "Deployment:operators/moodle-operator",
+			objects: append([]unstructured.Unstructured{
+				newTestWorkload(DEPLOYMENT, "moodle-operator", "default", "", nil),
+			}, clusterWideOperator...),
+			expected: []managedByTarget{
+				{Kind: DEPLOYMENT, Name: "moodle-operator", Namespace: "operators", Detail: "annotation:" + MANAGED_BY_ANNOTATION},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			setTestCustomResourceKind(t)
+			setTestDynamicClient(t, testCase.objects...)
+			parseManagedBy("Moodle", testCase.managedBy)
+
+			targets := getManagedByTargets("Moodle", testCase.crObj)
+			if !reflect.DeepEqual(targets, testCase.expected) {
+				t.Errorf("getManagedByTargets = %+v, expected %+v", targets, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestManagedByCacheIsResetPerSearch(t *testing.T) {
+	setTestCustomResourceKind(t)
+	setTestDynamicClient(t,
+		newTestWorkload(DEPLOYMENT, "moodle-operator", "default", "", nil),
+		newTestWorkload(DEPLOYMENT, "moodle-operator-v2", "operators", "", nil),
+	)
+	crObj := newTestCustomResource("default", map[string]string{managedByLabel: "moodle-operator"})
+	parseManagedBy("Moodle", "")
+
+	targets := getManagedByTargets("Moodle", crObj)
+	if len(targets) != 1 || targets[0].Name != "moodle-operator" {
+		t.Fatalf("getManagedByTargets = %+v, expected Deployment moodle-operator", targets)
+	}
+
+	// Within a search the targets of a custom resource are looked up once
+	parseManagedBy("Moodle", "Deployment:operators/moodle-operator-v2")
+	if cached := getManagedByTargets("Moodle", crObj); !reflect.DeepEqual(cached, targets) {
+		t.Errorf("getManagedByTargets = %+v, expected the cached %+v", cached, targets)
+	}
+
+	savedOrigLevel, savedOutputFormat := OrigLevel, OutputFormat
+	t.Cleanup(func() { OrigLevel, OutputFormat = savedOrigLevel, savedOutputFormat })
+	OrigLevel, OutputFormat = 0, "json"
+	_ = GetRelatives([]Connection{}, OrigLevel+1, "Moodle", "none", "Moodle", "none", "default", "")
+	targets = getManagedByTargets("Moodle", crObj)
+	if len(targets) != 1 || targets[0].Name != "moodle-operator-v2" {
+		t.Errorf("getManagedByTargets after a new search = %+v, expected Deployment moodle-operator-v2", targets)
+	}
+}
+
+func TestManagedByCacheIsKeptForSearchesInFlight(t *testing.T) {
+	setTestCustomResourceKind(t)
+	setTestDynamicClient(t,
+		newTestWritingRole(CLUSTER_ROLE, "moodle-writer", ""),
+		newTestServiceAccountBinding(CLUSTER_ROLE_BINDING, "moodle-writer", "", CLUSTER_ROLE, "moodle-writer", "operators", "moodle-operator"),
+		newTestWorkload(DEPLOYMENT, "moodle-operator", "operators", "moodle-operator", nil),
+	)
+	parseManagedBy("Moodle", "")
+	moodle1 := newTestCustomResource("default", nil)
+	moodle2 := newTestObject("Moodle", "moodle2", "default", "m2")
+
+	startManagedBySearch()
+	if targets := getManagedByTargets("Moodle", moodle1); len(targets) != 1 {
+		t.Fatalf("getManagedByTargets = %+v, expected Deployment moodle-operator", targets)
+	}
+	if targets := getManagedByTargets("Moodle", moodle2); len(targets) != 1 {
+		t.Fatalf("getManagedByTargets = %+v, expected Deployment moodle-operator", targets)
+	}
+	// The RBAC objects are scanned once for the custom resources of a namespace
+	if len(writingServiceAccountsCache) != 1 {
+		t.Errorf("writing ServiceAccounts are looked up for %d namespaces, expected 1", len(writingServiceAccountsCache))
+	}
+
+	// A search that starts while another one is in flight keeps its cache
+	startManagedBySearch()
+	if len(managedByCache) != 2 {
+		t.Errorf("cache of the search in flight has %d entries, expected 2", len(managedByCache))
+	}
+	endManagedBySearch()
+	endManagedBySearch()
+
+	startManagedBySearch()
+	defer endManagedBySearch()
+	if len(managedByCache) != 0 || len(writingServiceAccountsCache) != 0 {
+		t.Errorf("caches are not reset when a search starts after the previous one ended")
+	}
+}
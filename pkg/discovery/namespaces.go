@@ -0,0 +1,86 @@
+package discovery
+
+import (
+	"net"
+	"strings"
+)
+
+// Namespace scope of the connections search. Every step of the traversal searches
+// in the namespace of the object it reached. By default the search stays in the
+// input namespace; references that name another namespace (objectrefs with a
+// namespace, ServiceAccount subjects of bindings, Service DNS names such as
+// mysvc.other-ns) are followed only into the namespaces given with --namespaces,
+// or into any namespace with --all-namespaces.
+// The objects that refer to an object are searched in the namespace of the object;
+// for the rules that can refer across namespaces they are also searched in the
+// namespaces given with --namespaces, or in all of them with --all-namespaces.
+
+// Cluster-scoped objects are always in scope. A cluster-scoped input has no
+// input namespace to bound the search.
+func inNamespaceScope(namespace string) bool {
+	if namespace == "" || SearchAllNamespaces || OriginalInputNamespace == "" {
+		return true
+	}
+	return namespace == OriginalInputNamespace || containsString(SearchNamespaces, namespace)
+}
+
+func filterNamespaceScope(connections []Connection) []Connection {
+	inScope := make([]Connection, 0)
+	for _, conn := range connections {
+		if inNamespaceScope(conn.Namespace) {
+			inScope = append(inScope, conn)
+		}
+	}
+	return inScope
+}
+
+// Namespaces in which to search the objects that can refer to an object of the namespace.
+// An empty namespace lists the objects of all the namespaces.
+func getReferrerNamespaces(namespace string) []string {
+	if namespace == "" || SearchAllNamespaces {
+		return []string{""}
+	}
+	namespaces := []string{namespace}
+	for _, ns := range SearchNamespaces {
+		if !containsString(namespaces, ns) {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// Name and namespace of the Service of a DNS name: mysvc, mysvc.ns, mysvc.ns.svc or
+// mysvc.ns.svc.cluster.local, also as a URL or with a port. A name without namespace
+// is in the namespace of the object that uses it. As any host name of two labels
+// (example.com) has the mysvc.ns form, it is taken only when the namespace exists.
+func parseServiceDNSName(value, namespace string) (string, string) {
+	if i := strings.Index(value, "://"); i >= 0 {
+		value = value[i+3:]
+	}
+	if i := strings.IndexAny(value, "/?"); i >= 0 {
+		value = value[:i]
+	}
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	parts := strings.Split(value, ".")
+	if len(parts) == 1 {
+		return parts[0], namespace
+	}
+	if len(parts) == 2 && namespaceExists(parts[1]) {
+		return parts[0], parts[1]
+	}
+	if len(parts) > 2 && parts[2] == "svc" {
+		return parts[0], parts[1]
+	}
+	return "", ""
+}
+
+func namespaceExists(name string) bool {
+	for _, namespaceObj := range listKindObjects(NAMESPACE, "") {
+		if namespaceObj.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
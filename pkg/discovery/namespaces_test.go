@@ -0,0 +1,131 @@
+package discovery
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseServiceDNSName(t *testing.T) {
+	setTestDynamicClient(t, newTestObject(NAMESPACE, "db", "", "n1"), newTestObject(NAMESPACE, "frontend", "", "n2"))
+	testCases := []struct {
+		value             string
+		expectedName      string
+		expectedNamespace string
+	}{
+		{"mysql", "mysql", "default"},
+		{"mysql.db", "mysql", "db"},
+		{"mysql.db.svc", "mysql", "db"},
+		{"mysql.db.svc.cluster.local", "mysql", "db"},
+		{"mysql:3306", "mysql", "default"},
+		{"mysql.db:3306", "mysql", "db"},
+		{"mysql.db.svc:3306", "mysql", "db"},
+		{"mysql.db.svc.cluster.local:3306", "mysql", "db"},
+		{"http://web.frontend", "web", "frontend"},
+		{"https://web.frontend.svc.cluster.local:8443/api/v1?watch=true", "web", "frontend"},
+		{"redis://cache:6379/0", "cache", "default"},
+		// Other host names are not Services
+		{"example.com", "", ""},
+		{"https://api.github/repos", "", ""},
+		{"mysql.missing", "", ""},
+		{"api.example.com", "", ""},
+		{"web.frontend.pod.cluster.local", "", ""},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.value, func(t *testing.T) {
+			name, namespace := parseServiceDNSName(testCase.value, "default")
+			if name != testCase.expectedName || namespace != testCase.expectedNamespace {
+				t.Errorf("parseServiceDNSName = %q, %q, expected %q, %q",
+					name, namespace, testCase.expectedName, testCase.expectedNamespace)
+			}
+		})
+	}
+}
+
+// Sets the namespace flags of the connections search for the duration of a test.
+func setTestSearchNamespaces(t *testing.T, inputNamespace string, searchNamespaces []string, allNamespaces bool) {
+	savedInput, savedSearch, savedAll := OriginalInputNamespace, SearchNamespaces, SearchAllNamespaces
+	t.Cleanup(func() {
+		OriginalInputNamespace, SearchNamespaces, SearchAllNamespaces = savedInput, savedSearch, savedAll
+	})
+	OriginalInputNamespace, SearchNamespaces, SearchAllNamespaces = inputNamespace, searchNamespaces, allNamespaces
+}
+
+func TestNamespaceScope(t *testing.T) {
+	connections := []Connection{
+		{Kind: POD, Name: "web-1", Namespace: "frontend"},
+		{Kind: SERVICE, Name: "mysql", Namespace: "db"},
+		{Kind: SECRET, Name: "tls", Namespace: "other"},
+		{Kind: NODE, Name: "node1"},
+	}
+	testCases := []struct {
+		name              string
+		searchNamespaces  []string
+		allNamespaces     bool
+		expectedInScope   []string
+		expectedReferrers []string
+	}{
+		{
+			name:              "no flags",
+			expectedInScope:   []string{"web-1", "node1"},
+			expectedReferrers: []string{"db"},
+		},
+		{
+			name:              "--namespaces",
+			searchNamespaces:  []string{"db", "cache"},
+			expectedInScope:   []string{"web-1", "mysql", "node1"},
+			expectedReferrers: []string{"db", "cache"},
+		},
+		{
+			name:              "--namespaces with the input namespace",
+			searchNamespaces:  []string{"frontend"},
+			expectedInScope:   []string{"web-1", "node1"},
+			expectedReferrers: []string{"db", "frontend"},
+		},
+		{
+			name:              "--all-namespaces",
+			allNamespaces:     true,
+			expectedInScope:   []string{"web-1", "mysql", "tls", "node1"},
+			expectedReferrers: []string{""},
+		},
+		{
+			name:              "--all-namespaces with --namespaces",
+			searchNamespaces:  []string{"db"},
+			allNamespaces:     true,
+			expectedInScope:   []string{"web-1", "mysql", "tls", "node1"},
+			expectedReferrers: []string{""},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			setTestSearchNamespaces(t, "frontend", testCase.searchNamespaces, testCase.allNamespaces)
+
+			inScope := make([]string, 0)
+			for _, conn := range filterNamespaceScope(connections) {
+				inScope = append(inScope, conn.Name)
+			}
+			if !reflect.DeepEqual(inScope, testCase.expectedInScope) {
+				t.Errorf("filterNamespaceScope = %v, expected %v", inScope, testCase.expectedInScope)
+			}
+			// Referrers of an object in the db namespace
+			if namespaces := getReferrerNamespaces("db"); !reflect.DeepEqual(namespaces, testCase.expectedReferrers) {
+				t.Errorf("getReferrerNamespaces = %v, expected %v", namespaces, testCase.expectedReferrers)
+			}
+			// Cluster-scoped objects are in scope and their referrers are in all the namespaces
+			if !inNamespaceScope("") {
+				t.Errorf("inNamespaceScope(\"\") = false, expected true")
+			}
+			if namespaces := getReferrerNamespaces(""); !reflect.DeepEqual(namespaces, []string{""}) {
+				t.Errorf("getReferrerNamespaces(\"\") = %v, expected [\"\"]", namespaces)
+			}
+		})
+	}
+}
+
+func TestNamespaceScopeOfClusterScopedInput(t *testing.T) {
+	setTestSearchNamespaces(t, "", []string{}, false)
+	for _, namespace := range []string{"", "frontend", "db"} {
+		if !inNamespaceScope(namespace) {
+			t.Errorf("inNamespaceScope(%q) = false, expected true", namespace)
+		}
+	}
+}
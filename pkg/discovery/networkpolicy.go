@@ -0,0 +1,210 @@
+package discovery
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// NetworkPolicy relationships. A policy is connected to the Pods selected by its
+// podSelector (the Pods it applies to) and to the Pods and Namespaces selected by
+// the peers of its ingress (from) and egress (to) rules.
+
+// A Pod or Namespace that a NetworkPolicy refers to, with the reasons
+type networkPolicyTarget struct {
+	Kind      string
+	Name      string
+	Namespace string
+	Details   []string
+}
+
+func searchNetworkPolicies(level int, policyInstance, namespace, targetKind, targetInstance string) ([]Connection, string) {
+	relativesNames := make([]Connection, 0)
+	relDetail := ""
+	policies := listKindObjects(NETWORK_POLICY, namespace)
+	for _, policy := range policies {
+		if policyInstance != "*" && policy.GetName() != policyInstance {
+			continue
+		}
+		for _, target := range getNetworkPolicyTargets(policy) {
+			if target.Kind != targetKind || (targetInstance != "*" && target.Name != targetInstance) {
+				continue
+			}
+			relDetail = strings.Join(target.Details, "; ")
+			policyConn := Connection{Name: policy.GetName(), Kind: NETWORK_POLICY, Namespace: policy.GetNamespace()}
+			targetConn := Connection{Name: target.Name, Kind: target.Kind, Namespace: target.Namespace}
+			conn, peer := targetConn, policyConn
+			if policyInstance == "*" {
+				conn, peer = policyConn, targetConn
+			}
+			conn.Level = level
+			conn.RelationType = relTypeNetworkPolicy
+			conn.RelationDetails = relDetail
+			conn.Peer = &peer
+			relativesNames = append(relativesNames, conn)
+		}
+	}
+	return relativesNames, relDetail
+}
+
+// Targets are returned sorted; the details of a target list every part of the
+// policy that selects it.
+func getNetworkPolicyTargets(policy unstructured.Unstructured) []networkPolicyTarget {
+	policyNamespace := policy.GetNamespace()
+	content := policy.UnstructuredContent()
+	targets := make(map[string]*networkPolicyTarget)
+	addTarget := func(kind, name, namespace, detail string) {
+		key := kind + "/" + namespace + "/" + name
+		target, ok := targets[key]
+		if !ok {
+			target = &networkPolicyTarget{Kind: kind, Name: name, Namespace: namespace}
+			targets[key] = target
+		}
+		if !containsString(target.Details, detail) {
+			target.Details = append(target.Details, detail)
+		}
+	}
+
+	// The Pods the policy applies to; an empty podSelector selects all the Pods of the namespace.
+	podSelector := getLabelSelectorAtPath(content, "spec.podSelector")
+	if podSelector != nil {
+		for _, pod := range listKindObjects(POD, policyNamespace) {
+			if matchesNetworkPolicySelector(podSelector, pod.GetLabels()) {
+				addTarget(POD, pod.GetName(), pod.GetNamespace(), "applies to podSelector:"+describeNetworkPolicySelector(podSelector))
+			}
+		}
+	}
+
+	for _, direction := range [][]string{{"ingress", "from"}, {"egress", "to"}} {
+		rules, _, _ := unstructured.NestedSlice(content, "spec", direction[0])
+		for _, r := range rules {
+			rule, ok := r.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			ports := describeNetworkPolicyPorts(rule)
+			peers, _, _ := unstructured.NestedSlice(rule, direction[1])
+			for _, p := range peers {
+				peer, ok := p.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				peerPodSelector := toLabelSelector(peer["podSelector"])
+				peerNamespaceSelector := toLabelSelector(peer["namespaceSelector"])
+				if peerPodSelector == nil && peerNamespaceSelector == nil {
+					// ipBlock peers do not refer to any resource
+					continue
+				}
+				detail := direction[0] + " " + direction[1] + " " + describeNetworkPolicyPeer(peerPodSelector, peerNamespaceSelector) + " ports:" + ports
+
+				namespaces := []string{policyNamespace}
+				if peerNamespaceSelector != nil {
+					namespaces = []string{}
+					for _, ns := range listKindObjects(NAMESPACE, "") {
+						if matchesNetworkPolicySelector(peerNamespaceSelector, ns.GetLabels()) {
+							namespaces = append(namespaces, ns.GetName())
+							if peerPodSelector == nil {
+								addTarget(NAMESPACE, ns.GetName(), "", detail)
+							}
+						}
+					}
+				}
+				if peerPodSelector == nil {
+					continue
+				}
+				for _, ns := range namespaces {
+					for _, pod := range listKindObjects(POD, ns) {
+						if matchesNetworkPolicySelector(peerPodSelector, pod.GetLabels()) {
+							addTarget(POD, pod.GetName(), pod.GetNamespace(), detail)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	targetList := make([]networkPolicyTarget, 0)
+	for _, target := range targets {
+		targetList = append(targetList, *target)
+	}
+	sort.Slice(targetList, func(i, j int) bool {
+		lhs, rhs := targetList[i], targetList[j]
+		return lhs.Kind+"/"+lhs.Namespace+"/"+lhs.Name < rhs.Kind+"/"+rhs.Namespace+"/"+rhs.Name
+	})
+	return targetList
+}
+
+// Unlike in label relationships, an empty selector of a NetworkPolicy ({}) selects
+// everything; a missing one (nil) still selects nothing.
+func matchesNetworkPolicySelector(labelSelector *metav1.LabelSelector, objLabels map[string]string) bool {
+	if isEmptyLabelSelector(labelSelector) {
+		return labelSelector != nil
+	}
+	return matchLabelSelector(labelSelector, objLabels)
+}
+
+func describeNetworkPolicySelector(labelSelector *metav1.LabelSelector) string {
+	if isEmptyLabelSelector(labelSelector) {
+		return "all"
+	}
+	return strings.TrimSpace(describeLabelSelector(labelSelector))
+}
+
+func describeNetworkPolicyPeer(podSelector, namespaceSelector *metav1.LabelSelector) string {
+	parts := make([]string, 0)
+	if namespaceSelector != nil {
+		parts = append(parts, "namespaceSelector:"+describeNetworkPolicySelector(namespaceSelector))
+	}
+	if podSelector != nil {
+		parts = append(parts, "podSelector:"+describeNetworkPolicySelector(podSelector))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Ports of a rule as protocol/port[-endPort]; a rule without ports allows all of them.
+func describeNetworkPolicyPorts(rule map[string]interface{}) string {
+	ports, _, _ := unstructured.NestedSlice(rule, "ports")
+	if len(ports) == 0 {
+		return "all"
+	}
+	portList := make([]string, 0)
+	for _, p := range ports {
+		port, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		protocol, found, _ := unstructured.NestedString(port, "protocol")
+		if !found {
+			protocol = "TCP"
+		}
+		portString := protocol
+		if portValue, ok := port["port"]; ok {
+			portString = portString + "/" + fmt.Sprintf("%v", portValue)
+		}
+		if endPort, ok := port["endPort"]; ok {
+			portString = portString + "-" + fmt.Sprintf("%v", endPort)
+		}
+		portList = append(portList, portString)
+	}
+	return strings.Join(portList, ",")
+}
+
+func listKindObjects(kind, namespace string) []unstructured.Unstructured {
+	_, err := getDynamicClient()
+	if err != nil {
+		return []unstructured.Unstructured{}
+	}
+	resourceKindPlural, _, resourceApiVersion, resourceGroup := getKindAPIDetails(kind)
+	res := schema.GroupVersionResource{Group: resourceGroup,
+		Version:  resourceApiVersion,
+		Resource: resourceKindPlural}
+	list, err := getKubeObjectList(kind, namespace, res)
+	if err != nil {
+		return []unstructured.Unstructured{}
+	}
+	return list.Items
+}
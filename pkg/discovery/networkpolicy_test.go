@@ -0,0 +1,172 @@
+package discovery
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newTestLabeledObject(kind, name, namespace string, labels map[string]string) unstructured.Unstructured {
+	obj := newTestObject(kind, name, namespace, kind+"-"+namespace+"-"+name)
+	obj.SetLabels(labels)
+	return obj
+}
+
+func TestMatchesNetworkPolicySelector(t *testing.T) {
+	podLabels := map[string]string{"app": "api", "tier": "backend"}
+	testCases := []struct {
+		name     string
+		selector *metav1.LabelSelector
+		expected bool
+	}{
+		{"missing selector", nil, false},
+		{"empty selector", &metav1.LabelSelector{}, true},
+		{"matching labels", &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}}, true},
+		{"other labels", &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}, false},
+		{"matching expression", &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"backend", "cache"}},
+		}}, true},
+	}
+	for _, testCase := range testCases {
+		if matches := matchesNetworkPolicySelector(testCase.selector, podLabels); matches != testCase.expected {
+			t.Errorf("%s: matchesNetworkPolicySelector = %v, expected %v", testCase.name, matches, testCase.expected)
+		}
+	}
+}
+
+func TestDescribeNetworkPolicyPorts(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rule     map[string]interface{}
+		expected string
+	}{
+		{"no ports", map[string]interface{}{}, "all"},
+		{"port with the default protocol", map[string]interface{}{"ports": []interface{}{
+			map[string]interface{}{"port": int64(8080)},
+		}}, "TCP/8080"},
+		{"named port and port range", map[string]interface{}{"ports": []interface{}{
+			map[string]interface{}{"protocol": "UDP", "port": "dns"},
+			map[string]interface{}{"protocol": "TCP", "port": int64(32000), "endPort": int64(32768)},
+		}}, "UDP/dns,TCP/32000-32768"},
+		{"protocol only", map[string]interface{}{"ports": []interface{}{
+			map[string]interface{}{"protocol": "SCTP"},
+		}}, "SCTP"},
+	}
+	for _, testCase := range testCases {
+		if ports := describeNetworkPolicyPorts(testCase.rule); ports != testCase.expected {
+			t.Errorf("%s: describeNetworkPolicyPorts = %q, expected %q", testCase.name, ports, testCase.expected)
+		}
+	}
+}
+
+// Policy of the api Pods in prod: ingress from the web Pods of prod and staging and from the
+// monitoring namespace, egress to all the namespaces
+func newTestNetworkPolicy() unstructured.Unstructured {
+	policy := newTestObject(NETWORK_POLICY, "api-policy", "prod", "np1")
+	_ = unstructured.SetNestedMap(policy.Object, map[string]interface{}{
+		"podSelector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "api"}},
+		"ingress": []interface{}{
+			map[string]interface{}{
+				"from": []interface{}{
+					map[string]interface{}{"podSelector": map[string]interface{}{"matchLabels": map[string]interface{}{"app": "web"}}},
+					map[string]interface{}{"namespaceSelector": map[string]interface{}{"matchLabels": map[string]interface{}{"team": "ops"}}},
+					map[string]interface{}{
+						"namespaceSelector": map[string]interface{}{"matchLabels": map[string]interface{}{"env": "staging"}},
+						"podSelector":       map[string]interface{}{"matchLabels": map[string]interface{}{"app": "web"}},
+					},
+					map[string]interface{}{"ipBlock": map[string]interface{}{"cidr": "10.0.0.0/8"}},
+				},
+				"ports": []interface{}{map[string]interface{}{"protocol": "TCP", "port": int64(8080)}},
+			},
+		},
+		"egress": []interface{}{
+			map[string]interface{}{
+				"to": []interface{}{map[string]interface{}{"namespaceSelector": map[string]interface{}{}}},
+			},
+		},
+	}, "spec")
+	return policy
+}
+
+func setTestNetworkPolicyObjects(t *testing.T) {
+	setTestDynamicClient(t,
+		newTestNetworkPolicy(),
+		newTestLabeledObject(NAMESPACE, "prod", "", map[string]string{"env": "prod"}),
+		newTestLabeledObject(NAMESPACE, "staging", "", map[string]string{"env": "staging"}),
+		newTestLabeledObject(NAMESPACE, "monitoring", "", map[string]string{"team": "ops"}),
+		newTestLabeledObject(POD, "api-0", "prod", map[string]string{"app": "api"}),
+		newTestLabeledObject(POD, "web-0", "prod", map[string]string{"app": "web"}),
+		newTestLabeledObject(POD, "web-1", "staging", map[string]string{"app": "web"}),
+		newTestLabeledObject(POD, "web-2", "monitoring", map[string]string{"app": "web"}),
+		newTestLabeledObject(POD, "db-0", "prod", map[string]string{"app": "db"}),
+	)
+}
+
+func TestGetNetworkPolicyTargets(t *testing.T) {
+	setTestNetworkPolicyObjects(t)
+	allNamespaces := "egress to namespaceSelector:all ports:all"
+	expected := []networkPolicyTarget{
+		{Kind: NAMESPACE, Name: "monitoring", Details: []string{"ingress from namespaceSelector:team:ops ports:TCP/8080", allNamespaces}},
+		{Kind: NAMESPACE, Name: "prod", Details: []string{allNamespaces}},
+		{Kind: NAMESPACE, Name: "staging", Details: []string{allNamespaces}},
+		{Kind: POD, Name: "api-0", Namespace: "prod", Details: []string{"applies to podSelector:app:api"}},
+		{Kind: POD, Name: "web-0", Namespace: "prod", Details: []string{"ingress from podSelector:app:web ports:TCP/8080"}},
+		{Kind: POD, Name: "web-1", Namespace: "staging", Details: []string{"ingress from namespaceSelector:env:staging podSelector:app:web ports:TCP/8080"}},
+	}
+	if targets := getNetworkPolicyTargets(newTestNetworkPolicy()); !reflect.DeepEqual(targets, expected) {
+		t.Errorf("getNetworkPolicyTargets = %+v, expected %+v", targets, expected)
+	}
+
+	// An empty podSelector applies the policy to all the Pods of its namespace
+	policy := newTestObject(NETWORK_POLICY, "default-deny", "prod", "np2")
+	_ = unstructured.SetNestedMap(policy.Object, map[string]interface{}{}, "spec", "podSelector")
+	pods := make([]string, 0)
+	for _, target := range getNetworkPolicyTargets(policy) {
+		pods = append(pods, target.Kind+"/"+target.Name)
+	}
+	if expectedPods := []string{"Pod/api-0", "Pod/db-0", "Pod/web-0"}; !reflect.DeepEqual(pods, expectedPods) {
+		t.Errorf("targets of default-deny are %v, expected %v", pods, expectedPods)
+	}
+}
+
+func TestSearchNetworkPolicies(t *testing.T) {
+	setTestNetworkPolicyObjects(t)
+	testCases := []struct {
+		name           string
+		policyInstance string
+		targetKind     string
+		targetInstance string
+		expected       []string
+	}{
+		{
+			name: "policies that govern a Pod", policyInstance: "*", targetKind: POD, targetInstance: "api-0",
+			expected: []string{"NetworkPolicy prod/api-policy <- Pod prod/api-0: applies to podSelector:app:api"},
+		},
+		{
+			name: "Pods allowed by a policy", policyInstance: "api-policy", targetKind: POD, targetInstance: "*",
+			expected: []string{
+				"Pod prod/api-0 <- NetworkPolicy prod/api-policy: applies to podSelector:app:api",
+				"Pod prod/web-0 <- NetworkPolicy prod/api-policy: ingress from podSelector:app:web ports:TCP/8080",
+				"Pod staging/web-1 <- NetworkPolicy prod/api-policy: ingress from namespaceSelector:env:staging podSelector:app:web ports:TCP/8080",
+			},
+		},
+		{
+			name: "Namespace allowed by ingress and egress rules", policyInstance: "api-policy", targetKind: NAMESPACE, targetInstance: "monitoring",
+			expected: []string{"Namespace /monitoring <- NetworkPolicy prod/api-policy: ingress from namespaceSelector:team:ops ports:TCP/8080; egress to namespaceSelector:all ports:all"},
+		},
+		{
+			name: "Pod without policies", policyInstance: "*", targetKind: POD, targetInstance: "db-0",
+			expected: []string{},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			connections, _ := searchNetworkPolicies(1, testCase.policyInstance, "prod", testCase.targetKind, testCase.targetInstance)
+			if descriptions := describeConnections(connections); !reflect.DeepEqual(descriptions, testCase.expected) {
+				t.Errorf("searchNetworkPolicies = %v, expected %v", descriptions, testCase.expected)
+			}
+		})
+	}
+}
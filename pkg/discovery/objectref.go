@@ -0,0 +1,205 @@
+package discovery
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Object reference relationships, such as HorizontalPodAutoscaler.spec.scaleTargetRef,
+// Event.involvedObject or Gateway API parentRefs. The kind, group, namespace and name
+// of the target are read from the reference itself ({apiVersion|group|apiGroup, kind, name,
+// namespace}), so one rule can connect to several kinds. The value of the rule lists
+// the kinds the reference can refer to; "*" (or no value) accepts any kind. When a
+// reference has no kind and the rule lists a single kind, that kind is used.
+
+const objectRefAnyKind = "*"
+
+// The target of an object reference
+type objectReference struct {
+	Kind      string
+	Group     string
+	Version   string
+	Name      string
+	Namespace string
+	// Service references of webhooks and APIServices
+	Port string
+	Path string
+}
+
+func searchObjectReferences(level int, kind, instance, namespace string, relRule RelationshipRule, targetKind, targetInstance string) ([]Connection, string) {
+	relativesNames := make([]Connection, 0)
+	relDetail := ""
+	dynamicClient, err := getDynamicClient()
+	if err != nil {
+		return relativesNames, relDetail
+	}
+	lhsResKindPlural, _, lhsResApiVersion, lhsResGroup := getKindAPIDetails(kind)
+	lhsRes := schema.GroupVersionResource{Group: lhsResGroup,
+		Version:  lhsResApiVersion,
+		Resource: lhsResKindPlural}
+	// References can name the namespace of their target, so the objects that refer
+	// to an object are searched in all the namespaces of the search scope.
+	// Cluster-scoped objects, e.g. webhook configurations, are listed across the cluster.
+	lhsNamespaces := []string{namespace}
+	if IsClusterScoped(kind) {
+		lhsNamespaces = []string{""}
+	} else if instance == "*" {
+		lhsNamespaces = getReferrerNamespaces(namespace)
+	}
+	lhsInstList := make([]*unstructured.Unstructured, 0)
+	for _, lhsNamespace := range lhsNamespaces {
+		lhsNamespaceList, err := getObjects(kind, instance, lhsNamespace, lhsRes, dynamicClient)
+		if err != nil {
+			return relativesNames, relDetail
+		}
+		lhsInstList = append(lhsInstList, lhsNamespaceList...)
+	}
+
+	sourceField := trimInstancePrefix(relRule.SourcePath)
+	for _, instanceObj := range lhsInstList {
+		lhsConn := Connection{Name: instanceObj.GetName(), Kind: kind, Namespace: instanceObj.GetNamespace()}
+		for _, ref := range getObjectReferences(*instanceObj, sourceField, relRule) {
+			if targetKind != objectRefAnyKind && ref.Kind != targetKind {
+				continue
+			}
+			if targetInstance != "*" {
+				// Searching for the references to a particular object
+				if ref.Name != targetInstance || (ref.Namespace != "" && ref.Namespace != namespace) {
+					continue
+				}
+			} else if !objectReferenceExists(ref) {
+				continue
+			}
+			relDetail = "Name:" + sourceField + " Kind:" + ref.Kind + " Value:" + ref.Name
+			if ref.Namespace != "" && ref.Namespace != lhsConn.Namespace {
+				relDetail = "Name:" + sourceField + " Kind:" + ref.Kind + " Value:" + ref.Namespace + "/" + ref.Name
+			}
+			if ref.Port != "" {
+				relDetail = relDetail + " Port:" + ref.Port
+			}
+			if ref.Path != "" {
+				relDetail = relDetail + " Path:" + ref.Path
+			}
+			refConn := Connection{Name: ref.Name, Kind: ref.Kind, Namespace: ref.Namespace}
+			conn, peer := refConn, lhsConn
+			if instance == "*" {
+				conn, peer = lhsConn, refConn
+			}
+			conn.Level = level
+			conn.RelationType = relTypeObjectRef
+			conn.RelationDetails = relDetail
+			conn.Peer = &peer
+			relativesNames = appendConnections1(relativesNames, []Connection{conn})
+		}
+	}
+	return relativesNames, relDetail
+}
+
+// Connections grouped by kind, for rules whose target kind is only known from the data.
+func groupConnectionsByKind(connections []Connection) ([]string, map[string][]Connection) {
+	kinds := make([]string, 0)
+	groups := make(map[string][]Connection)
+	for _, conn := range connections {
+		if _, ok := groups[conn.Kind]; !ok {
+			kinds = append(kinds, conn.Kind)
+		}
+		groups[conn.Kind] = append(groups[conn.Kind], conn)
+	}
+	return kinds, groups
+}
+
+func getObjectReferences(instanceObj unstructured.Unstructured, sourceField string, relRule RelationshipRule) []objectReference {
+	refs := make([]objectReference, 0)
+	for _, refMap := range getFieldPathObjects(instanceObj.UnstructuredContent(), sourceField) {
+		ref := objectReference{}
+		ref.Kind, _ = refMap["kind"].(string)
+		ref.Name, _ = refMap["name"].(string)
+		ref.Namespace, _ = refMap["namespace"].(string)
+		ref.Path, _ = refMap["path"].(string)
+		if port, ok := refMap["port"]; ok {
+			ref.Port = fmt.Sprintf("%v", port)
+		}
+		ref.Group, _ = refMap["group"].(string)
+		if ref.Group == "" {
+			// TypedLocalObjectReference, e.g. Ingress resource backends
+			ref.Group, _ = refMap["apiGroup"].(string)
+		}
+		if apiVersion, ok := refMap["apiVersion"].(string); ok && apiVersion != "" {
+			gv, err := schema.ParseGroupVersion(apiVersion)
+			if err == nil {
+				ref.Group = gv.Group
+				ref.Version = gv.Version
+			}
+		}
+		if ref.Kind == "" && len(relRule.TargetKinds) == 1 && relRule.TargetKinds[0] != objectRefAnyKind {
+			ref.Kind = relRule.TargetKinds[0]
+		}
+		if ref.Kind == "" || ref.Name == "" {
+			continue
+		}
+		if !relRule.hasTargetKind(ref.Kind) && !relRule.hasTargetKind(objectRefAnyKind) {
+			continue
+		}
+		if !registerObjectReferenceKind(ref) {
+			continue
+		}
+		if IsClusterScoped(ref.Kind) {
+			ref.Namespace = ""
+		} else if ref.Namespace == "" {
+			ref.Namespace = instanceObj.GetNamespace()
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// Kinds that are neither built-in nor known from CRD annotations are looked up
+// with the discovery API, using the version of the reference or, when the
+// reference only has a group, the preferred version of the group.
+func registerObjectReferenceKind(ref objectReference) bool {
+	if isKnownKind(ref.Kind) {
+		return true
+	}
+	if ref.Version != "" {
+		apiVersion := ref.Version
+		if ref.Group != "" {
+			apiVersion = ref.Group + "/" + ref.Version
+		}
+		return discoverKind(ref.Kind, apiVersion)
+	}
+	if cfg == nil {
+		return false
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return false
+	}
+	groups, err := clientset.Discovery().ServerGroups()
+	if err != nil {
+		return false
+	}
+	for _, group := range groups.Groups {
+		if group.Name == ref.Group {
+			return discoverKind(ref.Kind, group.PreferredVersion.GroupVersion)
+		}
+	}
+	if ref.Group == "" {
+		return discoverKind(ref.Kind, "v1")
+	}
+	return false
+}
+
+func objectReferenceExists(ref objectReference) bool {
+	if _, err := getDynamicClient(); err != nil {
+		return false
+	}
+	resKindPlural, _, resApiVersion, resGroup := getKindAPIDetails(ref.Kind)
+	res := schema.GroupVersionResource{Group: resGroup,
+		Version:  resApiVersion,
+		Resource: resKindPlural}
+	_, err := getKubeObject(ref.Kind, ref.Name, ref.Namespace, res)
+	return err == nil
+}
@@ -0,0 +1,169 @@
+package discovery
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newTestReferrer(kind, name, namespace string, ref map[string]interface{}, fields ...string) unstructured.Unstructured {
+	obj := newTestObject(kind, name, namespace, kind+"-"+namespace+"-"+name)
+	_ = unstructured.SetNestedMap(obj.Object, ref, fields...)
+	return obj
+}
+
+func TestGetObjectReferences(t *testing.T) {
+	hpaRule := mustParseRelationshipRule("objectref, on:INSTANCE.spec.scaleTargetRef, value:Deployment; StatefulSet")
+	serviceRule := mustParseRelationshipRule("objectref, on:INSTANCE.spec.service, value:Service")
+	anyKindRule := mustParseRelationshipRule("objectref, on:INSTANCE.spec.targetRef, value:*")
+	webhook := newTestObject(VALIDATING_WEBHOOK_CONFIGURATION, "policy", "", "vw1")
+	_ = unstructured.SetNestedSlice(webhook.Object, []interface{}{
+		map[string]interface{}{"clientConfig": map[string]interface{}{"service": map[string]interface{}{
+			"name": "policy-webhook", "namespace": "policy", "path": "/validate"}}},
+		map[string]interface{}{"clientConfig": map[string]interface{}{"url": "https://policy.example.com/validate"}},
+	}, "webhooks")
+
+	testCases := []struct {
+		name     string
+		obj      unstructured.Unstructured
+		rule     RelationshipRule
+		expected []objectReference
+	}{
+		{
+			name: "kind and apiVersion of the reference",
+			obj: newTestReferrer(HPA, "web", "prod",
+				map[string]interface{}{"apiVersion": "apps/v1", "kind": DEPLOYMENT, "name": "web"}, "spec", "scaleTargetRef"),
+			rule:     hpaRule,
+			expected: []objectReference{{Kind: DEPLOYMENT, Group: "apps", Version: "v1", Name: "web", Namespace: "prod"}},
+		},
+		{
+			name: "kind that the rule does not list",
+			obj: newTestReferrer(HPA, "web", "prod",
+				map[string]interface{}{"apiVersion": "apps/v1", "kind": DAEMONSET, "name": "web"}, "spec", "scaleTargetRef"),
+			rule:     hpaRule,
+			expected: []objectReference{},
+		},
+		{
+			name: "kind of a single-kind rule, namespace and port of the reference",
+			obj: newTestReferrer(API_SERVICE, "v1beta1.metrics.k8s.io", "",
+				map[string]interface{}{"name": "metrics-server", "namespace": "kube-system", "port": int64(443)}, "spec", "service"),
+			rule:     serviceRule,
+			expected: []objectReference{{Kind: SERVICE, Name: "metrics-server", Namespace: "kube-system", Port: "443"}},
+		},
+		{
+			name:     "references in a list",
+			obj:      webhook,
+			rule:     mustParseRelationshipRule("objectref, on:INSTANCE.webhooks.clientConfig.service, value:Service"),
+			expected: []objectReference{{Kind: SERVICE, Name: "policy-webhook", Namespace: "policy", Path: "/validate"}},
+		},
+		{
+			name: "apiGroup of a typed local object reference",
+			obj: newTestReferrer(INGRESS, "static", "prod",
+				map[string]interface{}{"apiGroup": testCRGroup, "kind": "Moodle", "name": "moodle1"}, "spec", "targetRef"),
+			rule:     anyKindRule,
+			expected: []objectReference{{Kind: "Moodle", Group: testCRGroup, Name: "moodle1", Namespace: "prod"}},
+		},
+		{
+			name: "namespace of a cluster-scoped target",
+			obj: newTestReferrer("Event", "node1.17a", "default",
+				map[string]interface{}{"kind": NODE, "name": "node1", "namespace": "default"}, "spec", "targetRef"),
+			rule:     anyKindRule,
+			expected: []objectReference{{Kind: NODE, Name: "node1"}},
+		},
+		{
+			// Unknown kinds are looked up with the discovery API, which is not available here
+			name: "unknown kind",
+			obj: newTestReferrer(INGRESS, "static", "prod",
+				map[string]interface{}{"apiVersion": "cert-manager.io/v1", "kind": "Issuer", "name": "letsencrypt"}, "spec", "targetRef"),
+			rule:     anyKindRule,
+			expected: []objectReference{},
+		},
+		{
+			name: "reference without a name",
+			obj: newTestReferrer(HPA, "web", "prod",
+				map[string]interface{}{"apiVersion": "apps/v1", "kind": DEPLOYMENT}, "spec", "scaleTargetRef"),
+			rule:     hpaRule,
+			expected: []objectReference{},
+		},
+	}
+	setTestCustomResourceKind(t)
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			sourceField := trimInstancePrefix(testCase.rule.SourcePath)
+			refs := getObjectReferences(testCase.obj, sourceField, testCase.rule)
+			if !reflect.DeepEqual(refs, testCase.expected) {
+				t.Errorf("getObjectReferences = %+v, expected %+v", refs, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestSearchObjectReferences(t *testing.T) {
+	hpaRule := mustParseRelationshipRule("objectref, on:INSTANCE.spec.scaleTargetRef, value:Deployment; StatefulSet")
+	setTestSearchNamespaces(t, "prod", []string{}, false)
+	setTestDynamicClient(t,
+		newTestObject(DEPLOYMENT, "web", "prod", "d1"),
+		newTestObject(DEPLOYMENT, "web", "staging", "d2"),
+		newTestReferrer(HPA, "web", "prod",
+			map[string]interface{}{"apiVersion": "apps/v1", "kind": DEPLOYMENT, "name": "web"}, "spec", "scaleTargetRef"),
+		newTestReferrer(HPA, "api", "prod",
+			map[string]interface{}{"apiVersion": "apps/v1", "kind": STATEFULSET, "name": "api"}, "spec", "scaleTargetRef"),
+		newTestReferrer(HPA, "web", "staging",
+			map[string]interface{}{"apiVersion": "apps/v1", "kind": DEPLOYMENT, "name": "web"}, "spec", "scaleTargetRef"),
+	)
+
+	testCases := []struct {
+		name           string
+		instance       string
+		targetKind     string
+		targetInstance string
+		expected       []string
+	}{
+		{
+			name: "target of a reference", instance: "web", targetKind: DEPLOYMENT, targetInstance: "*",
+			expected: []string{"Deployment prod/web <- HorizontalPodAutoscaler prod/web: Name:spec.scaleTargetRef Kind:Deployment Value:web"},
+		},
+		{
+			name: "target kind from the data", instance: "web", targetKind: objectRefAnyKind, targetInstance: "*",
+			expected: []string{"Deployment prod/web <- HorizontalPodAutoscaler prod/web: Name:spec.scaleTargetRef Kind:Deployment Value:web"},
+		},
+		{
+			name: "target that does not exist", instance: "api", targetKind: STATEFULSET, targetInstance: "*",
+			expected: []string{},
+		},
+		{
+			name: "objects that refer to a target", instance: "*", targetKind: DEPLOYMENT, targetInstance: "web",
+			expected: []string{"HorizontalPodAutoscaler prod/web <- Deployment prod/web: Name:spec.scaleTargetRef Kind:Deployment Value:web"},
+		},
+		{
+			name: "other target kind", instance: "web", targetKind: STATEFULSET, targetInstance: "*",
+			expected: []string{},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			connections, _ := searchObjectReferences(1, HPA, testCase.instance, "prod", hpaRule,
+				testCase.targetKind, testCase.targetInstance)
+			if descriptions := describeConnections(connections); !reflect.DeepEqual(descriptions, testCase.expected) {
+				t.Errorf("searchObjectReferences = %v, expected %v", descriptions, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestSearchObjectReferencesAcrossNamespaces(t *testing.T) {
+	serviceRule := mustParseRelationshipRule("objectref, on:INSTANCE.spec.service, value:Service")
+	setTestSearchNamespaces(t, "", []string{}, false)
+	setTestDynamicClient(t,
+		newTestObject(SERVICE, "metrics-server", "kube-system", "sv1"),
+		newTestReferrer(API_SERVICE, "v1beta1.metrics.k8s.io", "",
+			map[string]interface{}{"name": "metrics-server", "namespace": "kube-system", "port": int64(443)}, "spec", "service"),
+	)
+	connections, _ := searchObjectReferences(1, API_SERVICE, "v1beta1.metrics.k8s.io", "", serviceRule, SERVICE, "*")
+	expected := []string{"Service kube-system/metrics-server <- APIService /v1beta1.metrics.k8s.io: " +
+		"Name:spec.service Kind:Service Value:kube-system/metrics-server Port:443"}
+	if descriptions := describeConnections(connections); !reflect.DeepEqual(descriptions, expected) {
+		t.Errorf("searchObjectReferences = %v, expected %v", descriptions, expected)
+	}
+}
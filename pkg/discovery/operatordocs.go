@@ -76,13 +76,13 @@ func GetUsageDetails(customResourceKind string) (string) {
 	var manPage, usageDetailsData, relationships, group, version string
 	crdClient, err1 := apiextensionsclientset.NewForConfig(cfg)
 	if err1 != nil {
-		fmt.Errorf("Error:%s\n", err1)
+		fmt.Printf("Error:%s\n", err1)
 		return manPage
 	}
 	crdList, err := crdClient.CustomResourceDefinitions().List(context.TODO(),
 		metav1.ListOptions{})
 	if err != nil {
-		fmt.Errorf("Error:%s\n", err)
+		fmt.Printf("Error:%s\n", err)
 		return manPage
 	}
 	for _, crd := range crdList.Items {
@@ -91,7 +91,7 @@ func GetUsageDetails(customResourceKind string) (string) {
 			crdName, 
 			metav1.GetOptions{})
 		if err != nil {
-			fmt.Errorf("Error:%s\n", err)
+			fmt.Printf("Error:%s\n", err)
 			return manPage
 		}
 		if customResourceKind != "" {
@@ -0,0 +1,188 @@
+package discovery
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// RBAC relationships. A RoleBinding/ClusterRoleBinding is connected to its subjects
+// (ServiceAccounts, Users and Groups) and to the Role/ClusterRole of its roleRef.
+// Subjects can be ServiceAccounts of other namespaces, so bindings are searched in
+// all the namespaces when looking for the bindings of a subject.
+
+// A subject or the roleRef of a binding
+type rbacTarget struct {
+	Kind      string
+	Name      string
+	Namespace string
+	Detail    string
+}
+
+func searchRoleBindings(level int, bindingKind, bindingInstance, namespace, targetKind, targetInstance string) ([]Connection, string) {
+	relativesNames := make([]Connection, 0)
+	relDetail := ""
+	bindingNamespace := namespace
+	if bindingKind == CLUSTER_ROLE_BINDING || (bindingInstance == "*" && isRBACSubjectKind(targetKind)) {
+		bindingNamespace = ""
+	}
+	for _, binding := range listKindObjects(bindingKind, bindingNamespace) {
+		if bindingInstance != "*" && binding.GetName() != bindingInstance {
+			continue
+		}
+		for _, target := range getRoleBindingTargets(binding) {
+			if target.Kind != targetKind || (targetInstance != "*" && target.Name != targetInstance) {
+				continue
+			}
+			// A subject is a ServiceAccount of a particular namespace
+			if bindingInstance == "*" && targetKind == SERVICE_ACCOUNT && target.Namespace != namespace {
+				continue
+			}
+			relDetail = target.Detail
+			bindingConn := Connection{Name: binding.GetName(), Kind: bindingKind, Namespace: binding.GetNamespace()}
+			targetConn := Connection{Name: target.Name, Kind: target.Kind, Namespace: target.Namespace}
+			conn, peer := targetConn, bindingConn
+			if bindingInstance == "*" {
+				conn, peer = bindingConn, targetConn
+			}
+			conn.Level = level
+			conn.RelationType = relTypeRBAC
+			conn.RelationDetails = relDetail
+			conn.Peer = &peer
+			relativesNames = append(relativesNames, conn)
+		}
+	}
+	return relativesNames, relDetail
+}
+
+func isRBACSubjectKind(kind string) bool {
+	return kind == SERVICE_ACCOUNT || kind == RBAC_USER || kind == RBAC_GROUP
+}
+
+func getRoleBindingTargets(binding unstructured.Unstructured) []rbacTarget {
+	targets := make([]rbacTarget, 0)
+	content := binding.UnstructuredContent()
+	subjects, _, _ := unstructured.NestedSlice(content, "subjects")
+	for _, s := range subjects {
+		subject, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		kind, _, _ := unstructured.NestedString(subject, "kind")
+		name, _, _ := unstructured.NestedString(subject, "name")
+		subjectNamespace := ""
+		if kind == SERVICE_ACCOUNT {
+			subjectNamespace, _, _ = unstructured.NestedString(subject, "namespace")
+			if subjectNamespace == "" {
+				subjectNamespace = binding.GetNamespace()
+			}
+		}
+		detail := "subject " + kind + ":" + name
+		if subjectNamespace != "" {
+			detail = "subject " + kind + ":" + subjectNamespace + "/" + name
+		}
+		targets = append(targets, rbacTarget{Kind: kind, Name: name, Namespace: subjectNamespace, Detail: detail})
+	}
+	roleKind, _, _ := unstructured.NestedString(content, "roleRef", "kind")
+	roleName, _, _ := unstructured.NestedString(content, "roleRef", "name")
+	if roleKind != "" && roleName != "" {
+		roleNamespace := ""
+		if roleKind == ROLE {
+			roleNamespace = binding.GetNamespace()
+		}
+		targets = append(targets, rbacTarget{Kind: roleKind, Name: roleName, Namespace: roleNamespace, Detail: "roleRef " + roleKind + ":" + roleName})
+	}
+	return targets
+}
+
+// GetRBACSummaryString summarizes the rules of the Roles and ClusterRoles found in the
+// connections, i.e. the effective permissions at the end of the RBAC chains.
+func GetRBACSummaryString(connections []Connection) string {
+	roles := make([]Connection, 0)
+	seen := make(map[string]bool)
+	for _, conn := range connections {
+		if conn.Kind != ROLE && conn.Kind != CLUSTER_ROLE {
+			continue
+		}
+		key := conn.Kind + "/" + conn.Namespace + "/" + conn.Name
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		roles = append(roles, conn)
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		lhs, rhs := roles[i], roles[j]
+		return lhs.Kind+"/"+lhs.Namespace+"/"+lhs.Name < rhs.Kind+"/"+rhs.Namespace+"/"+rhs.Name
+	})
+
+	var sb strings.Builder
+	sb.WriteString("RBAC summary:\n")
+	if len(roles) == 0 {
+		sb.WriteString("  No Roles or ClusterRoles found.\n")
+		return sb.String()
+	}
+	for _, role := range roles {
+		roleName := role.Kind + "/" + role.Name
+		if role.Namespace != "" {
+			roleName = role.Kind + "/" + role.Namespace + "/" + role.Name
+		}
+		sb.WriteString(roleName + "\n")
+		for _, rule := range getRoleRules(role.Kind, role.Name, role.Namespace) {
+			sb.WriteString("  " + rule + "\n")
+		}
+	}
+	return sb.String()
+}
+
+// Rules of a Role/ClusterRole as "verbs:... resources:... [apiGroups:...] [resourceNames:...]"
+func getRoleRules(kind, instance, namespace string) []string {
+	rules := make([]string, 0)
+	if _, err := getDynamicClient(); err != nil {
+		return rules
+	}
+	resKindPlural, _, resApiVersion, resGroup := getKindAPIDetails(kind)
+	res := schema.GroupVersionResource{Group: resGroup,
+		Version:  resApiVersion,
+		Resource: resKindPlural}
+	roleObj, err := getKubeObject(kind, instance, namespace, res)
+	if err != nil {
+		fmt.Printf("Error in fetching %s %s: %s\n", kind, instance, err.Error())
+		return rules
+	}
+	ruleList, _, _ := unstructured.NestedSlice(roleObj.UnstructuredContent(), "rules")
+	for _, r := range ruleList {
+		rule, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		verbs, _, _ := unstructured.NestedStringSlice(rule, "verbs")
+		ruleString := "verbs:" + strings.Join(verbs, ",")
+		if resources, found, _ := unstructured.NestedStringSlice(rule, "resources"); found {
+			ruleString = ruleString + " resources:" + strings.Join(resources, ",")
+		}
+		if apiGroups, found, _ := unstructured.NestedStringSlice(rule, "apiGroups"); found {
+			for i, apiGroup := range apiGroups {
+				if apiGroup == "" {
+					apiGroups[i] = "core"
+				}
+			}
+			ruleString = ruleString + " apiGroups:" + strings.Join(apiGroups, ",")
+		}
+		if resourceNames, found, _ := unstructured.NestedStringSlice(rule, "resourceNames"); found {
+			ruleString = ruleString + " resourceNames:" + strings.Join(resourceNames, ",")
+		}
+		if urls, found, _ := unstructured.NestedStringSlice(rule, "nonResourceURLs"); found {
+			ruleString = ruleString + " nonResourceURLs:" + strings.Join(urls, ",")
+		}
+		rules = append(rules, ruleString)
+	}
+	// Aggregated ClusterRoles get their rules from other ClusterRoles
+	if selectors, found, _ := unstructured.NestedSlice(roleObj.UnstructuredContent(), "aggregationRule", "clusterRoleSelectors"); found && len(rules) == 0 {
+		rules = append(rules, fmt.Sprintf("aggregated from %d clusterRoleSelectors", len(selectors)))
+	}
+	return rules
+}
@@ -0,0 +1,191 @@
+package discovery
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newTestRoleBinding(kind, name, namespace, roleKind, roleName string, subjects ...map[string]interface{}) unstructured.Unstructured {
+	binding := newTestObject(kind, name, namespace, kind+"-"+namespace+"-"+name)
+	subjectList := make([]interface{}, 0)
+	for _, subject := range subjects {
+		subjectList = append(subjectList, subject)
+	}
+	_ = unstructured.SetNestedSlice(binding.Object, subjectList, "subjects")
+	_ = unstructured.SetNestedMap(binding.Object, map[string]interface{}{"kind": roleKind, "name": roleName}, "roleRef")
+	return binding
+}
+
+func TestGetRoleBindingTargets(t *testing.T) {
+	testCases := []struct {
+		name     string
+		binding  unstructured.Unstructured
+		expected []rbacTarget
+	}{
+		{
+			name: "RoleBinding",
+			binding: newTestRoleBinding(ROLE_BINDING, "app-reader", "prod", ROLE, "reader",
+				map[string]interface{}{"kind": SERVICE_ACCOUNT, "name": "app"},
+				map[string]interface{}{"kind": SERVICE_ACCOUNT, "name": "ci", "namespace": "tools"},
+				map[string]interface{}{"kind": RBAC_USER, "name": "jane@example.com", "apiGroup": "rbac.authorization.k8s.io"},
+				map[string]interface{}{"kind": RBAC_GROUP, "name": "developers", "apiGroup": "rbac.authorization.k8s.io"},
+			),
+			expected: []rbacTarget{
+				// A ServiceAccount without a namespace is in the namespace of the binding
+				{Kind: SERVICE_ACCOUNT, Name: "app", Namespace: "prod", Detail: "subject ServiceAccount:prod/app"},
+				{Kind: SERVICE_ACCOUNT, Name: "ci", Namespace: "tools", Detail: "subject ServiceAccount:tools/ci"},
+				{Kind: RBAC_USER, Name: "jane@example.com", Detail: "subject User:jane@example.com"},
+				{Kind: RBAC_GROUP, Name: "developers", Detail: "subject Group:developers"},
+				{Kind: ROLE, Name: "reader", Namespace: "prod", Detail: "roleRef Role:reader"},
+			},
+		},
+		{
+			name: "RoleBinding of a ClusterRole",
+			binding: newTestRoleBinding(ROLE_BINDING, "app-view", "prod", CLUSTER_ROLE, "view",
+				map[string]interface{}{"kind": SERVICE_ACCOUNT, "name": "app"}),
+			expected: []rbacTarget{
+				{Kind: SERVICE_ACCOUNT, Name: "app", Namespace: "prod", Detail: "subject ServiceAccount:prod/app"},
+				{Kind: CLUSTER_ROLE, Name: "view", Detail: "roleRef ClusterRole:view"},
+			},
+		},
+		{
+			name: "ClusterRoleBinding",
+			binding: newTestRoleBinding(CLUSTER_ROLE_BINDING, "operator", "", CLUSTER_ROLE, "operator",
+				map[string]interface{}{"kind": SERVICE_ACCOUNT, "name": "operator", "namespace": "operators"}),
+			expected: []rbacTarget{
+				{Kind: SERVICE_ACCOUNT, Name: "operator", Namespace: "operators", Detail: "subject ServiceAccount:operators/operator"},
+				{Kind: CLUSTER_ROLE, Name: "operator", Detail: "roleRef ClusterRole:operator"},
+			},
+		},
+		{
+			name:     "binding without subjects and roleRef",
+			binding:  newTestObject(ROLE_BINDING, "empty", "prod", "rb1"),
+			expected: []rbacTarget{},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if targets := getRoleBindingTargets(testCase.binding); !reflect.DeepEqual(targets, testCase.expected) {
+				t.Errorf("getRoleBindingTargets = %+v, expected %+v", targets, testCase.expected)
+			}
+		})
+	}
+}
+
+func setTestRBACObjects(t *testing.T) {
+	setTestDynamicClient(t,
+		newTestRoleBinding(ROLE_BINDING, "app-reader", "prod", ROLE, "reader",
+			map[string]interface{}{"kind": SERVICE_ACCOUNT, "name": "app"},
+			map[string]interface{}{"kind": RBAC_GROUP, "name": "developers"}),
+		// Grants a ServiceAccount of prod access to the secrets of tools
+		newTestRoleBinding(ROLE_BINDING, "secret-reader", "tools", ROLE, "secret-reader",
+			map[string]interface{}{"kind": SERVICE_ACCOUNT, "name": "app", "namespace": "prod"}),
+		// Same ServiceAccount name in another namespace
+		newTestRoleBinding(ROLE_BINDING, "other-app", "staging", ROLE, "reader",
+			map[string]interface{}{"kind": SERVICE_ACCOUNT, "name": "app"}),
+		newTestRoleBinding(CLUSTER_ROLE_BINDING, "app-view", "", CLUSTER_ROLE, "view",
+			map[string]interface{}{"kind": SERVICE_ACCOUNT, "name": "app", "namespace": "prod"}),
+	)
+}
+
+func TestSearchRoleBindings(t *testing.T) {
+	setTestRBACObjects(t)
+	testCases := []struct {
+		name            string
+		bindingKind     string
+		bindingInstance string
+		targetKind      string
+		targetInstance  string
+		expected        []string
+	}{
+		{
+			name: "RoleBindings of a ServiceAccount in all the namespaces", bindingKind: ROLE_BINDING, bindingInstance: "*",
+			targetKind: SERVICE_ACCOUNT, targetInstance: "app",
+			expected: []string{
+				"RoleBinding prod/app-reader <- ServiceAccount prod/app: subject ServiceAccount:prod/app",
+				"RoleBinding tools/secret-reader <- ServiceAccount prod/app: subject ServiceAccount:prod/app",
+			},
+		},
+		{
+			name: "ClusterRoleBindings of a ServiceAccount", bindingKind: CLUSTER_ROLE_BINDING, bindingInstance: "*",
+			targetKind: SERVICE_ACCOUNT, targetInstance: "app",
+			expected: []string{"ClusterRoleBinding /app-view <- ServiceAccount prod/app: subject ServiceAccount:prod/app"},
+		},
+		{
+			name: "RoleBindings of a Group", bindingKind: ROLE_BINDING, bindingInstance: "*",
+			targetKind: RBAC_GROUP, targetInstance: "developers",
+			expected: []string{"RoleBinding prod/app-reader <- Group /developers: subject Group:developers"},
+		},
+		{
+			name: "Role of a RoleBinding", bindingKind: ROLE_BINDING, bindingInstance: "app-reader",
+			targetKind: ROLE, targetInstance: "*",
+			expected: []string{"Role prod/reader <- RoleBinding prod/app-reader: roleRef Role:reader"},
+		},
+		{
+			name: "ClusterRole of a ClusterRoleBinding", bindingKind: CLUSTER_ROLE_BINDING, bindingInstance: "app-view",
+			targetKind: CLUSTER_ROLE, targetInstance: "*",
+			expected: []string{"ClusterRole /view <- ClusterRoleBinding /app-view: roleRef ClusterRole:view"},
+		},
+		{
+			name: "ServiceAccount without bindings", bindingKind: ROLE_BINDING, bindingInstance: "*",
+			targetKind: SERVICE_ACCOUNT, targetInstance: "default",
+			expected: []string{},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			connections, _ := searchRoleBindings(1, testCase.bindingKind, testCase.bindingInstance, "prod",
+				testCase.targetKind, testCase.targetInstance)
+			if descriptions := describeConnections(connections); !reflect.DeepEqual(descriptions, testCase.expected) {
+				t.Errorf("searchRoleBindings = %v, expected %v", descriptions, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestGetRBACSummaryString(t *testing.T) {
+	reader := newTestObject(ROLE, "reader", "prod", "r1")
+	_ = unstructured.SetNestedSlice(reader.Object, []interface{}{
+		map[string]interface{}{
+			"apiGroups":     []interface{}{""},
+			"resources":     []interface{}{"configmaps", "secrets"},
+			"resourceNames": []interface{}{"app-config"},
+			"verbs":         []interface{}{"get", "list"},
+		},
+	}, "rules")
+	view := newTestObject(CLUSTER_ROLE, "view", "", "cr1")
+	_ = unstructured.SetNestedSlice(view.Object, []interface{}{
+		map[string]interface{}{"nonResourceURLs": []interface{}{"/healthz"}, "verbs": []interface{}{"get"}},
+	}, "rules")
+	aggregated := newTestObject(CLUSTER_ROLE, "admin", "", "cr2")
+	_ = unstructured.SetNestedSlice(aggregated.Object, []interface{}{
+		map[string]interface{}{"matchLabels": map[string]interface{}{"rbac.example.com/aggregate-to-admin": "true"}},
+	}, "aggregationRule", "clusterRoleSelectors")
+	setTestDynamicClient(t, reader, view, aggregated)
+
+	binding := &Connection{Kind: ROLE_BINDING, Name: "app-reader", Namespace: "prod"}
+	connections := []Connection{
+		*binding,
+		{Kind: ROLE, Name: "reader", Namespace: "prod", Peer: binding},
+		{Kind: CLUSTER_ROLE, Name: "view", Peer: binding},
+		{Kind: CLUSTER_ROLE, Name: "admin", Peer: binding},
+		// Roles reached through more than one binding are summarized once
+		{Kind: ROLE, Name: "reader", Namespace: "prod", Peer: binding},
+	}
+	expected := "RBAC summary:\n" +
+		"ClusterRole/admin\n" +
+		"  aggregated from 1 clusterRoleSelectors\n" +
+		"ClusterRole/view\n" +
+		"  verbs:get nonResourceURLs:/healthz\n" +
+		"Role/prod/reader\n" +
+		"  verbs:get,list resources:configmaps,secrets apiGroups:core resourceNames:app-config\n"
+	if summary := GetRBACSummaryString(connections); summary != expected {
+		t.Errorf("GetRBACSummaryString =\n%s\nexpected:\n%s", summary, expected)
+	}
+
+	if summary := GetRBACSummaryString([]Connection{*binding}); summary != "RBAC summary:\n  No Roles or ClusterRoles found.\n" {
+		t.Errorf("GetRBACSummaryString without roles =\n%s", summary)
+	}
+}
@@ -25,19 +25,24 @@ func GetRelatives(visited [] Connection, level int, kind, instance, origkind, or
 
 	//fmt.Printf("Node - Level: %d, Kind:%s, instance:%s origkind:%s, originstance:%s relType:%s\n", level, kind, instance, origkind, originstance, relType)
 
+	// The root of a search; the recursive calls are at deeper levels
+	if level == OrigLevel+1 {
+		startManagedBySearch()
+		defer endManagedBySearch()
+	}
+
 	ignored := checkIgnored(kind, instance)
 	if ignored {
 		return visited
 	}
 
-	if OutputFormat != "json" {
+	if OutputFormat != "json" && !isGraphOutput(OutputFormat) {
 		_ = makeTimestamp()
 		fmt.Printf("Discovering node - Level: %d, Kind:%s, instance:%s namespace:%s\n", level, kind, instance, namespace)
 	} 
 
+	// The relatives of a Namespace are searched in it
 	if kind == "Namespace" {
-		NamespaceToSearch = instance
-		//fmt.Printf("NamespaceToSearch:%s\n", NamespaceToSearch)
 		namespace = instance
 	}
 
@@ -69,16 +74,16 @@ func GetRelatives(visited [] Connection, level int, kind, instance, origkind, or
 }
 
 func findRelatives(visited []Connection, level int, kind, instance, origkind, originstance, namespace string, relType string) ([]Connection) {
-	relStringList := relationshipMap[kind]
-	visited = findDownstreamRelatives(visited, level, kind, instance, namespace, relStringList)
-	//fmt.Printf("Kind:%s, relStringList:%v\n",kind, relStringList)
+	relRules := getRelationshipRules(kind)
+	visited = findDownstreamRelatives(visited, level, kind, instance, namespace, relRules)
+	//fmt.Printf("Kind:%s, relRules:%v\n",kind, relRules)
 
 	relatedKindList := findRelatedKinds(kind)
 	//fmt.Printf("Kind:%s, Related Kind List 1:%v\n", kind, relatedKindList)
 	for _, relatedKind := range relatedKindList {
-		relStringListRelated := relationshipMap[relatedKind]
-		//fmt.Printf("RelStringListrelated:%v\n", relStringListRelated)
-		visited = findUpstreamRelatives(visited, level, relatedKind, kind, instance, namespace, relStringListRelated)
+		relRulesRelated := getRelationshipRules(relatedKind)
+		//fmt.Printf("RelRulesRelated:%v\n", relRulesRelated)
+		visited = findUpstreamRelatives(visited, level, relatedKind, kind, instance, namespace, relRulesRelated)
 	}
 	visited = findParentConnections(visited, level, kind, instance, namespace)
 	visited = findChildrenConnections(visited, level, kind, instance, namespace)
@@ -86,28 +91,62 @@ func findRelatives(visited []Connection, level int, kind, instance, origkind, or
 	return visited
 }
 
-func findDownstreamRelatives(visited []Connection, level int, kind, instance, namespace string, relStringList []string) ([]Connection) {
-	for _, relString := range relStringList {
-		relType, lhs, rhs, targetKindList := parseRelationship(relString)
-		//fmt.Printf("Reltype:%s, lhs:%s, rhs:%s, TargetKindList:%v\n", relType, lhs, rhs, targetKindList)
-		for _, targetKind := range targetKindList {
+func findDownstreamRelatives(visited []Connection, level int, kind, instance, namespace string, relRules []RelationshipRule) ([]Connection) {
+	for _, relRule := range relRules {
+		//fmt.Printf("RelRule:%s\n", relRule.String())
+		for _, targetKind := range relRule.TargetKinds {
+			relType := relRule.Type
 			if relType == relTypeLabel {
-				selectorLabelMap := getSelectorLabels(kind, instance, namespace)
-				relativesNames, relDetail := searchLabels(level, kind, instance, selectorLabelMap, targetKind, namespace)
+				labelSelector := getSelectorLabels(kind, instance, namespace, relRule.SourcePath)
+				relativesNames, relDetail := searchLabels(level, kind, instance, labelSelector, targetKind, namespace)
 				//fmt.Printf("FDSR label - Relnames:%v Relatives:%v\n", relativesNames, relatives)
 				visited = buildGraph(visited, level, kind, instance, relativesNames, targetKind, namespace, relType, relDetail)
 			}
 			if relType == relTypeSpecProperty {
 				targetInstance := "*"
-				relativesNames, relDetail, relTypeSpecific := searchSpecProperty(level, kind, instance, namespace, lhs, rhs, targetKind, targetInstance)
+				relativesNames, relDetail, relTypeSpecific := searchSpecProperty(level, kind, instance, namespace, relRule, targetKind, targetInstance)
 				relType = relTypeSpecific			
 				//fmt.Printf("FDSR Spec - Relnames:%v Relatives:%v\n", relativesNames, relatives)
 				visited = buildGraph(visited, level, kind, instance, relativesNames, targetKind, namespace, relType, relDetail)
 			}
+			if relType == relTypeVolume {
+				targetInstance := "*"
+				relativesNames, relDetail := searchVolumes(level, kind, instance, namespace, targetKind, targetInstance)
+				visited = buildGraph(visited, level, kind, instance, relativesNames, targetKind, namespace, relType, relDetail)
+			}
+			if relType == relTypeNetworkPolicy {
+				targetInstance := "*"
+				relativesNames, relDetail := searchNetworkPolicies(level, instance, namespace, targetKind, targetInstance)
+				visited = buildGraph(visited, level, kind, instance, relativesNames, targetKind, namespace, relType, relDetail)
+			}
+			if relType == relTypeManagedBy {
+				targetInstance := "*"
+				relativesNames, relDetail := searchManagedBy(level, kind, instance, namespace, targetKind, targetInstance)
+				visited = buildGraph(visited, level, kind, instance, relativesNames, targetKind, namespace, relType, relDetail)
+			}
+			if relType == relTypeEndpoints {
+				targetInstance := "*"
+				relativesNames, relDetail := searchEndpoints(level, instance, namespace, targetKind, targetInstance)
+				visited = buildGraph(visited, level, kind, instance, relativesNames, targetKind, namespace, relType, relDetail)
+			}
+			if relType == relTypeRBAC {
+				targetInstance := "*"
+				relativesNames, relDetail := searchRoleBindings(level, kind, instance, namespace, targetKind, targetInstance)
+				visited = buildGraph(visited, level, kind, instance, relativesNames, targetKind, namespace, relType, relDetail)
+			}
+			if relType == relTypeObjectRef {
+				targetInstance := "*"
+				relativesNames, relDetail := searchObjectReferences(level, kind, instance, namespace, relRule, targetKind, targetInstance)
+				// The kinds of the targets are known only after reading the references
+				refKinds, refConnections := groupConnectionsByKind(relativesNames)
+				for _, refKind := range refKinds {
+					visited = buildGraph(visited, level, kind, instance, refConnections[refKind], refKind, namespace, relType, relDetail)
+				}
+			}
 			if relType == relTypeAnnotation {
 				targetInstance := "*"
 				//fmt.Printf("kind:%s instance:%s targetkind:%s targetInstance:%s ns:%s\n", kind, instance, targetKind, targetInstance, namespace)
-				relativesNames, relDetail := searchAnnotations(level, kind, instance, namespace, lhs, rhs, targetKind, targetInstance)
+				relativesNames, relDetail := searchAnnotations(level, kind, instance, namespace, relRule.TargetPath, relRule.SourcePath, relRule.MatchMode, targetKind, targetInstance)
 				//fmt.Printf("FDSR Annotation:%v\n", relativesNames)				
 				visited = buildGraph(visited, level, kind, instance, relativesNames, targetKind, namespace, relType, relDetail)
 			}
@@ -139,27 +178,60 @@ func checkIgnored(kind, instance string) bool {
 	return false
 }
 
-func findUpstreamRelatives(visited []Connection, level int, relatedKind, kind, instance, namespace string, relStringList []string) ([]Connection) {
-	for _, relString := range relStringList {
-		relType, lhs, rhs, targetKindList := parseRelationship(relString)
-		for _, targetKind := range targetKindList {
+func findUpstreamRelatives(visited []Connection, level int, relatedKind, kind, instance, namespace string, relRules []RelationshipRule) ([]Connection) {
+	for _, relRule := range relRules {
+		for _, targetKind := range relRule.TargetKinds {
+			relType := relRule.Type
+			if targetKind == objectRefAnyKind {
+				targetKind = kind
+			}
 			if targetKind == kind {
 				if relType == relTypeLabel {
 					labelMap := getLabels(kind, instance, namespace)
-					relativesNames, relDetail := searchSelectors(level, relatedKind, labelMap, kind, instance, namespace)
+					relativesNames, relDetail := searchSelectors(level, relatedKind, relRule.SourcePath, labelMap, kind, instance, namespace)
 					//fmt.Printf("FUSR label - Relnames:%v Relatives:%v\n", relativesNames, relatives)
 					visited = buildGraph(visited, level, kind, instance, relativesNames, relatedKind, namespace, relType, relDetail)
 				}
 				if relType == relTypeSpecProperty {
 					targetInstance := "*"
-					relativesNames, relDetail, relTypeSpecific := searchSpecProperty(level, relatedKind, targetInstance, namespace, lhs, rhs, kind, instance)
+					relativesNames, relDetail, relTypeSpecific := searchSpecProperty(level, relatedKind, targetInstance, namespace, relRule, kind, instance)
 					//fmt.Printf("FUSR Spec - Relnames:%v Relatives:%v\n", relativesNames, relatives)
 					relType = relTypeSpecific
 					visited = buildGraph(visited, level, kind, instance, relativesNames, relatedKind, namespace, relType, relDetail)
 				}
+				if relType == relTypeVolume {
+					targetInstance := "*"
+					relativesNames, relDetail := searchVolumes(level, relatedKind, targetInstance, namespace, kind, instance)
+					visited = buildGraph(visited, level, kind, instance, relativesNames, relatedKind, namespace, relType, relDetail)
+				}
+				if relType == relTypeNetworkPolicy {
+					targetInstance := "*"
+					relativesNames, relDetail := searchNetworkPolicies(level, targetInstance, namespace, kind, instance)
+					visited = buildGraph(visited, level, kind, instance, relativesNames, relatedKind, namespace, relType, relDetail)
+				}
+				if relType == relTypeManagedBy {
+					targetInstance := "*"
+					relativesNames, relDetail := searchManagedBy(level, relatedKind, targetInstance, namespace, kind, instance)
+					visited = buildGraph(visited, level, kind, instance, relativesNames, relatedKind, namespace, relType, relDetail)
+				}
+				if relType == relTypeEndpoints {
+					targetInstance := "*"
+					relativesNames, relDetail := searchEndpoints(level, targetInstance, namespace, kind, instance)
+					visited = buildGraph(visited, level, kind, instance, relativesNames, relatedKind, namespace, relType, relDetail)
+				}
+				if relType == relTypeRBAC {
+					targetInstance := "*"
+					relativesNames, relDetail := searchRoleBindings(level, relatedKind, targetInstance, namespace, kind, instance)
+					visited = buildGraph(visited, level, kind, instance, relativesNames, relatedKind, namespace, relType, relDetail)
+				}
+				if relType == relTypeObjectRef {
+					targetInstance := "*"
+					relativesNames, relDetail := searchObjectReferences(level, relatedKind, targetInstance, namespace, relRule, kind, instance)
+					visited = buildGraph(visited, level, kind, instance, relativesNames, relatedKind, namespace, relType, relDetail)
+				}
 				if relType == relTypeAnnotation {
 					targetInstance := "*"
-					relativesNames, relDetail := searchAnnotations(level, relatedKind, targetInstance, namespace, lhs, rhs, kind, instance)
+					relativesNames, relDetail := searchAnnotations(level, relatedKind, targetInstance, namespace, relRule.TargetPath, relRule.SourcePath, relRule.MatchMode, kind, instance)
 					//fmt.Printf("FDSR Annotation:%v\n", relativesNames)				
 					visited = buildGraph(visited, level, kind, instance, relativesNames, relatedKind, namespace, relType, relDetail)
 				}
@@ -170,6 +242,7 @@ func findUpstreamRelatives(visited []Connection, level int, relatedKind, kind, i
 }
 
 func buildGraph(visited []Connection, level int, kind, instance string, relativesNames []Connection, targetKind, namespace, relType, relDetail string) ([]Connection) {
+	relativesNames = filterNamespaceScope(relativesNames)
 	unseenRelatives, seenRelatives := filterConnections(visited, relativesNames)
 
 	/*fmt.Printf("unseenRelatives:%v\n", unseenRelatives)
@@ -246,7 +319,7 @@ func findCompositionConnections(visited []Connection, level int, kind, instance,
 		}
 	}
 
-	childrenToSearch, seenRelatives := filterConnections(visited, childrenConnections)
+	childrenToSearch, seenRelatives := filterConnections(visited, filterNamespaceScope(childrenConnections))
 
 	if len(childrenToSearch) > 0 {
 		level = level + 1
@@ -254,10 +327,11 @@ func findCompositionConnections(visited []Connection, level int, kind, instance,
 				relType := relTypeOwnerReference
 				//fmt.Printf("Conn.Kind:%s Conn.Name:%s kind:%s instance:%s\n", conn.Kind, conn.Name, kind, instance)
 				TotalClusterConnections = AppendConnections(TotalClusterConnections, conn)
-				if NamespaceToSearch != "" {
-					namespace = NamespaceToSearch
+				childNamespace := conn.Namespace
+				if childNamespace == "" {
+					childNamespace = namespace
 				}
-				visited = GetRelatives(visited, level, conn.Kind, conn.Name, kind, instance, namespace, relType)
+				visited = GetRelatives(visited, level, conn.Kind, conn.Name, kind, instance, childNamespace, relType)
 		}
 	}
 
@@ -270,17 +344,24 @@ func findCompositionConnections(visited []Connection, level int, kind, instance,
 }
 
 func findParentConnections(visited []Connection, level int, kind, instance, namespace string) []Connection {
-	ownerKind, ownerInstance := getOwnerDetail(kind, instance, namespace)
+	ownerRefs := getOwnerDetails(kind, instance, namespace)
 	//fmt.Printf("Kind:%s Instance:%s\n", kind, instance)
-	//fmt.Printf("OKind:%s OInstance:%s\n", ownerKind, ownerInstance)
 	peer := Connection{
 				Kind: kind,
 				Name: instance,
 				Namespace: namespace,
 				RelationType: relTypeOwnerReference,
 	}
-	//fmt.Printf("Kind:%s Instance:%s OwnerKind:%s OwnerInstance:%s\n", kind, instance, ownerKind, ownerInstance)
-	if ownerKind != "" && ownerInstance != "" {
+	owners := make([]Connection,0)
+	// Follow all the owners, not only the first owner reference
+	for _, ownerRef := range ownerRefs {
+		ownerKind := ownerRef.Kind
+		ownerInstance := ownerRef.Name
+		//fmt.Printf("Kind:%s Instance:%s OwnerKind:%s OwnerInstance:%s\n", kind, instance, ownerKind, ownerInstance)
+		if ownerKind == "" || ownerInstance == "" {
+			continue
+		}
+		discoverKind(ownerKind, ownerRef.APIVersion)
 		ownerConn := Connection{
 			Name: ownerInstance,
 			Kind: ownerKind,
@@ -299,9 +380,10 @@ func findParentConnections(visited []Connection, level int, kind, instance, name
 				Namespace: "",
 			}
 		}
-
-		owners := make([]Connection,0)
 		owners = append(owners, ownerConn)
+	}
+
+	if len(owners) > 0 {
 		ownerToSearch, seenRelatives := filterConnections(visited, owners)
 
 		if len(ownerToSearch) > 0 {
@@ -310,9 +392,6 @@ func findParentConnections(visited []Connection, level int, kind, instance, name
 				relType := relTypeOwnerReference
 				//fmt.Printf("ABC:%v\n", conn)
 				TotalClusterConnections = AppendConnections(TotalClusterConnections, conn)
-				if NamespaceToSearch != "" {
-					namespace = NamespaceToSearch
-				}
 				visited = GetRelatives(visited, level, conn.Kind, conn.Name, kind, instance, namespace, relType)
 			}
 		}
@@ -334,6 +413,7 @@ func findChildrenConnections(visited []Connection, level int, kind, instance, na
 				Namespace: namespace,
 				RelationType: relTypeOwnerReference,
 	}
+	parentUID := getOwnerUID(kind, instance, namespace)
 	for _, relKind := range relatedKindList {
 		childResKindPlural, _, childResApiVersion, childResGroup := getKindAPIDetails(relKind)
 		childRes := schema.GroupVersionResource{Group: childResGroup,
@@ -356,8 +436,8 @@ func findChildrenConnections(visited []Connection, level int, kind, instance, na
 			}
 		}*/
 		for _, child := range children.Items {
-			ownerKind, ownerInstance := findOwner(child)
-			if ownerKind == kind && ownerInstance == instance {
+			// A child can have several owners; it is related to each of them.
+			if isOwnedBy(child, kind, instance, parentUID) {
 				connection := Connection {
 					Name: child.GetName(),
 					Kind: relKind,
@@ -382,9 +462,6 @@ func findChildrenConnections(visited []Connection, level int, kind, instance, na
 			if conn.Kind != "" && conn.Name != "" {
 				//fmt.Printf("ABC:%v\n", conn)
 				TotalClusterConnections = AppendConnections(TotalClusterConnections, conn)
-				if NamespaceToSearch != "" {
-					namespace = NamespaceToSearch
-				}
 				visited = GetRelatives(visited, level, conn.Kind, conn.Name, kind, instance, namespace, relType)
 			}
 		}
@@ -398,13 +475,24 @@ func findChildrenConnections(visited []Connection, level int, kind, instance, na
 
 func searchNextLevel(visited []Connection, level int, relativeNames []Connection, kind, instance, targetKind, namespace, relType string) ([]Connection) {
 	level = level + 1
+	// A relative can be reached through several matching values; search it only once.
+	searched := make(map[string]bool)
 	for _, relative := range relativeNames {
 		relativeName := relative.Name
 		TotalClusterConnections = AppendConnections(TotalClusterConnections, relative)
-		if NamespaceToSearch != "" {
-			namespace = NamespaceToSearch
+		relativeKey := relative.Kind + "/" + relative.Namespace + "/" + relativeName
+		if searched[relativeKey] {
+			continue
+		}
+		searched[relativeKey] = true
+		// Each relative is searched in its own namespace, which can differ from the
+		// namespace of this step for cross-namespace references. Cluster-scoped
+		// relatives are searched in the namespace of this step.
+		relativeNamespace := relative.Namespace
+		if relativeNamespace == "" {
+			relativeNamespace = namespace
 		}
-		visited = GetRelatives(visited, level, targetKind, relativeName, kind, instance, namespace, relType)
+		visited = GetRelatives(visited, level, targetKind, relativeName, kind, instance, relativeNamespace, relType)
 	}
 	return visited
 }
@@ -423,6 +511,65 @@ func findOwner(instanceObj unstructured.Unstructured) (string, string) {
 	return ownerKind, ownerName
 }
 
+func findOwners(instanceObj unstructured.Unstructured) []OwnerReference {
+	owners := make([]OwnerReference, 0)
+	for _, ownerReference := range instanceObj.GetOwnerReferences() {
+		owner := OwnerReference{
+			APIVersion: ownerReference.APIVersion,
+			Kind: ownerReference.Kind,
+			Name: ownerReference.Name,
+			UID: string(ownerReference.UID),
+		}
+		if ownerReference.Controller != nil {
+			owner.Controller = *ownerReference.Controller
+		}
+		if ownerReference.BlockOwnerDeletion != nil {
+			owner.BlockOwnerDeletion = *ownerReference.BlockOwnerDeletion
+		}
+		owners = append(owners, owner)
+	}
+	return owners
+}
+
+// Checks the owner references by UID when the owner's UID is known,
+// otherwise by owner kind and name.
+func isOwnedBy(instanceObj unstructured.Unstructured, ownerKind, ownerInstance, ownerUID string) bool {
+	for _, owner := range findOwners(instanceObj) {
+		if ownerUID != "" {
+			if owner.UID == ownerUID {
+				return true
+			}
+		} else if owner.Kind == ownerKind && owner.Name == ownerInstance {
+			return true
+		}
+	}
+	return false
+}
+
+func getOwnerUID(kind, instance, namespace string) string {
+	resKindPlural, _, resApiVersion, resGroup := getKindAPIDetails(kind)
+	res := schema.GroupVersionResource{Group: resGroup,
+									   Version: resApiVersion,
+									   Resource: resKindPlural}
+	instanceObj, err := getKubeObject(kind, instance, namespace, res)
+	if err != nil {
+		return ""
+	}
+	return string(instanceObj.GetUID())
+}
+
+func getOwnerDetails(kind, instance, namespace string) []OwnerReference {
+	ownerResKindPlural, _, ownerResApiVersion, ownerResGroup := getKindAPIDetails(kind)
+	ownerRes := schema.GroupVersionResource{Group: ownerResGroup,
+									 		Version: ownerResApiVersion,
+									   		Resource: ownerResKindPlural}
+	instanceObj, err := getKubeObject(kind, instance, namespace, ownerRes)
+	if err != nil {
+		return []OwnerReference{}
+	}
+	return findOwners(instanceObj)
+}
+
 func getOwnerDetail(kind, instance, namespace string) (string, string) {
 	ownerKind := ""
 	ownerInstance := ""
@@ -446,7 +593,7 @@ func getOwnerDetail(kind, instance, namespace string) (string, string) {
 	return ownerKind, ownerInstance
 }
 
-func searchAnnotations(level int, kind, instance, namespace, annotationKey, annotationValue, targetKind, targetInstance string) ([]Connection, string) {
+func searchAnnotations(level int, kind, instance, namespace, annotationKey, annotationValue, matchMode, targetKind, targetInstance string) ([]Connection, string) {
 	relativesNames := make([]Connection, 0)
 	relDetail := ""
 	dynamicClient, err := getDynamicClient()
@@ -473,9 +620,6 @@ func searchAnnotations(level int, kind, instance, namespace, annotationKey, anno
 									   Resource: rhsResKindPlural}
 	//fmt.Printf("RHSRes:%v\n", rhsRes)
 	rhsNamespace := namespace
-	if NamespaceToSearch != "" {
-		rhsNamespace = NamespaceToSearch
-	}
 	rhsInstList, err := getObjects(targetKind, targetInstance, rhsNamespace, rhsRes, dynamicClient)
 	if err != nil {
 		return relativesNames, relDetail
@@ -501,7 +645,7 @@ func searchAnnotations(level int, kind, instance, namespace, annotationKey, anno
 			for key, value := range annotations {
 				//fmt.Printf("Key:%s, AnnotationKey:%s, value:%s, lhsName:%s\n", key, annotationKey, value, lhsName)
 				if instance == "*" {
-					if key == annotationKey && (value == lhsName || (value == fqvalue) || strings.Contains(value, lhsName)) {
+					if key == annotationKey && annotationValueMatches(value, lhsName, fqvalue, matchMode) {
 						//rhsInstanceName := unstructuredObj.GetName()
 						//fmt.Printf("RHSKIND:%s RHS InstanceName:%s\n", targetKind, rhsInstanceName)
 						relDetail = annotationKey + "::" + annotationValue
@@ -523,7 +667,7 @@ func searchAnnotations(level int, kind, instance, namespace, annotationKey, anno
 				} else {
 					//fmt.Printf("Value:%s\n", value)
 					//fmt.Printf("Instance:%s\n", instance)
-					if key == annotationKey && (value == instance || (value == fqvalue) || strings.Contains(value, instance)) {
+					if key == annotationKey && annotationValueMatches(value, instance, fqvalue, matchMode) {
 						rhsInstanceName := unstructuredObj.GetName()
 						//fmt.Printf("RHS InstanceName:%s\n", rhsInstanceName)
 						relDetail = annotationKey + "::" + annotationValue
@@ -549,21 +693,23 @@ func searchAnnotations(level int, kind, instance, namespace, annotationKey, anno
 	return relativesNames, relDetail
 }
 
-func searchSpecProperty(level int, kind, instance, namespace, lhs, rhs, targetKind, targetInstance string) ([]Connection, string, string) {
+func searchSpecProperty(level int, kind, instance, namespace string, relRule RelationshipRule, targetKind, targetInstance string) ([]Connection, string, string) {
 	relativesNames := make([]Connection, 0)
 	envNameValue := ""
 	relTypeSpecific := ""
-	if lhs == "env" {
-		relativesNames, envNameValue = searchSpecPropertyEnv(level, kind, instance, namespace, rhs, targetKind, targetInstance)
+	if relRule.sourceField() == "env" {
+		relativesNames, envNameValue = searchSpecPropertyEnv(level, kind, instance, namespace, relRule.targetField(), targetKind, targetInstance)
 		relTypeSpecific = relTypeEnvvariable
 	} else {
-		relativesNames, envNameValue = searchSpecPropertyField(level, kind, instance, namespace, lhs, rhs, targetKind, targetInstance)		
+		relativesNames, envNameValue = searchSpecPropertyField(level, kind, instance, namespace, relRule.SourcePath, relRule.TargetPath, relRule.MatchMode, targetKind, targetInstance)		
 		relTypeSpecific = relTypeSpecProperty
 	}
 	return relativesNames, envNameValue, relTypeSpecific
 }
 
-func searchSpecPropertyField(level int, kind, instance, namespace, lhs, rhs, targetKind, targetInstance string) ([]Connection, string) {
+// Every value found at the source path of an object is compared with the value at the target
+// path of the target objects. Each match is a separate connection.
+func searchSpecPropertyField(level int, kind, instance, namespace, sourcePath, targetPath, matchMode, targetKind, targetInstance string) ([]Connection, string) {
 	relativesNames := make([]Connection, 0)
 	propertyNameValue := ""
 
@@ -576,7 +722,12 @@ func searchSpecPropertyField(level int, kind, instance, namespace, lhs, rhs, tar
 	if err != nil {
 		return relativesNames, propertyNameValue
 	}
-	lhsInstList, err := getObjects(kind, instance, namespace, lhsRes, dynamicClient)
+	lhsNamespace := namespace
+	// Cluster-scoped objects, e.g. PersistentVolumes, are listed across the cluster
+	if IsClusterScoped(kind) {
+		lhsNamespace = ""
+	}
+	lhsInstList, err := getObjects(kind, instance, lhsNamespace, lhsRes, dynamicClient)
 	if err != nil {
 		return relativesNames, propertyNameValue
 	}
@@ -586,8 +737,9 @@ func searchSpecPropertyField(level int, kind, instance, namespace, lhs, rhs, tar
 									   Version: rhsResApiVersion,
 									   Resource: rhsResKindPlural}
 	rhsNamespace := namespace
-	if targetKind == "Namespace" {
-		rhsNamespace = OriginalInputNamespace
+	// Namespaces and other cluster-scoped targets are listed across the cluster
+	if targetKind == "Namespace" || IsClusterScoped(targetKind) {
+		rhsNamespace = ""
 	}
 	//fmt.Printf("TargetKind:%s, TargetInstance:%s rhsNamespace:%s\n", targetKind, targetInstance, rhsNamespace)
 	rhsInstList, err := getObjects(targetKind, targetInstance, rhsNamespace, rhsRes, dynamicClient)
@@ -596,137 +748,79 @@ func searchSpecPropertyField(level int, kind, instance, namespace, lhs, rhs, tar
 		//fmt.Printf("Error:%v\n", err)
 		return relativesNames, propertyNameValue
 	}
-	//fmt.Printf("LHSObj:%v\n", lhsInstList)
-	//fmt.Printf("LHSKind:%s lhs:%s namespace:%s\n", kind, lhs, namespace)
+	sourceFieldPath := trimInstancePrefix(sourcePath)
+	//fmt.Printf("LHSKind:%s path:%s namespace:%s\n", kind, sourceFieldPath, namespace)
 	for _, instanceObj := range lhsInstList {
-		lhsContent := instanceObj.UnstructuredContent()
 		lhsName := instanceObj.GetName()
-		//fmt.Printf("LHSContent:%v\n", lhsContent)
-		//fmt.Printf("LHSName:%s\n",lhsName)
-		var fieldValue string
-		var found bool
-		if lhs == "namespace" {
-			fieldValue = instanceObj.GetNamespace()
-			//fmt.Printf("FieldValue:%s\n", fieldValue)
-			found = true
-		} else {
-			fieldValue, found = findFieldValue(lhsContent, lhs)
-		}
-		//fieldValue, found, err := unstructured.NestedString(lhsContent, "spec", lhs)
-		//fmt.Printf("FieldValue:%s, found:%v, Error:%v", fieldValue, found, err)
-		//if err != nil || !found {
-		//	return relativesNames, propertyNameValue
-		//}
-		//fmt.Printf("ABC ABC: %v, %s", found, fieldValue)
-		if found {
+		fieldValues := getFieldPathValues(instanceObj.UnstructuredContent(), sourceFieldPath)
+		//fmt.Printf("LHSName:%s FieldValues:%v\n", lhsName, fieldValues)
+		for _, fieldValue := range fieldValues {
 			for _, unstructuredObj := range rhsInstList {
-				//fmt.Printf(" 444 %s\n", unstructuredObj.GetName())
-				if rhs == "name" {
-					rhsInstanceName := unstructuredObj.GetName()
-					if fieldValue == rhsInstanceName {
-						var connName, connKind string
-						var peerName, peerKind string
-						if instance == "*" {
-							connName = lhsName
-							connKind = kind
-							peerName = rhsInstanceName
-							peerKind = targetKind
-							//relativesNames = append(relativesNames, lhsName)
-						} else {
-							connName = rhsInstanceName
-							connKind = targetKind
-							peerName = lhsName
-							peerKind = kind
-							//relativesNames = append(relativesNames, rhsInstanceName)
-						}
-						propertyNameValue = "Name:" + lhs + " " + "Value:" + fieldValue
-						conn := Connection{
-							Level: level,
-							Name: connName,
-							Kind: connKind,
-							Namespace: namespace,
-							RelationDetails: propertyNameValue,
-							RelationType: relTypeSpecProperty,
-							Peer: &Connection{
-								Name: peerName,
-								Kind: peerKind,
-								Namespace: namespace,
-							},
-						}
-						relativesNames = append(relativesNames, conn)
-						if found {
-							break
-						}
-					}
+				rhsInstanceName := unstructuredObj.GetName()
+				if !specPropertyValueMatches(fieldValue, getTargetPathValues(*unstructuredObj, targetPath), lhsName, matchMode) {
+					continue
 				}
+				propertyNameValue = "Name:" + sourceFieldPath + " " + "Value:" + fieldValue
+				conn := orientConnection(level, kind, lhsName, instanceObj.GetNamespace(), targetKind, rhsInstanceName, unstructuredObj.GetNamespace(), instance,
+										 relTypeSpecProperty, propertyNameValue)
+				relativesNames = appendConnections1(relativesNames, []Connection{conn})
 			}
 		}
 	}
 	return relativesNames, propertyNameValue
 }
 
-func findFieldValue(lhsContent interface{}, specfield string) (string, bool) {
-	fieldValue := ""
-	found := false
-	//fmt.Printf("lhsContent:%v\n", lhsContent)
-	mapval, ok1 := lhsContent.(map[string]interface{})
-	if ok1 {
-		for key, value := range mapval {
-			if found {
-				break
+// With the claimtemplate match mode the value is the name of a volumeClaimTemplate
+// of a StatefulSet, whose PVCs are named <template>-<statefulset>-<ordinal>.
+func specPropertyValueMatches(fieldValue string, targetValues []string, lhsName, matchMode string) bool {
+	for _, targetValue := range targetValues {
+		switch matchMode {
+		case matchModeContains:
+			if strings.Contains(targetValue, fieldValue) {
+				return true
 			}
-			//fmt.Printf("Key:%s, Value:%v\n", key, value)
-			stringval, ok := value.(string)
-			if ok {
-				if key == specfield {
-					//fmt.Printf("Field:%s Value:%s\n", key, stringval)
-					fieldValue = stringval
-					found = true
-					break
-				}
-			} 
-			mapval1, ok1 := value.(map[string]interface{})
-			if ok1 {
-				//fmt.Printf("Mapval1:%v\n", mapval1)
-				fieldValue, found = findFieldValue(mapval1, specfield)
+		case matchModeClaimTemplate:
+			ordinal := strings.TrimPrefix(targetValue, fieldValue + "-" + lhsName + "-")
+			if ordinal != targetValue && ordinal != "" && strings.Trim(ordinal, "0123456789") == "" {
+				return true
 			}
-			listval, ok2 := value.([]interface{})
-			if ok2 {
-				//fmt.Printf("Listval:%v\n", listval)
-				for _, mapInList := range listval {
-					mapInListMap, ok3 := mapInList.(map[string]interface{})
-					if ok3 {
-						fieldValue, found = findFieldValue(mapInListMap, specfield)
-						if found {
-							break
-						}
-						//return fieldValue, found
-					}
-					stringInListMap, ok4 := mapInList.(string)
-					if ok4 {
-						if key == specfield {
-							//fmt.Printf("Field:%s Value:%s\n", key, stringInListMap)
-							fieldValue = stringInListMap
-							found = true
-							break
-						}
-					}
-				}
+		default:
+			if targetValue == fieldValue {
+				return true
 			}
 		}
 	}
-	//fmt.Printf("FieldValue:%s\n", fieldValue)
+	return false
+}
 
-	// Handle default field values such as Pod.spec.serviceAccountName
-	/*if !found {
-		if specfield == "serviceAccountName" {
-			fieldValue = "default"
-			found = true
-		}
-	}*/
-	return fieldValue, found
+// When the relationship is searched from the target side (instance is "*") the
+// connection is to the source object, otherwise it is to the target object.
+// Connections have the namespace of their object; it is empty for cluster-scoped objects.
+func orientConnection(level int, kind, lhsName, lhsNamespace, targetKind, rhsName, rhsNamespace, instance, relType, relDetail string) Connection {
+	connName, connKind, connNamespace := rhsName, targetKind, rhsNamespace
+	peerName, peerKind, peerNamespace := lhsName, kind, lhsNamespace
+	if instance == "*" {
+		connName, connKind, connNamespace = lhsName, kind, lhsNamespace
+		peerName, peerKind, peerNamespace = rhsName, targetKind, rhsNamespace
+	}
+	return Connection{
+		Level: level,
+		Name: connName,
+		Kind: connKind,
+		Namespace: connNamespace,
+		RelationDetails: relDetail,
+		RelationType: relType,
+		Peer: &Connection{
+			Name: peerName,
+			Kind: peerKind,
+			Namespace: peerNamespace,
+		},
+	}
 }
 
+// Env variables of all the containers of the pod spec/s of an object are searched:
+// literal values are matched with the name of any target, configMapKeyRef/secretKeyRef
+// and envFrom references with ConfigMaps and Secrets.
 func searchSpecPropertyEnv(level int, kind, instance, namespace, rhs, targetKind, targetInstance string) ([]Connection, string) {
 	relativesNames := make([]Connection, 0)
 	envNameValue := ""
@@ -739,88 +833,65 @@ func searchSpecPropertyEnv(level int, kind, instance, namespace, rhs, targetKind
 	lhsRes := schema.GroupVersionResource{Group: lhsResGroup,
 									   Version: lhsResApiVersion,
 									   Resource: lhsResKindPlural}
-	lhsInstList, err := getObjects(kind, instance, namespace, lhsRes, dynamicClient)
-	if err != nil {
-		return relativesNames, envNameValue
+	// Services can be referred to by DNS names from other namespaces
+	lhsNamespaces := []string{namespace}
+	if instance == "*" && targetKind == SERVICE {
+		lhsNamespaces = getReferrerNamespaces(namespace)
+	}
+	lhsInstList := make([]*unstructured.Unstructured, 0)
+	for _, lhsNamespace := range lhsNamespaces {
+		lhsNamespaceList, err := getObjects(kind, instance, lhsNamespace, lhsRes, dynamicClient)
+		if err != nil {
+			return relativesNames, envNameValue
+		}
+		lhsInstList = append(lhsInstList, lhsNamespaceList...)
 	}
 
 	rhsResKindPlural, _, rhsResApiVersion, rhsResGroup := getKindAPIDetails(targetKind)
 	rhsRes := schema.GroupVersionResource{Group: rhsResGroup,
 									   Version: rhsResApiVersion,
 									   Resource: rhsResKindPlural}
-	rhsInstList, err := getObjects(targetKind, targetInstance, namespace, rhsRes, dynamicClient)
-	if err != nil {
-		return relativesNames, envNameValue
+	rhsNamespaces := []string{namespace}
+	if instance != "*" && targetKind == SERVICE {
+		for _, instanceObj := range lhsInstList {
+			for _, envRef := range getEnvReferences(instanceObj.UnstructuredContent()) {
+				_, serviceNamespace := parseServiceDNSName(envRef.Value, instanceObj.GetNamespace())
+				if envRef.Kind == "" && serviceNamespace != "" && inNamespaceScope(serviceNamespace) &&
+				   !containsString(rhsNamespaces, serviceNamespace) {
+					rhsNamespaces = append(rhsNamespaces, serviceNamespace)
+				}
+			}
+		}
+	}
+	rhsInstList := make([]*unstructured.Unstructured, 0)
+	for _, rhsNamespace := range rhsNamespaces {
+		rhsNamespaceList, err := getObjects(targetKind, targetInstance, rhsNamespace, rhsRes, dynamicClient)
+		if err != nil {
+			return relativesNames, envNameValue
+		}
+		rhsInstList = append(rhsInstList, rhsNamespaceList...)
 	}
 
 	//fmt.Printf("LHSList:%v\n", lhsInstList)
 	//fmt.Printf("RHSList:%v\n", rhsInstList)
 	for _, instanceObj := range lhsInstList {
-		lhsContent := instanceObj.UnstructuredContent()
 		lhsName := instanceObj.GetName()
-		//jsonContent, _ := instanceObj.MarshalJSON()
-		//fmt.Printf("JSON Content:%s\n", string(jsonContent))
-		containerList, ok, _ := unstructured.NestedSlice(lhsContent, "spec", "containers")
-		if ok {
-			for _, cont := range containerList {
-				container := cont.(map[string]interface{})
-				envVarList, ok, _ := unstructured.NestedSlice(container,"env")
-				if ok {
-					for _, envVar := range envVarList {
-						envMap := envVar.(map[string]interface{})
-						envName := ""
-						envValue := ""
-						if val, ok := envMap["name"]; ok {
-							envName = val.(string)
-						}
-						//envName := envMap["name"].(string)
-						if val, ok := envMap["value"]; ok {
-							envValue = val.(string)
-						}
-						//envValue := envMap["value"].(string)
-						//fmt.Printf("Name:%s, Value:%s", envName, envValue)
-						for _, unstructuredObj := range rhsInstList {
-							//fmt.Printf(" Service name:%s\n", unstructuredObj.GetName())
-							if rhs == "name" {
-								rhsInstanceName := unstructuredObj.GetName()
-								if envValue == rhsInstanceName {
-									var connName, connKind string
-									var peerName, peerKind string
-									if instance == "*" {
-										//fmt.Printf("LHS InstanceName:%s\n", lhsName)
-										connName = lhsName
-										connKind = kind
-										peerName = rhsInstanceName
-										peerKind = targetKind
-									} else {
-										//fmt.Printf("RHS InstanceName:%s\n", rhsInstanceName)
-										connName = rhsInstanceName
-										connKind = targetKind
-										peerName = lhsName
-										peerKind = kind
-										envNameValue = "Name:" + envName + " " + "Value:" + envValue
-									}
-									conn := Connection{
-										Level: level,
-										Name: connName,
-										Kind: connKind,
-										Namespace: namespace,
-										RelationDetails: envNameValue,
-										RelationType: relTypeEnvvariable,
-										Peer: &Connection{
-											Name: peerName,
-											Kind: peerKind,
-											Namespace: namespace,
-										},
-									}
-									connList := make([]Connection,0)
-									connList = append(connList,conn)
-									relativesNames = appendConnections1(relativesNames, connList)
-								}
-							}
-						}
-					}
+		for _, envRef := range getEnvReferences(instanceObj.UnstructuredContent()) {
+			if envRef.Kind != "" && envRef.Kind != targetKind {
+				continue
+			}
+			// Literal values are not taken as references to ConfigMaps or Secrets
+			if envRef.Kind == "" && (targetKind == CONFIG_MAP || targetKind == SECRET) {
+				continue
+			}
+			for _, unstructuredObj := range rhsInstList {
+				if rhs != "name" || !envReferenceMatches(envRef, instanceObj.GetNamespace(), targetKind, unstructuredObj) {
+					continue
 				}
+				envNameValue = envRef.details()
+				conn := orientConnection(level, kind, lhsName, instanceObj.GetNamespace(), targetKind, unstructuredObj.GetName(), unstructuredObj.GetNamespace(), instance,
+										 relTypeEnvvariable, envNameValue)
+				relativesNames = appendConnections1(relativesNames, []Connection{conn})
 			}
 		}
 	}
@@ -828,6 +899,110 @@ func searchSpecPropertyEnv(level int, kind, instance, namespace, rhs, targetKind
 	return relativesNames, envNameValue
 }
 
+// Literal values refer to Services by DNS name; the other references are by name
+// in the namespace of the object.
+func envReferenceMatches(envRef envReference, lhsNamespace, targetKind string, rhsObj *unstructured.Unstructured) bool {
+	if envRef.Kind == "" && targetKind == SERVICE {
+		serviceName, serviceNamespace := parseServiceDNSName(envRef.Value, lhsNamespace)
+		return serviceName == rhsObj.GetName() && serviceNamespace == rhsObj.GetNamespace()
+	}
+	if rhsObj.GetNamespace() != "" && rhsObj.GetNamespace() != lhsNamespace {
+		return false
+	}
+	return envRef.Value == rhsObj.GetName()
+}
+
+func (envRef envReference) details() string {
+	switch {
+	case envRef.Kind != "" && envRef.EnvName == "":
+		return "Container:" + envRef.Container + " EnvFrom " + envRef.Kind + ":" + envRef.Value
+	case envRef.Kind != "":
+		return "Container:" + envRef.Container + " Name:" + envRef.EnvName + " " + envRef.Kind + ":" + envRef.Value + " Key:" + envRef.Key
+	}
+	return "Name:" + envRef.EnvName + " " + "Value:" + envRef.Value
+}
+
+// Pod specs of Pods, of workloads with a pod template and of CronJobs.
+func getPodSpecs(content map[string]interface{}) []map[string]interface{} {
+	podSpecs := make([]map[string]interface{}, 0)
+	podSpecPaths := [][]string{
+		{"spec"},
+		{"spec", "template", "spec"},
+		{"spec", "jobTemplate", "spec", "template", "spec"},
+	}
+	for _, podSpecPath := range podSpecPaths {
+		podSpec, found, _ := unstructured.NestedMap(content, podSpecPath...)
+		if !found {
+			continue
+		}
+		if _, ok := podSpec["containers"]; ok {
+			podSpecs = append(podSpecs, podSpec)
+		}
+	}
+	return podSpecs
+}
+
+func getContainers(podSpec map[string]interface{}) []map[string]interface{} {
+	containers := make([]map[string]interface{}, 0)
+	for _, field := range []string{"initContainers", "containers", "ephemeralContainers"} {
+		containerList, found, _ := unstructured.NestedSlice(podSpec, field)
+		if !found {
+			continue
+		}
+		for _, cont := range containerList {
+			if container, ok := cont.(map[string]interface{}); ok {
+				containers = append(containers, container)
+			}
+		}
+	}
+	return containers
+}
+
+func getEnvReferences(content map[string]interface{}) []envReference {
+	envRefs := make([]envReference, 0)
+	for _, podSpec := range getPodSpecs(content) {
+		for _, container := range getContainers(podSpec) {
+			containerName, _, _ := unstructured.NestedString(container, "name")
+			envVarList, _, _ := unstructured.NestedSlice(container, "env")
+			for _, envVar := range envVarList {
+				envMap, ok := envVar.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				envName, _, _ := unstructured.NestedString(envMap, "name")
+				if envValue, found, _ := unstructured.NestedString(envMap, "value"); found {
+					envRefs = append(envRefs, envReference{Container: containerName, EnvName: envName, Value: envValue})
+				}
+				for _, ref := range [][]string{{"configMapKeyRef", CONFIG_MAP}, {"secretKeyRef", SECRET}} {
+					refField, refKind := ref[0], ref[1]
+					refName, found, _ := unstructured.NestedString(envMap, "valueFrom", refField, "name")
+					if !found {
+						continue
+					}
+					refKey, _, _ := unstructured.NestedString(envMap, "valueFrom", refField, "key")
+					envRefs = append(envRefs, envReference{Container: containerName, EnvName: envName, Value: refName,
+														   Kind: refKind, Key: refKey})
+				}
+			}
+			envFromList, _, _ := unstructured.NestedSlice(container, "envFrom")
+			for _, envFrom := range envFromList {
+				envFromMap, ok := envFrom.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				for _, ref := range [][]string{{"configMapRef", CONFIG_MAP}, {"secretRef", SECRET}} {
+					refField, refKind := ref[0], ref[1]
+					refName, found, _ := unstructured.NestedString(envFromMap, refField, "name")
+					if found {
+						envRefs = append(envRefs, envReference{Container: containerName, Value: refName, Kind: refKind})
+					}
+				}
+			}
+		}
+	}
+	return envRefs
+}
+
 func getObjects(kind, instance, namespace string, res schema.GroupVersionResource, dynamicClient dynamic.Interface) ([]*unstructured.Unstructured, error) {
 	lhsInstList := make([]*unstructured.Unstructured,0)
 	var err error
@@ -893,52 +1068,13 @@ func getObjects(kind, instance, namespace string, res schema.GroupVersionResourc
 	return lhsInstList, err
 }
 
-func parseRelationship(relString string) (string, string, string, []string) {
-	targetKindList := make([]string, 0)
-	parts := strings.Split(relString, ",")
-	relType := strings.TrimSpace(parts[0])
-	var lhs, rhs string
-	if relType == relTypeLabel {
-		targetKind := strings.Split(strings.TrimSpace(parts[1]), ":")[1]
-		lhs = strings.Split(strings.TrimSpace(parts[2]), ":")[1]
-		targetKindList = append(targetKindList, targetKind)
-	}
-	if relType == relTypeSpecProperty {
-		targetKindString := strings.Split(strings.TrimSpace(parts[2]), ":")[1]
-		targetKindStringParts := strings.Split(targetKindString, ".")
-		targetKind := targetKindStringParts[0]
-		targetKindList = append(targetKindList, targetKind)
-		rhs = targetKindStringParts[len(targetKindStringParts)-1]
-
-		lhsString := strings.Split(strings.TrimSpace(parts[1]), ":")[1]
-		lhsStringParts := strings.Split(lhsString, ".")
-		lhs = lhsStringParts[len(lhsStringParts)-1]
-	}
-	if relType == relTypeAnnotation {
-		targetKindString := strings.Split(strings.TrimSpace(parts[1]), ":")[1]
-		// resource/annotation-relationship: "on:MysqlCluster; Secret, key:meta.helm.sh/release-name, value:INSTANCE.metadata.name"
-		targetKinds := strings.Split(targetKindString, ";")
-		lhs = strings.Split(strings.TrimSpace(parts[2]), ":")[1]
-		rhsparts := strings.Split(strings.TrimSpace(parts[3]), ":")
-		if len(rhsparts) == 2 { // value:name
-			rhs = rhsparts[1]
-		} else if len(rhsparts) == 3 { //value:[{name:INSTANCE.metadata.name}]
-			rhs = rhsparts[2]
-			rhs = strings.ReplaceAll(rhs, "}", "")
-			rhs = strings.ReplaceAll(rhs, "]", "")
-		}
-		for _, targetKind := range targetKinds {
-			targetKind = strings.TrimSpace(targetKind)
-			targetKindList = append(targetKindList, targetKind)
-		}
-		//fmt.Printf("TargetKindList:%s\n", targetKindList)
-	}
-	if relType == relTypeOwnerReference {
-		targetKind := strings.Split(strings.TrimSpace(parts[1]), ":")[1]
-		targetKindList = append(targetKindList, targetKind)	
+// Annotation values can refer to an instance by its name or by <kind>-<name>.
+// With the contains match mode the name can also be part of the value.
+func annotationValueMatches(value, name, fqvalue, matchMode string) bool {
+	if value == name || value == fqvalue {
+		return true
 	}
-	//fmt.Printf("RelType:%s, lhs:%s, rhs:%s TargetKindList:%s\n", relType, lhs, rhs, targetKindList)
-	return relType, lhs, rhs, targetKindList
+	return matchMode == matchModeContains && strings.Contains(value, name)
 }
 
 func getLabels(kind, instance, namespace string) map[string]string {
@@ -969,14 +1105,11 @@ func getLabels(kind, instance, namespace string) map[string]string {
 	return labelMap
 }
 
-func getSelectorLabels(kind, instance, namespace string) map[string]string {
-	selectorMap := make(map[string]string)
-	var found bool
-
+func getSelectorLabels(kind, instance, namespace, selectorPath string) *metav1.LabelSelector {
 	dynamicClient, err := getDynamicClient()
 	if err != nil {
 		//fmt.Printf(err.Error())
-		return selectorMap
+		return nil
 	}
 	resourceKindPlural, _, resourceApiVersion, resourceGroup := getKindAPIDetails(kind)
 	//fmt.Printf("%s, %s, %s\n", resourceGroup, resourceApiVersion, resourceKindPlural)
@@ -991,18 +1124,15 @@ func getSelectorLabels(kind, instance, namespace string) map[string]string {
 
 	if err != nil {
 		//fmt.Printf(err.Error())
-		return selectorMap
+		return nil
 	}
 	content := instanceObj.UnstructuredContent()
-	selectorMap, found, _ = unstructured.NestedStringMap(content, "spec", "selector")
-	if !found {
-		selectorMap, found, _ = unstructured.NestedStringMap(content, "spec", "selector", "matchLabels")
-	}
-	//fmt.Printf("SelectorMap:%s\n", selectorMap)
-	return selectorMap
+	labelSelector := getLabelSelectorAtPath(content, selectorPath)
+	//fmt.Printf("LabelSelector:%v\n", labelSelector)
+	return labelSelector
 }
 
-func searchSelectors(level int, lhsKind string, labelMap map[string]string, rhsKind, rhsInstance, namespace string) ([]Connection, string) {
+func searchSelectors(level int, lhsKind, selectorPath string, labelMap map[string]string, rhsKind, rhsInstance, namespace string) ([]Connection, string) {
 	instanceNames := make([]Connection, 0)
 	relDetail := ""
 	/*dynamicClient, err := getDynamicClient()
@@ -1023,20 +1153,12 @@ func searchSelectors(level int, lhsKind string, labelMap map[string]string, rhsK
 	}
 	for _, unstructuredObj := range list.Items {
 		content := unstructuredObj.UnstructuredContent()
-		selectorMap, found, _ := unstructured.NestedStringMap(content, "spec", "selector")
-		if !found {
-			selectorMap, found, _ = unstructured.NestedStringMap(content, "spec", "selector", "matchLabels")
-		}
-		//fmt.Printf("searchSelectors %s %v\n", unstructuredObj.GetName(), selectorMap)
+		labelSelector := getLabelSelectorAtPath(content, selectorPath)
+		//fmt.Printf("searchSelectors %s %v\n", unstructuredObj.GetName(), labelSelector)
 		//fmt.Printf("searchSelectors %v\n", labelMap)
-		match := false
-		if len(selectorMap) > 0 {
-			match = subsetMatchMaps(selectorMap, labelMap)
-		} 
+		match := matchLabelSelector(labelSelector, labelMap)
 		if match {
-			for key, value := range selectorMap {
-				relDetail = relDetail + key + ":" + value + " "
-			}
+			relDetail = describeLabelSelector(labelSelector)
 			instanceName := Connection{
 				Level: level,
 				Name: unstructuredObj.GetName(),
@@ -1068,7 +1190,7 @@ func searchNameInLabels(name string, label map[string]string) bool {
 	return false
 }
 
-func searchLabels(level int, sourceKind, sourceInstance string, labelMap map[string]string, targetKind, namespace string) ([]Connection, string) {
+func searchLabels(level int, sourceKind, sourceInstance string, labelSelector *metav1.LabelSelector, targetKind, namespace string) ([]Connection, string) {
 	instanceNames := make([]Connection, 0)
 	relDetail := ""
 	/*dynamicClient, err := getDynamicClient()
@@ -1090,15 +1212,13 @@ func searchLabels(level int, sourceKind, sourceInstance string, labelMap map[str
 	for _, unstructuredObj := range list.Items {
 		unstructuredObjLabelMap := unstructuredObj.GetLabels()
 		match := false
-		if len(labelMap) > 0 {
-			match = subsetMatchMaps(labelMap, unstructuredObjLabelMap)
+		if !isEmptyLabelSelector(labelSelector) {
+			match = matchLabelSelector(labelSelector, unstructuredObjLabelMap)
 		} else {
 			match = searchNameInLabels(sourceInstance, unstructuredObjLabelMap)
 		}
 		if match {
-			for key, value := range labelMap {
-				relDetail = relDetail + key + ":" + value + " "
-			}
+			relDetail = describeLabelSelector(labelSelector)
 			instanceName := Connection{
 				Level: level,
 				Name: unstructuredObj.GetName(),
@@ -1117,19 +1237,3 @@ func searchLabels(level int, sourceKind, sourceInstance string, labelMap map[str
 	}
 	return instanceNames, relDetail
 }
-
-func subsetMatchMaps(map1, map2 map[string]string) bool {
-	if len(map1) == 0 {
-		return false
-	}
-	for key, element := range map1 {
-		value, found := map2[key]
-		if !found {
-			return false
-		}
-		if value != element {
-			return false
-		}
-	}
-	return true
-}
\ No newline at end of file
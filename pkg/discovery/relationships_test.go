@@ -0,0 +1,591 @@
+package discovery
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Connections as "Kind namespace/name <- PeerKind peerNamespace/peerName: details", sorted
+func describeConnections(connections []Connection) []string {
+	descriptions := make([]string, 0)
+	for _, conn := range connections {
+		description := conn.Kind + " " + conn.Namespace + "/" + conn.Name
+		if conn.Peer != nil {
+			description += " <- " + conn.Peer.Kind + " " + conn.Peer.Namespace + "/" + conn.Peer.Name
+		}
+		if conn.RelationDetails != "" {
+			description += ": " + conn.RelationDetails
+		}
+		descriptions = append(descriptions, description)
+	}
+	sort.Strings(descriptions)
+	return descriptions
+}
+
+func newTestEnvContainer(name string, env []interface{}, envFrom []interface{}) map[string]interface{} {
+	container := map[string]interface{}{"name": name}
+	if env != nil {
+		container["env"] = env
+	}
+	if envFrom != nil {
+		container["envFrom"] = envFrom
+	}
+	return container
+}
+
+func TestGetEnvReferences(t *testing.T) {
+	literal := map[string]interface{}{"name": "DB_HOST", "value": "mysql.db"}
+	configMapKey := map[string]interface{}{"name": "LOG_LEVEL", "valueFrom": map[string]interface{}{
+		"configMapKeyRef": map[string]interface{}{"name": "web-config", "key": "level"}}}
+	secretKey := map[string]interface{}{"name": "DB_PASSWORD", "valueFrom": map[string]interface{}{
+		"secretKeyRef": map[string]interface{}{"name": "db-secret", "key": "password"}}}
+	fieldRef := map[string]interface{}{"name": "POD_NAME", "valueFrom": map[string]interface{}{
+		"fieldRef": map[string]interface{}{"fieldPath": "metadata.name"}}}
+	envFrom := []interface{}{
+		map[string]interface{}{"configMapRef": map[string]interface{}{"name": "web-env"}},
+		map[string]interface{}{"secretRef": map[string]interface{}{"name": "web-secrets"}, "prefix": "WEB_"},
+	}
+	podSpec := map[string]interface{}{
+		"initContainers": []interface{}{newTestEnvContainer("migrate", []interface{}{secretKey}, nil)},
+		"containers": []interface{}{
+			newTestEnvContainer("app", []interface{}{literal, configMapKey, fieldRef, "not an env variable"}, envFrom),
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		content  map[string]interface{}
+		expected []envReference
+	}{
+		{
+			name:    "Pod",
+			content: map[string]interface{}{"spec": podSpec},
+			expected: []envReference{
+				{Container: "migrate", EnvName: "DB_PASSWORD", Value: "db-secret", Kind: SECRET, Key: "password"},
+				{Container: "app", EnvName: "DB_HOST", Value: "mysql.db"},
+				{Container: "app", EnvName: "LOG_LEVEL", Value: "web-config", Kind: CONFIG_MAP, Key: "level"},
+				{Container: "app", Value: "web-env", Kind: CONFIG_MAP},
+				{Container: "app", Value: "web-secrets", Kind: SECRET},
+			},
+		},
+		{
+			name: "pod template of a workload",
+			content: map[string]interface{}{"spec": map[string]interface{}{
+				"replicas": int64(2),
+				"template": map[string]interface{}{"spec": map[string]interface{}{
+					"containers": []interface{}{newTestEnvContainer("app", nil, envFrom)},
+				}},
+			}},
+			expected: []envReference{
+				{Container: "app", Value: "web-env", Kind: CONFIG_MAP},
+				{Container: "app", Value: "web-secrets", Kind: SECRET},
+			},
+		},
+		{
+			name: "job template of a CronJob",
+			content: map[string]interface{}{"spec": map[string]interface{}{
+				"jobTemplate": map[string]interface{}{"spec": map[string]interface{}{
+					"template": map[string]interface{}{"spec": map[string]interface{}{
+						"containers": []interface{}{newTestEnvContainer("backup", []interface{}{secretKey}, nil)},
+					}},
+				}},
+			}},
+			expected: []envReference{
+				{Container: "backup", EnvName: "DB_PASSWORD", Value: "db-secret", Kind: SECRET, Key: "password"},
+			},
+		},
+		{
+			name:     "spec without containers",
+			content:  map[string]interface{}{"spec": map[string]interface{}{"type": "ClusterIP"}},
+			expected: []envReference{},
+		},
+		{
+			name:     "no spec",
+			content:  map[string]interface{}{"data": map[string]interface{}{"key": "value"}},
+			expected: []envReference{},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			envRefs := getEnvReferences(testCase.content)
+			if !reflect.DeepEqual(envRefs, testCase.expected) {
+				t.Errorf("getEnvReferences = %+v, expected %+v", envRefs, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestEnvReferenceDetails(t *testing.T) {
+	testCases := map[string]envReference{
+		"Name:DB_HOST Value:mysql.db": {Container: "app", EnvName: "DB_HOST", Value: "mysql.db"},
+		"Container:app Name:DB_PASSWORD Secret:db-secret Key:password": {Container: "app", EnvName: "DB_PASSWORD",
+			Value: "db-secret", Kind: SECRET, Key: "password"},
+		"Container:app EnvFrom ConfigMap:web-env": {Container: "app", Value: "web-env", Kind: CONFIG_MAP},
+	}
+	for expected, envRef := range testCases {
+		if details := envRef.details(); details != expected {
+			t.Errorf("details of %+v = %q, expected %q", envRef, details, expected)
+		}
+	}
+}
+
+func TestSearchSpecPropertyEnv(t *testing.T) {
+	web := newTestObject(DEPLOYMENT, "web", "default", "d1")
+	_ = unstructured.SetNestedSlice(web.Object, []interface{}{
+		newTestEnvContainer("app", []interface{}{
+			map[string]interface{}{"name": "DB_HOST", "value": "mysql"},
+			map[string]interface{}{"name": "DB_PASSWORD", "valueFrom": map[string]interface{}{
+				"secretKeyRef": map[string]interface{}{"name": "db-secret", "key": "password"}}},
+		}, []interface{}{
+			map[string]interface{}{"configMapRef": map[string]interface{}{"name": "web-config"}},
+		}),
+	}, "spec", "template", "spec", "containers")
+	setTestSearchNamespaces(t, "default", []string{}, false)
+	setTestDynamicClient(t,
+		web,
+		newTestObject(SECRET, "db-secret", "default", "s1"),
+		newTestObject(SECRET, "db-secret", "other", "s2"),
+		// Literal values are not references to Secrets
+		newTestObject(SECRET, "mysql", "default", "s3"),
+		newTestObject(CONFIG_MAP, "web-config", "default", "c1"),
+		newTestObject(CONFIG_MAP, "unused", "default", "c2"),
+		newTestObject(SERVICE, "mysql", "default", "sv1"),
+	)
+
+	testCases := []struct {
+		name           string
+		kind           string
+		instance       string
+		targetKind     string
+		targetInstance string
+		expected       []string
+	}{
+		{
+			name: "Secret of a secretKeyRef", kind: DEPLOYMENT, instance: "web", targetKind: SECRET, targetInstance: "*",
+			expected: []string{"Secret default/db-secret <- Deployment default/web: Container:app Name:DB_PASSWORD Secret:db-secret Key:password"},
+		},
+		{
+			name: "ConfigMap of an envFrom", kind: DEPLOYMENT, instance: "web", targetKind: CONFIG_MAP, targetInstance: "*",
+			expected: []string{"ConfigMap default/web-config <- Deployment default/web: Container:app EnvFrom ConfigMap:web-config"},
+		},
+		{
+			name: "Service of a literal value", kind: DEPLOYMENT, instance: "web", targetKind: SERVICE, targetInstance: "*",
+			expected: []string{"Service default/mysql <- Deployment default/web: Name:DB_HOST Value:mysql"},
+		},
+		{
+			name: "workloads that use a Secret", kind: DEPLOYMENT, instance: "*", targetKind: SECRET, targetInstance: "db-secret",
+			expected: []string{"Deployment default/web <- Secret default/db-secret: Container:app Name:DB_PASSWORD Secret:db-secret Key:password"},
+		},
+		{
+			name: "unused ConfigMap", kind: DEPLOYMENT, instance: "*", targetKind: CONFIG_MAP, targetInstance: "unused",
+			expected: []string{},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			connections, _ := searchSpecPropertyEnv(1, testCase.kind, testCase.instance, "default", "name",
+				testCase.targetKind, testCase.targetInstance)
+			if descriptions := describeConnections(connections); !reflect.DeepEqual(descriptions, testCase.expected) {
+				t.Errorf("searchSpecPropertyEnv = %v, expected %v", descriptions, testCase.expected)
+			}
+		})
+	}
+}
+
+// Connections between objects of a kind and targets of a kind through the built-in
+// specproperty and objectref rules of the kind
+func searchBuiltInReferences(kind, instance, namespace, targetKind, targetInstance string) []string {
+	connections := make([]Connection, 0)
+	for _, relRule := range getRelationshipRules(kind) {
+		if !relRule.hasTargetKind(targetKind) && !relRule.hasTargetKind(objectRefAnyKind) {
+			continue
+		}
+		switch relRule.Type {
+		case relTypeSpecProperty:
+			relativesNames, _, _ := searchSpecProperty(1, kind, instance, namespace, relRule, targetKind, targetInstance)
+			connections = append(connections, relativesNames...)
+		case relTypeObjectRef:
+			relativesNames, _ := searchObjectReferences(1, kind, instance, namespace, relRule, targetKind, targetInstance)
+			connections = append(connections, relativesNames...)
+		}
+	}
+	return describeConnections(connections)
+}
+
+type builtInReferenceTestCase struct {
+	name           string
+	kind           string
+	instance       string
+	targetKind     string
+	targetInstance string
+	expected       []string
+}
+
+func runBuiltInReferenceTestCases(t *testing.T, namespace string, testCases []builtInReferenceTestCase) {
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			descriptions := searchBuiltInReferences(testCase.kind, testCase.instance, namespace, testCase.targetKind, testCase.targetInstance)
+			if !reflect.DeepEqual(descriptions, testCase.expected) {
+				t.Errorf("connections of %s %s to %s %s = %v, expected %v", testCase.kind, testCase.instance,
+					testCase.targetKind, testCase.targetInstance, descriptions, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestIngressRelationships(t *testing.T) {
+	web := newTestObject(INGRESS, "web", "prod", "i1")
+	_ = unstructured.SetNestedMap(web.Object, map[string]interface{}{
+		"ingressClassName": "nginx",
+		"defaultBackend": map[string]interface{}{"service": map[string]interface{}{
+			"name": "default-http", "port": map[string]interface{}{"number": int64(80)}}},
+		"tls": []interface{}{map[string]interface{}{"hosts": []interface{}{"web.example.com"}, "secretName": "web-tls"}},
+		"rules": []interface{}{map[string]interface{}{
+			"host": "web.example.com",
+			"http": map[string]interface{}{"paths": []interface{}{
+				map[string]interface{}{"path": "/", "backend": map[string]interface{}{"service": map[string]interface{}{
+					"name": "web", "port": map[string]interface{}{"name": "http"}}}},
+				map[string]interface{}{"path": "/courses", "backend": map[string]interface{}{"resource": map[string]interface{}{
+					"apiGroup": testCRGroup, "kind": "Moodle", "name": "moodle1"}}},
+			}},
+		}},
+	}, "spec")
+	// networking.k8s.io/v1beta1 Ingress
+	legacy := newTestObject(INGRESS, "legacy", "prod", "i2")
+	_ = unstructured.SetNestedMap(legacy.Object, map[string]interface{}{
+		"backend": map[string]interface{}{"serviceName": "legacy-default", "servicePort": int64(80)},
+		"rules": []interface{}{map[string]interface{}{
+			"http": map[string]interface{}{"paths": []interface{}{
+				map[string]interface{}{"path": "/", "backend": map[string]interface{}{"serviceName": "legacy", "servicePort": "http"}},
+			}},
+		}},
+	}, "spec")
+	setTestCustomResourceKind(t)
+	setTestSearchNamespaces(t, "prod", []string{}, false)
+	setTestDynamicClient(t,
+		web,
+		legacy,
+		newTestObject(SERVICE, "web", "prod", "sv1"),
+		newTestObject(SERVICE, "default-http", "prod", "sv2"),
+		newTestObject(SERVICE, "legacy", "prod", "sv3"),
+		newTestObject(SERVICE, "legacy-default", "prod", "sv4"),
+		newTestObject(SERVICE, "unused", "prod", "sv5"),
+		newTestObject(SECRET, "web-tls", "prod", "s1"),
+		newTestObject(INGRESS_CLASS, "nginx", "", "ic1"),
+		newTestObject("Moodle", "moodle1", "prod", "m1"),
+	)
+
+	runBuiltInReferenceTestCases(t, "prod", []builtInReferenceTestCase{
+		{
+			name: "v1 service backends", kind: INGRESS, instance: "web", targetKind: SERVICE, targetInstance: "*",
+			expected: []string{
+				"Service prod/default-http <- Ingress prod/web: Name:spec.defaultBackend.service.name Value:default-http",
+				"Service prod/web <- Ingress prod/web: Name:spec.rules.http.paths.backend.service.name Value:web",
+			},
+		},
+		{
+			name: "v1beta1 service backends", kind: INGRESS, instance: "legacy", targetKind: SERVICE, targetInstance: "*",
+			expected: []string{
+				"Service prod/legacy <- Ingress prod/legacy: Name:spec.rules.http.paths.backend.serviceName Value:legacy",
+				"Service prod/legacy-default <- Ingress prod/legacy: Name:spec.backend.serviceName Value:legacy-default",
+			},
+		},
+		{
+			name: "resource backend", kind: INGRESS, instance: "web", targetKind: "Moodle", targetInstance: "*",
+			expected: []string{"Moodle prod/moodle1 <- Ingress prod/web: Name:spec.rules.http.paths.backend.resource Kind:Moodle Value:moodle1"},
+		},
+		{
+			name: "TLS Secret", kind: INGRESS, instance: "web", targetKind: SECRET, targetInstance: "*",
+			expected: []string{"Secret prod/web-tls <- Ingress prod/web: Name:spec.tls.secretName Value:web-tls"},
+		},
+		{
+			name: "IngressClass", kind: INGRESS, instance: "web", targetKind: INGRESS_CLASS, targetInstance: "*",
+			expected: []string{"IngressClass /nginx <- Ingress prod/web: Name:spec.ingressClassName Value:nginx"},
+		},
+		{
+			name: "Ingresses of a Service", kind: INGRESS, instance: "*", targetKind: SERVICE, targetInstance: "legacy",
+			expected: []string{"Ingress prod/legacy <- Service prod/legacy: Name:spec.rules.http.paths.backend.serviceName Value:legacy"},
+		},
+		{
+			name: "Service without Ingresses", kind: INGRESS, instance: "*", targetKind: SERVICE, targetInstance: "unused",
+			expected: []string{},
+		},
+	})
+}
+
+func TestSpecPropertyValueMatches(t *testing.T) {
+	testCases := []struct {
+		name         string
+		fieldValue   string
+		targetValues []string
+		matchMode    string
+		expected     bool
+	}{
+		{"exact", "fast", []string{"slow", "fast"}, matchModeExact, true},
+		{"exact mismatch", "fast", []string{"faster"}, matchModeExact, false},
+		{"contains", "fast", []string{"faster"}, matchModeContains, true},
+		{"claim template", "data", []string{"data-db-0"}, matchModeClaimTemplate, true},
+		{"claim template with a large ordinal", "data", []string{"data-db-12"}, matchModeClaimTemplate, true},
+		{"claim template of another StatefulSet", "data", []string{"data-db2-0"}, matchModeClaimTemplate, false},
+		{"claim template without an ordinal", "data", []string{"data-db-"}, matchModeClaimTemplate, false},
+		{"claim template with a suffix that is not an ordinal", "data", []string{"data-db-0-backup"}, matchModeClaimTemplate, false},
+	}
+	for _, testCase := range testCases {
+		if matches := specPropertyValueMatches(testCase.fieldValue, testCase.targetValues, "db", testCase.matchMode); matches != testCase.expected {
+			t.Errorf("%s: specPropertyValueMatches = %v, expected %v", testCase.name, matches, testCase.expected)
+		}
+	}
+}
+
+func TestStorageRelationships(t *testing.T) {
+	claim := newTestObject(PVCLAIM, "data-db-0", "prod", "pvc1")
+	_ = unstructured.SetNestedMap(claim.Object, map[string]interface{}{
+		"volumeName":       "pv-1",
+		"storageClassName": "fast",
+		"dataSource":       map[string]interface{}{"apiGroup": "snapshot.storage.k8s.io", "kind": VOLUME_SNAPSHOT, "name": "nightly"},
+	}, "spec")
+	volume := newTestObject(PV, "pv-1", "", "pv1")
+	_ = unstructured.SetNestedMap(volume.Object, map[string]interface{}{
+		"storageClassName": "fast",
+		"csi":              map[string]interface{}{"driver": "ebs.csi.aws.com", "volumeHandle": "vol-1"},
+	}, "spec")
+	storageClass := newTestObject(STORAGE_CLASS, "fast", "", "sc1")
+	_ = unstructured.SetNestedField(storageClass.Object, "ebs.csi.aws.com", "provisioner")
+	snapshot := newTestObject(VOLUME_SNAPSHOT, "nightly", "prod", "vs1")
+	_ = unstructured.SetNestedMap(snapshot.Object, map[string]interface{}{
+		"source":                  map[string]interface{}{"persistentVolumeClaimName": "data-db-0"},
+		"volumeSnapshotClassName": "ebs-snap",
+	}, "spec")
+	snapshotClass := newTestObject(VOLUME_SNAPSHOT_CLASS, "ebs-snap", "", "vsc1")
+	_ = unstructured.SetNestedField(snapshotClass.Object, "ebs.csi.aws.com", "driver")
+	statefulSet := newTestObject(STATEFULSET, "db", "prod", "ss1")
+	_ = unstructured.SetNestedSlice(statefulSet.Object, []interface{}{
+		map[string]interface{}{"metadata": map[string]interface{}{"name": "data"}},
+	}, "spec", "volumeClaimTemplates")
+	setTestSearchNamespaces(t, "prod", []string{}, false)
+	setTestDynamicClient(t,
+		claim,
+		newTestObject(PVCLAIM, "data-db-1", "prod", "pvc2"),
+		newTestObject(PVCLAIM, "data-dbadmin-0", "prod", "pvc3"),
+		volume,
+		storageClass,
+		newTestObject(CSI_DRIVER, "ebs.csi.aws.com", "", "csi1"),
+		snapshot,
+		snapshotClass,
+		statefulSet,
+	)
+
+	runBuiltInReferenceTestCases(t, "prod", []builtInReferenceTestCase{
+		{
+			name: "PV of a PVC", kind: PVCLAIM, instance: "data-db-0", targetKind: PV, targetInstance: "*",
+			expected: []string{"PersistentVolume /pv-1 <- PersistentVolumeClaim prod/data-db-0: Name:spec.volumeName Value:pv-1"},
+		},
+		{
+			name: "StorageClass of a PVC", kind: PVCLAIM, instance: "data-db-0", targetKind: STORAGE_CLASS, targetInstance: "*",
+			expected: []string{"StorageClass /fast <- PersistentVolumeClaim prod/data-db-0: Name:spec.storageClassName Value:fast"},
+		},
+		{
+			name: "data source of a PVC", kind: PVCLAIM, instance: "data-db-0", targetKind: VOLUME_SNAPSHOT, targetInstance: "*",
+			expected: []string{"VolumeSnapshot prod/nightly <- PersistentVolumeClaim prod/data-db-0: Name:spec.dataSource Kind:VolumeSnapshot Value:nightly"},
+		},
+		{
+			name: "PVCs of a StorageClass", kind: PVCLAIM, instance: "*", targetKind: STORAGE_CLASS, targetInstance: "fast",
+			expected: []string{"PersistentVolumeClaim prod/data-db-0 <- StorageClass /fast: Name:spec.storageClassName Value:fast"},
+		},
+		{
+			name: "PVC of a VolumeSnapshot", kind: VOLUME_SNAPSHOT, instance: "nightly", targetKind: PVCLAIM, targetInstance: "*",
+			expected: []string{"PersistentVolumeClaim prod/data-db-0 <- VolumeSnapshot prod/nightly: Name:spec.source.persistentVolumeClaimName Value:data-db-0"},
+		},
+		{
+			name: "VolumeSnapshotClass of a VolumeSnapshot", kind: VOLUME_SNAPSHOT, instance: "nightly", targetKind: VOLUME_SNAPSHOT_CLASS, targetInstance: "*",
+			expected: []string{"VolumeSnapshotClass /ebs-snap <- VolumeSnapshot prod/nightly: Name:spec.volumeSnapshotClassName Value:ebs-snap"},
+		},
+		{
+			name: "PVs of a StorageClass", kind: PV, instance: "*", targetKind: STORAGE_CLASS, targetInstance: "fast",
+			expected: []string{"PersistentVolume /pv-1 <- StorageClass /fast: Name:spec.storageClassName Value:fast"},
+		},
+		{
+			// PVCs of volumeClaimTemplates are named <template>-<statefulset>-<ordinal>
+			name: "PVCs of a StatefulSet", kind: STATEFULSET, instance: "db", targetKind: PVCLAIM, targetInstance: "*",
+			expected: []string{
+				"PersistentVolumeClaim prod/data-db-0 <- StatefulSet prod/db: Name:spec.volumeClaimTemplates.metadata.name Value:data",
+				"PersistentVolumeClaim prod/data-db-1 <- StatefulSet prod/db: Name:spec.volumeClaimTemplates.metadata.name Value:data",
+			},
+		},
+	})
+
+	// Cluster-scoped sources
+	runBuiltInReferenceTestCases(t, "", []builtInReferenceTestCase{
+		{
+			name: "StorageClass of a PV", kind: PV, instance: "pv-1", targetKind: STORAGE_CLASS, targetInstance: "*",
+			expected: []string{"StorageClass /fast <- PersistentVolume /pv-1: Name:spec.storageClassName Value:fast"},
+		},
+		{
+			name: "CSIDriver of a PV", kind: PV, instance: "pv-1", targetKind: CSI_DRIVER, targetInstance: "*",
+			expected: []string{"CSIDriver /ebs.csi.aws.com <- PersistentVolume /pv-1: Name:spec.csi.driver Value:ebs.csi.aws.com"},
+		},
+		{
+			name: "CSIDriver of a StorageClass", kind: STORAGE_CLASS, instance: "fast", targetKind: CSI_DRIVER, targetInstance: "*",
+			expected: []string{"CSIDriver /ebs.csi.aws.com <- StorageClass /fast: Name:provisioner Value:ebs.csi.aws.com"},
+		},
+		{
+			name: "CSIDriver of a VolumeSnapshotClass", kind: VOLUME_SNAPSHOT_CLASS, instance: "ebs-snap", targetKind: CSI_DRIVER, targetInstance: "*",
+			expected: []string{"CSIDriver /ebs.csi.aws.com <- VolumeSnapshotClass /ebs-snap: Name:driver Value:ebs.csi.aws.com"},
+		},
+	})
+}
+
+func TestPodRuntimeRelationships(t *testing.T) {
+	pod := newTestObject(POD, "api-0", "prod", "p1")
+	_ = unstructured.SetNestedMap(pod.Object, map[string]interface{}{
+		"nodeName":           "worker-3",
+		"priorityClassName":  "critical",
+		"runtimeClassName":   "gvisor",
+		"serviceAccountName": "api",
+		"imagePullSecrets":   []interface{}{map[string]interface{}{"name": "registry"}},
+		"containers":         []interface{}{map[string]interface{}{"name": "api", "image": "registry.example.com/api:1"}},
+	}, "spec")
+	otherPod := newTestObject(POD, "web-0", "prod", "p2")
+	_ = unstructured.SetNestedField(otherPod.Object, "worker-1", "spec", "nodeName")
+	serviceAccount := newTestObject(SERVICE_ACCOUNT, "api", "prod", "sa1")
+	_ = unstructured.SetNestedSlice(serviceAccount.Object, []interface{}{map[string]interface{}{"name": "api-token"}}, "secrets")
+	_ = unstructured.SetNestedSlice(serviceAccount.Object, []interface{}{map[string]interface{}{"name": "registry"}}, "imagePullSecrets")
+	setTestSearchNamespaces(t, "prod", []string{}, false)
+	setTestDynamicClient(t,
+		pod,
+		otherPod,
+		serviceAccount,
+		newTestObject(NODE, "worker-3", "", "n3"),
+		newTestObject(NODE, "worker-1", "", "n1"),
+		newTestObject(PRIORITY_CLASS, "critical", "", "pc1"),
+		newTestObject(RUNTIME_CLASS, "gvisor", "", "rc1"),
+		newTestObject(SECRET, "registry", "prod", "s1"),
+		newTestObject(SECRET, "api-token", "prod", "s2"),
+		newTestObject(SECRET, "registry", "other", "s3"),
+	)
+
+	runBuiltInReferenceTestCases(t, "prod", []builtInReferenceTestCase{
+		{
+			name: "Node of a Pod", kind: POD, instance: "api-0", targetKind: NODE, targetInstance: "*",
+			expected: []string{"Node /worker-3 <- Pod prod/api-0: Name:spec.nodeName Value:worker-3"},
+		},
+		{
+			name: "Pods of a Node", kind: POD, instance: "*", targetKind: NODE, targetInstance: "worker-3",
+			expected: []string{"Pod prod/api-0 <- Node /worker-3: Name:spec.nodeName Value:worker-3"},
+		},
+		{
+			name: "PriorityClass of a Pod", kind: POD, instance: "api-0", targetKind: PRIORITY_CLASS, targetInstance: "*",
+			expected: []string{"PriorityClass /critical <- Pod prod/api-0: Name:spec.priorityClassName Value:critical"},
+		},
+		{
+			name: "RuntimeClass of a Pod", kind: POD, instance: "api-0", targetKind: RUNTIME_CLASS, targetInstance: "*",
+			expected: []string{"RuntimeClass /gvisor <- Pod prod/api-0: Name:spec.runtimeClassName Value:gvisor"},
+		},
+		{
+			name: "imagePullSecrets of a Pod", kind: POD, instance: "api-0", targetKind: SECRET, targetInstance: "*",
+			expected: []string{"Secret prod/registry <- Pod prod/api-0: Name:spec.imagePullSecrets.name Value:registry"},
+		},
+		{
+			name: "Secrets of a ServiceAccount", kind: SERVICE_ACCOUNT, instance: "api", targetKind: SECRET, targetInstance: "*",
+			expected: []string{
+				"Secret prod/api-token <- ServiceAccount prod/api: Name:secrets.name Value:api-token",
+				"Secret prod/registry <- ServiceAccount prod/api: Name:imagePullSecrets.name Value:registry",
+			},
+		},
+	})
+}
+
+func TestControlPlaneRelationships(t *testing.T) {
+	webhookService := map[string]interface{}{"name": "policy-webhook", "namespace": "policy", "path": "/validate", "port": int64(8443)}
+	validating := newTestObject(VALIDATING_WEBHOOK_CONFIGURATION, "policy", "", "vw1")
+	_ = unstructured.SetNestedSlice(validating.Object, []interface{}{
+		map[string]interface{}{"name": "validate.policy.example.com", "clientConfig": map[string]interface{}{"service": webhookService}},
+	}, "webhooks")
+	mutating := newTestObject(MUTATING_WEBHOOK_CONFIGURATION, "sidecar-injector", "", "mw1")
+	_ = unstructured.SetNestedSlice(mutating.Object, []interface{}{
+		map[string]interface{}{"name": "inject.example.com", "clientConfig": map[string]interface{}{"service": map[string]interface{}{
+			"name": "injector", "namespace": "mesh", "path": "/inject"}}},
+		map[string]interface{}{"name": "external.example.com", "clientConfig": map[string]interface{}{"url": "https://hooks.example.com"}},
+	}, "webhooks")
+	apiService := newTestObject(API_SERVICE, "v1beta1.metrics.k8s.io", "", "as1")
+	_ = unstructured.SetNestedMap(apiService.Object, map[string]interface{}{"name": "metrics-server", "namespace": "kube-system", "port": int64(443)},
+		"spec", "service")
+	// Local APIServices have no Service
+	localAPIService := newTestObject(API_SERVICE, "v1.apps", "", "as2")
+	_ = unstructured.SetNestedField(localAPIService.Object, "apps", "spec", "group")
+	crd := newTestObject(CRD, "moodles."+testCRGroup, "", "crd1")
+	_ = unstructured.SetNestedMap(crd.Object, map[string]interface{}{"strategy": "Webhook", "webhook": map[string]interface{}{
+		"clientConfig":             map[string]interface{}{"service": map[string]interface{}{"name": "moodle-conversion", "namespace": "moodle", "path": "/convert"}},
+		"conversionReviewVersions": []interface{}{"v1"},
+	}}, "spec", "conversion")
+	// apiextensions.k8s.io/v1beta1 CRD
+	legacyCRD := newTestObject(CRD, "wordpresses."+testCRGroup, "", "crd2")
+	_ = unstructured.SetNestedMap(legacyCRD.Object, map[string]interface{}{"strategy": "Webhook",
+		"webhookClientConfig": map[string]interface{}{"service": map[string]interface{}{"name": "wordpress-conversion", "namespace": "moodle"}},
+	}, "spec", "conversion")
+	setTestSearchNamespaces(t, "", []string{}, false)
+	setTestDynamicClient(t,
+		validating,
+		mutating,
+		apiService,
+		localAPIService,
+		crd,
+		legacyCRD,
+		newTestObject(SERVICE, "policy-webhook", "policy", "sv1"),
+		newTestObject(SERVICE, "injector", "mesh", "sv2"),
+		newTestObject(SERVICE, "metrics-server", "kube-system", "sv3"),
+		newTestObject(SERVICE, "moodle-conversion", "moodle", "sv4"),
+		newTestObject(SERVICE, "wordpress-conversion", "moodle", "sv5"),
+	)
+
+	runBuiltInReferenceTestCases(t, "", []builtInReferenceTestCase{
+		{
+			name: "Service of a ValidatingWebhookConfiguration", kind: VALIDATING_WEBHOOK_CONFIGURATION, instance: "policy",
+			targetKind: SERVICE, targetInstance: "*",
+			expected: []string{"Service policy/policy-webhook <- ValidatingWebhookConfiguration /policy: " +
+				"Name:webhooks.clientConfig.service Kind:Service Value:policy/policy-webhook Port:8443 Path:/validate"},
+		},
+		{
+			name: "Service of a MutatingWebhookConfiguration", kind: MUTATING_WEBHOOK_CONFIGURATION, instance: "sidecar-injector",
+			targetKind: SERVICE, targetInstance: "*",
+			expected: []string{"Service mesh/injector <- MutatingWebhookConfiguration /sidecar-injector: " +
+				"Name:webhooks.clientConfig.service Kind:Service Value:mesh/injector Path:/inject"},
+		},
+		{
+			name: "Service of an APIService", kind: API_SERVICE, instance: "v1beta1.metrics.k8s.io", targetKind: SERVICE, targetInstance: "*",
+			expected: []string{"Service kube-system/metrics-server <- APIService /v1beta1.metrics.k8s.io: " +
+				"Name:spec.service Kind:Service Value:kube-system/metrics-server Port:443"},
+		},
+		{
+			name: "local APIService", kind: API_SERVICE, instance: "v1.apps", targetKind: SERVICE, targetInstance: "*",
+			expected: []string{},
+		},
+		{
+			name: "conversion Service of a CRD", kind: CRD, instance: "moodles." + testCRGroup, targetKind: SERVICE, targetInstance: "*",
+			expected: []string{"Service moodle/moodle-conversion <- CustomResourceDefinition /moodles." + testCRGroup + ": " +
+				"Name:spec.conversion.webhook.clientConfig.service Kind:Service Value:moodle/moodle-conversion Path:/convert"},
+		},
+		{
+			name: "conversion Service of a v1beta1 CRD", kind: CRD, instance: "wordpresses." + testCRGroup, targetKind: SERVICE, targetInstance: "*",
+			expected: []string{"Service moodle/wordpress-conversion <- CustomResourceDefinition /wordpresses." + testCRGroup + ": " +
+				"Name:spec.conversion.webhookClientConfig.service Kind:Service Value:moodle/wordpress-conversion"},
+		},
+	})
+
+	// API surfaces that depend on a Service
+	setTestSearchNamespaces(t, "policy", []string{}, false)
+	runBuiltInReferenceTestCases(t, "policy", []builtInReferenceTestCase{
+		{
+			name: "webhooks of a Service", kind: VALIDATING_WEBHOOK_CONFIGURATION, instance: "*", targetKind: SERVICE, targetInstance: "policy-webhook",
+			expected: []string{"ValidatingWebhookConfiguration /policy <- Service policy/policy-webhook: " +
+				"Name:webhooks.clientConfig.service Kind:Service Value:policy/policy-webhook Port:8443 Path:/validate"},
+		},
+		{
+			name: "webhooks of another Service", kind: MUTATING_WEBHOOK_CONFIGURATION, instance: "*", targetKind: SERVICE, targetInstance: "policy-webhook",
+			expected: []string{},
+		},
+	})
+}
@@ -0,0 +1,258 @@
+package discovery
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Relationship rules are written as comma separated parts, the first part being
+// the relationship type and the rest being <name>:<value> pairs:
+//   label, on:Pod, value:INSTANCE.spec.selector
+//   specproperty, on:INSTANCE.spec.serviceAccountName, value:ServiceAccount.metadata.name
+//   annotation, on:Secret; ConfigMap, key:meta.helm.sh/release-name, value:INSTANCE.metadata.name
+//   owner reference, of:ReplicaSet, value:INSTANCE.name
+//   volume, on:INSTANCE.spec.volumes, value:Secret.metadata.name
+//   networkpolicy, on:Pod; Namespace
+//   rbac, on:ServiceAccount; Role
+//   endpoints, on:Pod; ExternalName; ExternalEndpoint
+//   managedby, on:Deployment; StatefulSet; DaemonSet
+//   objectref, on:INSTANCE.spec.scaleTargetRef, value:Deployment; StatefulSet
+//   objectref, on:INSTANCE.spec.parentRefs, value:*
+// Any rule can end with "match:exact" or "match:contains"; specproperty rules
+// can also use "match:claimtemplate".
+
+const (
+	matchModeExact    = "exact"
+	matchModeContains = "contains"
+	// PVCs of the volumeClaimTemplates of a StatefulSet (specproperty only)
+	matchModeClaimTemplate = "claimtemplate"
+)
+
+// ParseRelationshipRule parses the string form of a relationship.
+// A malformed rule results in an error describing what is wrong with it.
+func ParseRelationshipRule(relString string) (RelationshipRule, error) {
+	rule := RelationshipRule{}
+	parts := strings.Split(relString, ",")
+	rule.Type = strings.TrimSpace(parts[0])
+
+	values := make(map[string]string)
+	for _, part := range parts[1:] {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		nameValue := strings.SplitN(part, ":", 2)
+		if len(nameValue) != 2 {
+			return rule, fmt.Errorf("Relationship %q: expected <name>:<value> but found %q", relString, part)
+		}
+		name := strings.TrimSpace(nameValue[0])
+		if _, ok := values[name]; ok {
+			return rule, fmt.Errorf("Relationship %q: %q specified more than once", relString, name)
+		}
+		values[name] = strings.TrimSpace(nameValue[1])
+	}
+
+	var allowed []string
+	switch rule.Type {
+	case relTypeLabel:
+		allowed = []string{"on", "value", "match"}
+		rule.TargetKinds = parseTargetKinds(values["on"])
+		rule.SourcePath = values["value"]
+		rule.MatchMode = matchModeExact
+	case relTypeSpecProperty, relTypeVolume:
+		allowed = []string{"on", "value", "match"}
+		rule.SourcePath = values["on"]
+		target := values["value"]
+		targetParts := strings.SplitN(target, ".", 2)
+		if len(targetParts) == 2 {
+			rule.TargetKinds = parseTargetKinds(targetParts[0])
+			rule.TargetPath = targetParts[1]
+		} else if target != "" {
+			return rule, fmt.Errorf("Relationship %q: value %q should be of the form <Kind>.<path>", relString, target)
+		}
+		rule.MatchMode = matchModeExact
+	case relTypeAnnotation:
+		allowed = []string{"on", "key", "value", "match"}
+		rule.TargetKinds = parseTargetKinds(values["on"])
+		rule.TargetPath = values["key"]
+		rule.SourcePath = trimListValue(values["value"])
+		rule.MatchMode = matchModeContains
+	case relTypeOwnerReference:
+		allowed = []string{"of", "value", "match"}
+		rule.TargetKinds = parseTargetKinds(values["of"])
+		rule.SourcePath = values["value"]
+		rule.MatchMode = matchModeExact
+	case relTypeObjectRef:
+		// The kind and name are read from the reference; value lists the kinds it can refer to.
+		allowed = []string{"on", "value", "match"}
+		if values["value"] == "" {
+			values["value"] = objectRefAnyKind
+		}
+		rule.SourcePath = values["on"]
+		rule.TargetKinds = parseTargetKinds(values["value"])
+		rule.MatchMode = matchModeExact
+	case relTypeNetworkPolicy, relTypeRBAC, relTypeEndpoints, relTypeManagedBy:
+		allowed = []string{"on", "match"}
+		rule.TargetKinds = parseTargetKinds(values["on"])
+		rule.MatchMode = matchModeExact
+	default:
+		return rule, fmt.Errorf("Relationship %q: unknown relationship type %q", relString, rule.Type)
+	}
+
+	for name := range values {
+		if !containsString(allowed, name) {
+			return rule, fmt.Errorf("Relationship %q: %q is not valid for %s relationships", relString, name, rule.Type)
+		}
+	}
+	for _, name := range allowed {
+		if name != "match" && values[name] == "" {
+			return rule, fmt.Errorf("Relationship %q: missing %q", relString, name)
+		}
+	}
+	if len(rule.TargetKinds) == 0 {
+		return rule, fmt.Errorf("Relationship %q: no target Kind", relString)
+	}
+	if matchMode, ok := values["match"]; ok {
+		if matchMode != matchModeExact && matchMode != matchModeContains && matchMode != matchModeClaimTemplate {
+			return rule, fmt.Errorf("Relationship %q: unknown match mode %q", relString, matchMode)
+		}
+		if matchMode == matchModeClaimTemplate && rule.Type != relTypeSpecProperty {
+			return rule, fmt.Errorf("Relationship %q: match mode %q is only valid for %s relationships", relString, matchMode, relTypeSpecProperty)
+		}
+		rule.MatchMode = matchMode
+	}
+	return rule, nil
+}
+
+// Built-in rules are constant; an error in them is a programming error.
+func mustParseRelationshipRule(relString string) RelationshipRule {
+	rule, err := ParseRelationshipRule(relString)
+	if err != nil {
+		panic(err)
+	}
+	return rule
+}
+
+// Parses the relationships defined on a CRD. Malformed ones are reported and skipped.
+func parseRelationshipRules(kind string, relStrings []string) []RelationshipRule {
+	rules := make([]RelationshipRule, 0)
+	for _, relString := range relStrings {
+		rule, err := ParseRelationshipRule(relString)
+		if err != nil {
+			fmt.Printf("Ignoring relationship of %s: %s\n", kind, err.Error())
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// Adds rules to the relationships of a kind, skipping the ones it already has,
+// so that reading the kind composition file again does not duplicate them.
+func addRelationshipRules(kind string, rules []RelationshipRule) {
+	relationshipMapMux.Lock()
+	defer relationshipMapMux.Unlock()
+	for _, rule := range rules {
+		found := false
+		for _, existing := range relationshipMap[kind] {
+			if existing.String() == rule.String() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			relationshipMap[kind] = append(relationshipMap[kind], rule)
+		}
+	}
+}
+
+func getRelationshipRules(kind string) []RelationshipRule {
+	relationshipMapMux.RLock()
+	defer relationshipMapMux.RUnlock()
+	return relationshipMap[kind]
+}
+
+func setRelationshipRules(kind string, rules []RelationshipRule) {
+	relationshipMapMux.Lock()
+	defer relationshipMapMux.Unlock()
+	relationshipMap[kind] = rules
+}
+
+// String returns the canonical string form of the rule, which parses back to the same rule.
+func (rule RelationshipRule) String() string {
+	var relString string
+	targetKinds := strings.Join(rule.TargetKinds, "; ")
+	switch rule.Type {
+	case relTypeLabel:
+		relString = rule.Type + ", on:" + targetKinds + ", value:" + rule.SourcePath
+	case relTypeSpecProperty, relTypeVolume:
+		relString = rule.Type + ", on:" + rule.SourcePath + ", value:" + targetKinds + "." + rule.TargetPath
+	case relTypeAnnotation:
+		relString = rule.Type + ", on:" + targetKinds + ", key:" + rule.TargetPath + ", value:" + rule.SourcePath
+	case relTypeOwnerReference:
+		relString = rule.Type + ", of:" + targetKinds + ", value:" + rule.SourcePath
+	case relTypeObjectRef:
+		relString = rule.Type + ", on:" + rule.SourcePath + ", value:" + targetKinds
+	case relTypeNetworkPolicy, relTypeRBAC, relTypeEndpoints, relTypeManagedBy:
+		relString = rule.Type + ", on:" + targetKinds
+	default:
+		relString = rule.Type
+	}
+	if rule.MatchMode != defaultMatchMode(rule.Type) {
+		relString = relString + ", match:" + rule.MatchMode
+	}
+	return relString
+}
+
+// Last element of the source path, e.g. serviceAccountName for INSTANCE.spec.serviceAccountName
+func (rule RelationshipRule) sourceField() string {
+	pathParts := strings.Split(rule.SourcePath, ".")
+	return pathParts[len(pathParts)-1]
+}
+
+// Last element of the target path, e.g. name for spec.metadata.name
+func (rule RelationshipRule) targetField() string {
+	pathParts := strings.Split(rule.TargetPath, ".")
+	return pathParts[len(pathParts)-1]
+}
+
+func (rule RelationshipRule) hasTargetKind(kind string) bool {
+	return containsString(rule.TargetKinds, kind)
+}
+
+func defaultMatchMode(relType string) string {
+	if relType == relTypeAnnotation {
+		return matchModeContains
+	}
+	return matchModeExact
+}
+
+func parseTargetKinds(targetKindString string) []string {
+	targetKinds := make([]string, 0)
+	for _, targetKind := range strings.Split(targetKindString, ";") {
+		targetKind = strings.TrimSpace(targetKind)
+		if targetKind != "" {
+			targetKinds = append(targetKinds, targetKind)
+		}
+	}
+	return targetKinds
+}
+
+// Annotation values can be given as [{name:INSTANCE.metadata.name}]
+func trimListValue(value string) string {
+	value = strings.TrimPrefix(value, "[")
+	value = strings.TrimSuffix(value, "]")
+	value = strings.TrimPrefix(value, "{")
+	value = strings.TrimSuffix(value, "}")
+	value = strings.TrimPrefix(value, "name:")
+	return strings.TrimSpace(value)
+}
+
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
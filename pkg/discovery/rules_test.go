@@ -0,0 +1,163 @@
+package discovery
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseRelationshipRule(t *testing.T) {
+	testCases := []struct {
+		relString string
+		expected  RelationshipRule
+	}{
+		{
+			relString: "label, on:Pod, value:INSTANCE.spec.selector",
+			expected: RelationshipRule{Type: relTypeLabel, SourcePath: "INSTANCE.spec.selector",
+				TargetKinds: []string{POD}, MatchMode: matchModeExact},
+		},
+		{
+			relString: "specproperty, on:INSTANCE.spec.serviceAccountName, value:ServiceAccount.metadata.name",
+			expected: RelationshipRule{Type: relTypeSpecProperty, SourcePath: "INSTANCE.spec.serviceAccountName",
+				TargetKinds: []string{SERVICE_ACCOUNT}, TargetPath: "metadata.name", MatchMode: matchModeExact},
+		},
+		{
+			relString: "specproperty, on:INSTANCE.spec.template.spec.containers[*].env[*].value, value:Service.metadata.name, match:contains",
+			expected: RelationshipRule{Type: relTypeSpecProperty, SourcePath: "INSTANCE.spec.template.spec.containers[*].env[*].value",
+				TargetKinds: []string{SERVICE}, TargetPath: "metadata.name", MatchMode: matchModeContains},
+		},
+		{
+			relString: "specproperty, on:INSTANCE.spec.volumeClaimTemplates, value:PersistentVolumeClaim.metadata.name, match:claimtemplate",
+			expected: RelationshipRule{Type: relTypeSpecProperty, SourcePath: "INSTANCE.spec.volumeClaimTemplates",
+				TargetKinds: []string{PVCLAIM}, TargetPath: "metadata.name", MatchMode: matchModeClaimTemplate},
+		},
+		{
+			relString: "annotation, on:Secret; ConfigMap, key:meta.helm.sh/release-name, value:[{name:INSTANCE.metadata.name}]",
+			expected: RelationshipRule{Type: relTypeAnnotation, SourcePath: "INSTANCE.metadata.name",
+				TargetKinds: []string{SECRET, CONFIG_MAP}, TargetPath: "meta.helm.sh/release-name", MatchMode: matchModeContains},
+		},
+		{
+			relString: "annotation, on:Secret, key:meta.helm.sh/release-name, value:INSTANCE.metadata.name, match:exact",
+			expected: RelationshipRule{Type: relTypeAnnotation, SourcePath: "INSTANCE.metadata.name",
+				TargetKinds: []string{SECRET}, TargetPath: "meta.helm.sh/release-name", MatchMode: matchModeExact},
+		},
+		{
+			relString: "owner reference, of:ReplicaSet, value:INSTANCE.name",
+			expected: RelationshipRule{Type: relTypeOwnerReference, SourcePath: "INSTANCE.name",
+				TargetKinds: []string{REPLICA_SET}, MatchMode: matchModeExact},
+		},
+		{
+			relString: "volume, on:INSTANCE.spec.volumes, value:Secret.metadata.name",
+			expected: RelationshipRule{Type: relTypeVolume, SourcePath: "INSTANCE.spec.volumes",
+				TargetKinds: []string{SECRET}, TargetPath: "metadata.name", MatchMode: matchModeExact},
+		},
+		{
+			relString: "objectref, on:INSTANCE.spec.scaleTargetRef, value:Deployment; StatefulSet",
+			expected: RelationshipRule{Type: relTypeObjectRef, SourcePath: "INSTANCE.spec.scaleTargetRef",
+				TargetKinds: []string{DEPLOYMENT, STATEFULSET}, MatchMode: matchModeExact},
+		},
+		{
+			relString: "objectref, on:INSTANCE.spec.parentRefs",
+			expected: RelationshipRule{Type: relTypeObjectRef, SourcePath: "INSTANCE.spec.parentRefs",
+				TargetKinds: []string{objectRefAnyKind}, MatchMode: matchModeExact},
+		},
+		{
+			relString: "networkpolicy, on:Pod; Namespace",
+			expected: RelationshipRule{Type: relTypeNetworkPolicy,
+				TargetKinds: []string{POD, NAMESPACE}, MatchMode: matchModeExact},
+		},
+		{
+			relString: "rbac, on:ServiceAccount; Role",
+			expected: RelationshipRule{Type: relTypeRBAC,
+				TargetKinds: []string{SERVICE_ACCOUNT, ROLE}, MatchMode: matchModeExact},
+		},
+		{
+			relString: "endpoints, on:Pod; ExternalName; ExternalEndpoint",
+			expected: RelationshipRule{Type: relTypeEndpoints,
+				TargetKinds: []string{POD, "ExternalName", "ExternalEndpoint"}, MatchMode: matchModeExact},
+		},
+		{
+			relString: " managedby , on: Deployment ;StatefulSet, ",
+			expected: RelationshipRule{Type: relTypeManagedBy,
+				TargetKinds: []string{DEPLOYMENT, STATEFULSET}, MatchMode: matchModeExact},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.relString, func(t *testing.T) {
+			rule, err := ParseRelationshipRule(testCase.relString)
+			if err != nil {
+				t.Fatalf("ParseRelationshipRule: %v", err)
+			}
+			if !reflect.DeepEqual(rule, testCase.expected) {
+				t.Fatalf("ParseRelationshipRule = %+v, expected %+v", rule, testCase.expected)
+			}
+			roundTrip, err := ParseRelationshipRule(rule.String())
+			if err != nil {
+				t.Fatalf("ParseRelationshipRule(%q): %v", rule.String(), err)
+			}
+			if !reflect.DeepEqual(roundTrip, rule) {
+				t.Errorf("ParseRelationshipRule(%q) = %+v, expected %+v", rule.String(), roundTrip, rule)
+			}
+		})
+	}
+}
+
+func TestParseRelationshipRuleErrors(t *testing.T) {
+	testCases := []struct {
+		relString string
+		expected  string
+	}{
+		{
+			relString: "label, on:Pod, value",
+			expected:  `Relationship "label, on:Pod, value": expected <name>:<value> but found "value"`,
+		},
+		{
+			relString: "label, on:Pod, on:Service, value:INSTANCE.spec.selector",
+			expected:  `Relationship "label, on:Pod, on:Service, value:INSTANCE.spec.selector": "on" specified more than once`,
+		},
+		{
+			relString: "specproperty, on:INSTANCE.spec.serviceAccountName, value:ServiceAccount",
+			expected: `Relationship "specproperty, on:INSTANCE.spec.serviceAccountName, value:ServiceAccount": ` +
+				`value "ServiceAccount" should be of the form <Kind>.<path>`,
+		},
+		{
+			relString: "selector, on:Pod",
+			expected:  `Relationship "selector, on:Pod": unknown relationship type "selector"`,
+		},
+		{
+			relString: "",
+			expected:  `Relationship "": unknown relationship type ""`,
+		},
+		{
+			relString: "label, on:Pod, value:INSTANCE.spec.selector, key:app",
+			expected:  `Relationship "label, on:Pod, value:INSTANCE.spec.selector, key:app": "key" is not valid for label relationships`,
+		},
+		{
+			relString: "label, on:Pod",
+			expected:  `Relationship "label, on:Pod": missing "value"`,
+		},
+		{
+			relString: "label, on: ; , value:INSTANCE.spec.selector",
+			expected:  `Relationship "label, on: ; , value:INSTANCE.spec.selector": no target Kind`,
+		},
+		{
+			relString: "label, on:Pod, value:INSTANCE.spec.selector, match:regex",
+			expected:  `Relationship "label, on:Pod, value:INSTANCE.spec.selector, match:regex": unknown match mode "regex"`,
+		},
+		{
+			relString: "label, on:Pod, value:INSTANCE.spec.selector, match:claimtemplate",
+			expected: `Relationship "label, on:Pod, value:INSTANCE.spec.selector, match:claimtemplate": ` +
+				`match mode "claimtemplate" is only valid for specproperty relationships`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.relString, func(t *testing.T) {
+			_, err := ParseRelationshipRule(testCase.relString)
+			if err == nil {
+				t.Fatalf("ParseRelationshipRule did not fail, expected %s", testCase.expected)
+			}
+			if err.Error() != testCase.expected {
+				t.Errorf("ParseRelationshipRule error = %s, expected %s", err.Error(), testCase.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package discovery
+
+import (
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Label selectors used in label relationships. The selector at the path of the
+// relationship can be a plain label map (as in Service.spec.selector) or a full
+// metav1.LabelSelector with matchLabels and/or matchExpressions.
+//
+// Kubernetes gives empty selectors two meanings. A Service without a selector, or
+// a workload whose selector is missing, selects nothing: its Pods are managed by
+// other means. An empty podSelector or namespaceSelector of a NetworkPolicy ({})
+// selects every Pod or Namespace. matchLabelSelector follows the first convention
+// and is used by label relationships; matchesNetworkPolicySelector in
+// networkpolicy.go follows the second one.
+
+const defaultSelectorPath = "spec.selector"
+
+// Returns nil when there is no selector at the path.
+func getLabelSelectorAtPath(content map[string]interface{}, selectorPath string) *metav1.LabelSelector {
+	selectorPath = trimInstancePrefix(selectorPath)
+	if selectorPath == "" {
+		selectorPath = defaultSelectorPath
+	}
+	fields := strings.Split(selectorPath, ".")
+	value, found, err := unstructured.NestedFieldNoCopy(content, fields...)
+	if !found || err != nil {
+		return nil
+	}
+	return toLabelSelector(value)
+}
+
+func toLabelSelector(value interface{}) *metav1.LabelSelector {
+	selectorMap, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	_, hasMatchLabels := selectorMap["matchLabels"]
+	_, hasMatchExpressions := selectorMap["matchExpressions"]
+	if hasMatchLabels || hasMatchExpressions {
+		labelSelector := metav1.LabelSelector{}
+		err := runtime.DefaultUnstructuredConverter.FromUnstructured(selectorMap, &labelSelector)
+		if err != nil {
+			return nil
+		}
+		return &labelSelector
+	}
+	matchLabels := make(map[string]string)
+	for key, val := range selectorMap {
+		stringVal, ok := val.(string)
+		if !ok {
+			return nil
+		}
+		matchLabels[key] = stringVal
+	}
+	return &metav1.LabelSelector{MatchLabels: matchLabels}
+}
+
+// Both a missing (nil) selector and one without requirements are empty.
+func isEmptyLabelSelector(labelSelector *metav1.LabelSelector) bool {
+	return labelSelector == nil || (len(labelSelector.MatchLabels) == 0 && len(labelSelector.MatchExpressions) == 0)
+}
+
+// An empty selector matches no labels.
+func matchLabelSelector(labelSelector *metav1.LabelSelector, objLabels map[string]string) bool {
+	if isEmptyLabelSelector(labelSelector) {
+		return false
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(objLabels))
+}
+
+// All the requirements of a selector have to match, so all of them are listed:
+// matchLabels as key:value and matchExpressions as "key Operator [values]".
+func describeLabelSelector(labelSelector *metav1.LabelSelector) string {
+	relDetail := ""
+	if labelSelector == nil {
+		return relDetail
+	}
+	keys := make([]string, 0)
+	for key := range labelSelector.MatchLabels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		relDetail = relDetail + key + ":" + labelSelector.MatchLabels[key] + " "
+	}
+	for _, expression := range labelSelector.MatchExpressions {
+		relDetail = relDetail + expression.Key + " " + string(expression.Operator)
+		if len(expression.Values) > 0 {
+			relDetail = relDetail + " [" + strings.Join(expression.Values, ",") + "]"
+		}
+		relDetail = relDetail + " "
+	}
+	return relDetail
+}
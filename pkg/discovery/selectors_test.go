@@ -0,0 +1,132 @@
+package discovery
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestToLabelSelector(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    interface{}
+		expected *metav1.LabelSelector
+	}{
+		{
+			name:     "label map",
+			value:    map[string]interface{}{"app": "web"},
+			expected: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		},
+		{
+			name: "LabelSelector",
+			value: map[string]interface{}{
+				"matchLabels": map[string]interface{}{"app": "web"},
+				"matchExpressions": []interface{}{
+					map[string]interface{}{"key": "tier", "operator": "In", "values": []interface{}{"frontend", "cache"}},
+				},
+			},
+			expected: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "web"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"frontend", "cache"}},
+				},
+			},
+		},
+		{
+			name:     "empty map",
+			value:    map[string]interface{}{},
+			expected: &metav1.LabelSelector{MatchLabels: map[string]string{}},
+		},
+		{
+			name:     "label map with a non-string value",
+			value:    map[string]interface{}{"replicas": int64(1)},
+			expected: nil,
+		},
+		{
+			name:     "not a map",
+			value:    "app=web",
+			expected: nil,
+		},
+		{
+			name:     "missing",
+			value:    nil,
+			expected: nil,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			labelSelector := toLabelSelector(testCase.value)
+			if !reflect.DeepEqual(labelSelector, testCase.expected) {
+				t.Errorf("toLabelSelector = %+v, expected %+v", labelSelector, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestMatchLabelSelector(t *testing.T) {
+	webLabels := map[string]string{"app": "web", "tier": "frontend"}
+	expression := func(key string, operator metav1.LabelSelectorOperator, values ...string) *metav1.LabelSelector {
+		return &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: key, Operator: operator, Values: values},
+		}}
+	}
+	testCases := []struct {
+		name           string
+		labelSelector  *metav1.LabelSelector
+		labels         map[string]string
+		expected       bool
+		expectedPolicy bool
+	}{
+		{"matchLabels", &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}, webLabels, true, true},
+		{"matchLabels of another value", &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}, webLabels, false, false},
+		{"In", expression("tier", metav1.LabelSelectorOpIn, "frontend", "cache"), webLabels, true, true},
+		{"In without the value", expression("tier", metav1.LabelSelectorOpIn, "backend"), webLabels, false, false},
+		{"NotIn", expression("tier", metav1.LabelSelectorOpNotIn, "backend"), webLabels, true, true},
+		{"NotIn with the value", expression("tier", metav1.LabelSelectorOpNotIn, "frontend"), webLabels, false, false},
+		{"NotIn without the label", expression("zone", metav1.LabelSelectorOpNotIn, "a"), webLabels, true, true},
+		{"Exists", expression("app", metav1.LabelSelectorOpExists), webLabels, true, true},
+		{"Exists without the label", expression("zone", metav1.LabelSelectorOpExists), webLabels, false, false},
+		{"DoesNotExist", expression("zone", metav1.LabelSelectorOpDoesNotExist), webLabels, true, true},
+		{"DoesNotExist with the label", expression("app", metav1.LabelSelectorOpDoesNotExist), webLabels, false, false},
+		{"invalid operator", expression("app", "Equals", "web"), webLabels, false, false},
+		{
+			name: "all the requirements",
+			labelSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": "web"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"backend"}},
+				},
+			},
+			labels: webLabels,
+		},
+		// Empty selectors: nothing for label relationships, everything for NetworkPolicies
+		{"empty selector", &metav1.LabelSelector{}, webLabels, false, true},
+		{"empty selector and no labels", &metav1.LabelSelector{MatchLabels: map[string]string{}}, nil, false, true},
+		{"missing selector", nil, webLabels, false, false},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if match := matchLabelSelector(testCase.labelSelector, testCase.labels); match != testCase.expected {
+				t.Errorf("matchLabelSelector = %t, expected %t", match, testCase.expected)
+			}
+			if match := matchesNetworkPolicySelector(testCase.labelSelector, testCase.labels); match != testCase.expectedPolicy {
+				t.Errorf("matchesNetworkPolicySelector = %t, expected %t", match, testCase.expectedPolicy)
+			}
+		})
+	}
+}
+
+func TestDescribeLabelSelector(t *testing.T) {
+	labelSelector := &metav1.LabelSelector{
+		MatchLabels: map[string]string{"tier": "frontend", "app": "web"},
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "zone", Operator: metav1.LabelSelectorOpIn, Values: []string{"a", "b"}},
+			{Key: "canary", Operator: metav1.LabelSelectorOpDoesNotExist},
+		},
+	}
+	expected := "app:web tier:frontend zone In [a,b] canary DoesNotExist "
+	if detail := describeLabelSelector(labelSelector); detail != expected {
+		t.Errorf("describeLabelSelector = %q, expected %q", detail, expected)
+	}
+}
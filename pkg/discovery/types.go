@@ -12,32 +12,61 @@ type composition struct {
 	Plural      string   `yaml:"plural"`
 	Endpoint    string   `yaml:"endpoint"`
 	Composition []string `yaml:"composition"`
+	Relationships []string `yaml:"relationships"`
+	ManagedBy   string   `yaml:"managedBy"`
 }
 
 // Used for Final output
 type Composition struct {
-	Level     int
-	Kind      string
-	Name      string
-	Namespace string
-	Status    string
-	Children  []Composition
+	Level           int
+	Kind            string
+	Name            string
+	Namespace       string
+	UID             string
+	Status          string
+	// Own health aggregated with the worst health of the children
+	Health          Health
+	OwnerReferences []OwnerReference
+	Children        []Composition
+}
+
+// Computed health of a resource: Ready, Progressing, Degraded or Unknown
+type Health struct {
+	Status string
+	Reason string
+}
+
+// Owner reference of a resource, as recorded in its metadata
+type OwnerReference struct {
+	APIVersion         string
+	Kind               string
+	Name               string
+	UID                string
+	Controller         bool
+	BlockOwnerDeletion bool
 }
 
 // Used to store information queried from the main API server
 type MetaDataAndOwnerReferences struct {
 	MetaDataName             string
+	UID                      string
 	Status                   string
+	Health                   Health
 	Namespace                string
 	OwnerReferenceName       string
 	OwnerReferenceKind       string
 	OwnerReferenceAPIVersion string
+	OwnerReferenceUID        string
+	// All the owners of the resource, not only the one it was queried for
+	OwnerReferences          []OwnerReference
 }
 
 // Used for intermediate storage -- probably can be combined/merged with
-// type Composition
+// type Composition. Each node holds the children of one Kind of the resource
+// identified by ParentUID; the tree is rebuilt by following these UIDs.
 type CompositionTreeNode struct {
 	Level     int
+	ParentUID string
 	ChildKind string
 	Children  []MetaDataAndOwnerReferences
 }
@@ -47,7 +76,10 @@ type Compositions struct {
 	Kind            string
 	Name            string
 	Namespace       string
+	UID             string
 	Status          string
+	Health          Health
+	OwnerReferences []OwnerReference
 	CompositionTree *[]CompositionTreeNode
 }
 
@@ -57,6 +89,48 @@ type ClusterCompositions struct {
 	mux                 sync.Mutex
 }
 
+// Used for output of ancestry queries
+type Ancestor struct {
+	Kind      string
+	Name      string
+	Namespace string
+	UID       string
+	Status    string
+	Health    Health
+	Owners    []Ancestor
+}
+
+// Parsed form of a relationship such as
+// "specproperty, on:INSTANCE.spec.serviceAccountName, value:ServiceAccount.metadata.name"
+type RelationshipRule struct {
+	Type        string
+	// Path on the instance that has the relationship, e.g. INSTANCE.spec.serviceAccountName
+	SourcePath  string
+	TargetKinds []string
+	// Path (or annotation key) on the target, e.g. metadata.name
+	TargetPath  string
+	// exact or contains
+	MatchMode   string
+}
+
+// An env variable of a container that refers to another resource, either by its
+// value or through valueFrom/envFrom (Kind is ConfigMap or Secret in that case)
+type envReference struct {
+	Container string
+	EnvName   string
+	Value     string
+	Kind      string
+	Key       string
+}
+
+// A volume of a pod spec that refers to a ConfigMap, Secret or PersistentVolumeClaim
+type volumeReference struct {
+	Volume     string
+	Kind       string
+	Name       string
+	MountPaths []string
+}
+
 type Connection struct {
 	Level           int
 	Kind            string
@@ -82,6 +156,19 @@ type ConnectionOutput struct {
 	RelationDetails string
 }
 
+// Used for dot and mermaid output of connections and compositions
+type graphNode struct {
+	Kind      string
+	Name      string
+	Namespace string
+}
+
+type graphEdge struct {
+	From  graphNode
+	To    graphNode
+	Label string
+}
+
 type KubeObjectCacheEntry struct {
 	Namespace string
 	Kind string
@@ -98,6 +185,8 @@ var (
 	ANNOTATION_REL_ANNOTATION string
 	LABEL_REL_ANNOTATION string
 	SPECPROPERTY_REL_ANNOTATION string
+	OBJECTREF_REL_ANNOTATION string
+	MANAGED_BY_ANNOTATION string
 
 	TotalClusterCompositions ClusterCompositions
 	TotalClusterConnections []Connection
@@ -105,9 +194,19 @@ var (
 	KindPluralMap  map[string]string
 	kindVersionMap map[string]string
 	kindGroupMap map[string]string
+	// Guards KindPluralMap, kindVersionMap, kindGroupMap and clusterScopedKinds, which
+	// are written when CRDs are read and Kinds are discovered while queries are served
+	kindMapsMux sync.RWMutex
 	compositionMap map[string][]string
-	relationshipMap map[string][]string
+	// compositionMap is read by the informer handlers while ReadKinds updates it
+	compositionMapMux sync.RWMutex
+	relationshipMap map[string][]RelationshipRule
+	// relationshipMap is updated by EnableEndpointsRelationships and ReadKinds while connections are searched
+	relationshipMapMux sync.RWMutex
 	crdcompositionMap map[string][]string
+	clusterScopedKinds map[string]bool
+	// Kind -> version used when the cluster does not serve the version in kindVersionMap
+	betaVersionMap map[string]string
 
 	kubeObjectListCache map[KubeObjectCacheEntry]interface{}
 	kubeObjectCache map[KubeObjectCacheEntry]interface{}
@@ -123,27 +222,67 @@ var (
 	PV           string
 	ETCD_CLUSTER string
 	INGRESS      string
+	INGRESS_CLASS string
+	ENDPOINTS    string
+	ENDPOINT_SLICE string
+	EXTERNAL_NAME string
+	EXTERNAL_ENDPOINT string
+	STORAGE_CLASS string
+	CSI_DRIVER   string
+	VOLUME_SNAPSHOT string
+	VOLUME_SNAPSHOT_CLASS string
+	NODE         string
+	PRIORITY_CLASS string
+	RUNTIME_CLASS string
+	VALIDATING_WEBHOOK_CONFIGURATION string
+	MUTATING_WEBHOOK_CONFIGURATION string
+	API_SERVICE  string
+	CRD          string
 	STATEFULSET  string
 	DAEMONSET    string
+	JOB          string
+	CRONJOB      string
+	CONTROLLER_REVISION string
 	RC           string
 	PDB 		 string
 	NAMESPACE    string
+	NETWORK_POLICY string
+	ROLE         string
+	CLUSTER_ROLE string
+	ROLE_BINDING string
+	CLUSTER_ROLE_BINDING string
+	RBAC_USER    string
+	RBAC_GROUP   string
+	HPA          string
+	VPA          string
 
 	relTypeLabel string
 	relTypeSpecProperty string
 	relTypeEnvvariable string
 	relTypeAnnotation string
 	relTypeOwnerReference string
+	relTypeVolume string
+	relTypeNetworkPolicy string
+	relTypeRBAC string
+	relTypeObjectRef string
+	relTypeEndpoints string
+	relTypeManagedBy string
 
-	green, red, yellow, purple, cyan, reset string
+	healthReady, healthProgressing, healthDegraded, healthUnknown string
+
+	green, red, yellow, purple, cyan, blue, reset string
 
 	// Set to inputs given to connections
 	OrigKind, OrigName, OrigNamespace string
 	OrigLevel int
 	OutputFormat string
 	RelsToIgnore string
+	RBACSummary bool
 
-	NamespaceToSearch string
+	// Namespaces the connections search can reach besides the input namespace
+	// (--namespaces); no bound with SearchAllNamespaces (--all-namespaces)
+	SearchNamespaces []string
+	SearchAllNamespaces bool
 	OriginalInputNamespace string
 	OriginalInputKind string
 	OriginalInputInstance string
@@ -154,13 +293,15 @@ func init() {
 	ALLOWED_COMMANDS = make(map[string]string,0)
 	ALLOWED_COMMANDS["composition"] = "composition"
 	ALLOWED_COMMANDS["connections"] = "connections"
+	ALLOWED_COMMANDS["ancestry"] = "ancestry"
+	ALLOWED_COMMANDS["kinds"] = "kinds"
 	ALLOWED_COMMANDS["man"] = "man"
 	ALLOWED_COMMANDS["networkmetrics"] = "networkmetrics"
 	ALLOWED_COMMANDS["podmetrics"] = "podmetrics"
 
 	TotalClusterCompositions = ClusterCompositions{}
 
-	NamespaceToSearch = ""
+	SearchNamespaces = make([]string, 0)
 
 	DEPLOYMENT = "Deployment"
 	REPLICA_SET = "ReplicaSet"
@@ -172,24 +313,65 @@ func init() {
 	PV = "PersistentVolume"
 	ETCD_CLUSTER = "EtcdCluster"
 	INGRESS = "Ingress"
+	INGRESS_CLASS = "IngressClass"
+	ENDPOINTS = "Endpoints"
+	ENDPOINT_SLICE = "EndpointSlice"
+	// External nodes of Services
+	EXTERNAL_NAME = "ExternalName"
+	EXTERNAL_ENDPOINT = "ExternalEndpoint"
+	STORAGE_CLASS = "StorageClass"
+	CSI_DRIVER = "CSIDriver"
+	VOLUME_SNAPSHOT = "VolumeSnapshot"
+	VOLUME_SNAPSHOT_CLASS = "VolumeSnapshotClass"
+	NODE = "Node"
+	PRIORITY_CLASS = "PriorityClass"
+	RUNTIME_CLASS = "RuntimeClass"
+	VALIDATING_WEBHOOK_CONFIGURATION = "ValidatingWebhookConfiguration"
+	MUTATING_WEBHOOK_CONFIGURATION = "MutatingWebhookConfiguration"
+	API_SERVICE = "APIService"
+	CRD = "CustomResourceDefinition"
 	STATEFULSET = "StatefulSet"
 	DAEMONSET = "DaemonSet"
+	JOB = "Job"
+	CRONJOB = "CronJob"
+	CONTROLLER_REVISION = "ControllerRevision"
 	RC = "ReplicationController"
 	PDB = "PodDisruptionBudget"
 	SERVICE_ACCOUNT = "ServiceAccount"
 	NAMESPACE = "Namespace"
+	NETWORK_POLICY = "NetworkPolicy"
+	ROLE = "Role"
+	CLUSTER_ROLE = "ClusterRole"
+	ROLE_BINDING = "RoleBinding"
+	CLUSTER_ROLE_BINDING = "ClusterRoleBinding"
+	RBAC_USER = "User"
+	RBAC_GROUP = "Group"
+	HPA = "HorizontalPodAutoscaler"
+	VPA = "VerticalPodAutoscaler"
 
 	relTypeLabel = "label"
 	relTypeSpecProperty = "specproperty"
 	relTypeEnvvariable = "envvariable"
 	relTypeAnnotation = "annotation"
 	relTypeOwnerReference = "owner reference"
+	relTypeVolume = "volume"
+	relTypeNetworkPolicy = "networkpolicy"
+	relTypeRBAC = "rbac"
+	relTypeObjectRef = "objectref"
+	relTypeEndpoints = "endpoints"
+	relTypeManagedBy = "managedby"
+
+	healthReady = "Ready"
+	healthProgressing = "Progressing"
+	healthDegraded = "Degraded"
+	healthUnknown = "Unknown"
 
 	green = "\033[32m"
 	red   = "\033[31m"
 	yellow = "\033[33m"
 	purple = "\033[35m"
 	cyan   = "\033[36m"
+	blue   = "\033[34m"
 	reset = "\033[0m"
 
 	KindPluralMap = make(map[string]string)
@@ -197,8 +379,10 @@ func init() {
 	kindVersionMap = make(map[string]string) 
 	compositionMap = make(map[string][]string, 0)
 	crdcompositionMap = make(map[string][]string, 0)
+	clusterScopedKinds = make(map[string]bool)
+	betaVersionMap = make(map[string]string)
 	kindGroupMap = make(map[string]string)
-	relationshipMap = make(map[string][]string)
+	relationshipMap = make(map[string][]RelationshipRule)
 
 	kubeObjectListCache = make(map[KubeObjectCacheEntry]interface{})
 	kubeObjectCache = make(map[KubeObjectCacheEntry]interface{})
@@ -208,24 +392,35 @@ func init() {
 	kindVersionMap[DEPLOYMENT] = "apis/apps/v1"
 	kindGroupMap[DEPLOYMENT] = "apps"
 	compositionMap[DEPLOYMENT] = []string{"ReplicaSet"}
-	deploymentRelationships := make([]string,0)
+	deploymentRelationships := make([]RelationshipRule,0)
 	depRel := "owner reference, of:ReplicaSet, value:INSTANCE.name"
-	deploymentRelationships = append(deploymentRelationships, depRel)
+	deploymentRelationships = append(deploymentRelationships, mustParseRelationshipRule(depRel))
 	relationshipMap[DEPLOYMENT] = deploymentRelationships
 
 	KindPluralMap[REPLICA_SET] = "replicasets"
 	kindVersionMap[REPLICA_SET] = "apis/apps/v1"
 	kindGroupMap[REPLICA_SET] = "apps"
 	compositionMap[REPLICA_SET] = []string{"Pod"}
-	replicasetRelationships := make([]string,0)
+	replicasetRelationships := make([]RelationshipRule,0)
 	replicasetRel := "owner reference, of:Pod, value:INSTANCE.name"
-	replicasetRelationships = append(replicasetRelationships, replicasetRel)
+	replicasetRelationships = append(replicasetRelationships, mustParseRelationshipRule(replicasetRel))
 	relationshipMap[REPLICA_SET] = replicasetRelationships
 
 	KindPluralMap[DAEMONSET] = "daemonsets"
 	kindVersionMap[DAEMONSET] = "apis/apps/v1"
 	kindGroupMap[DAEMONSET] = "apps"
-	compositionMap[DAEMONSET] = []string{"Pod"}
+	compositionMap[DAEMONSET] = []string{"Pod", "ControllerRevision"}
+	dsetRelationships := make([]RelationshipRule,0)
+	dsRel1 := "owner reference, of:Pod, value:INSTANCE.name"
+	dsetRelationships = append(dsetRelationships, mustParseRelationshipRule(dsRel1))
+	dsRel2 := "owner reference, of:ControllerRevision, value:INSTANCE.name"
+	dsetRelationships = append(dsetRelationships, mustParseRelationshipRule(dsRel2))
+	relationshipMap[DAEMONSET] = dsetRelationships
+
+	KindPluralMap[CONTROLLER_REVISION] = "controllerrevisions"
+	kindVersionMap[CONTROLLER_REVISION] = "apis/apps/v1"
+	kindGroupMap[CONTROLLER_REVISION] = "apps"
+	compositionMap[CONTROLLER_REVISION] = []string{}
 
 	KindPluralMap[RC] = "replicationcontrollers"
 	kindVersionMap[RC] = "api/v1"
@@ -233,54 +428,270 @@ func init() {
 	compositionMap[RC] = []string{"Pod"}
 
 	KindPluralMap[PDB] = "poddisruptionbudgets"
-	kindVersionMap[PDB] = "apis/policy/v1beta1"
+	kindVersionMap[PDB] = "apis/policy/v1"
 	kindGroupMap[PDB] = "policy"
 	compositionMap[PDB] = []string{}
+	betaVersionMap[PDB] = "v1beta1"
+	pdbRelationships := make([]RelationshipRule,0)
+	pdbRel := "label, on:Pod, value:INSTANCE.spec.selector"
+	pdbRelationships = append(pdbRelationships, mustParseRelationshipRule(pdbRel))
+	relationshipMap[PDB] = pdbRelationships
+
+	KindPluralMap[HPA] = "horizontalpodautoscalers"
+	kindVersionMap[HPA] = "apis/autoscaling/v1"
+	kindGroupMap[HPA] = "autoscaling"
+	compositionMap[HPA] = []string{}
+	hpaRelationships := make([]RelationshipRule,0)
+	hpaRel := "objectref, on:INSTANCE.spec.scaleTargetRef, value:Deployment; StatefulSet; ReplicaSet; ReplicationController"
+	hpaRelationships = append(hpaRelationships, mustParseRelationshipRule(hpaRel))
+	relationshipMap[HPA] = hpaRelationships
+
+	KindPluralMap[VPA] = "verticalpodautoscalers"
+	kindVersionMap[VPA] = "apis/autoscaling.k8s.io/v1"
+	kindGroupMap[VPA] = "autoscaling.k8s.io"
+	compositionMap[VPA] = []string{}
+	vpaRelationships := make([]RelationshipRule,0)
+	vpaRel := "objectref, on:INSTANCE.spec.targetRef, value:Deployment; StatefulSet; DaemonSet; ReplicaSet; ReplicationController; Job; CronJob"
+	vpaRelationships = append(vpaRelationships, mustParseRelationshipRule(vpaRel))
+	relationshipMap[VPA] = vpaRelationships
 
 	KindPluralMap[POD] = "pods"
 	kindVersionMap[POD] = "api/v1"
 	kindGroupMap[POD] = ""
 	compositionMap[POD] = []string{}
 
-	podRelationships := make([]string,0)
+	podRelationships := make([]RelationshipRule,0)
 	podRel0 := "specproperty, on:INSTANCE.spec.env, value:Service.spec.metadata.name"
-	podRel1 := "specproperty, on:INSTANCE.spec.volumes.persistentVolumeClaim.claimName, value:PersistentVolumeClaim.spec.metadata.name"
+	podRel1 := "volume, on:INSTANCE.spec.volumes, value:PersistentVolumeClaim.metadata.name"
 	podRel2 := "specproperty, on:INSTANCE.spec.serviceAccountName, value:ServiceAccount.metadata.name"
 	podRel3 := "specproperty, on:INSTANCE.metadata.namespace, value:Namespace.metadata.name"	
-	podRelationships = append(podRelationships, podRel0)
-	podRelationships = append(podRelationships, podRel1)
-	podRelationships = append(podRelationships, podRel2)
-	podRelationships = append(podRelationships, podRel3)
+	podRelationships = append(podRelationships, mustParseRelationshipRule(podRel0))
+	podRelationships = append(podRelationships, mustParseRelationshipRule(podRel1))
+	podRelationships = append(podRelationships, mustParseRelationshipRule(podRel2))
+	podRelationships = append(podRelationships, mustParseRelationshipRule(podRel3))
+	podRel4 := "specproperty, on:INSTANCE.spec.env, value:ConfigMap.metadata.name"
+	podRel5 := "specproperty, on:INSTANCE.spec.env, value:Secret.metadata.name"
+	podRelationships = append(podRelationships, mustParseRelationshipRule(podRel4))
+	podRelationships = append(podRelationships, mustParseRelationshipRule(podRel5))
+	podRel6 := "volume, on:INSTANCE.spec.volumes, value:ConfigMap.metadata.name"
+	podRel7 := "volume, on:INSTANCE.spec.volumes, value:Secret.metadata.name"
+	podRelationships = append(podRelationships, mustParseRelationshipRule(podRel6))
+	podRelationships = append(podRelationships, mustParseRelationshipRule(podRel7))
+	// Where and how the pod runs
+	podRel8 := "specproperty, on:INSTANCE.spec.nodeName, value:Node.metadata.name"
+	podRel9 := "specproperty, on:INSTANCE.spec.priorityClassName, value:PriorityClass.metadata.name"
+	podRel10 := "specproperty, on:INSTANCE.spec.runtimeClassName, value:RuntimeClass.metadata.name"
+	podRel11 := "specproperty, on:INSTANCE.spec.imagePullSecrets.name, value:Secret.metadata.name"
+	podRelationships = append(podRelationships, mustParseRelationshipRule(podRel8))
+	podRelationships = append(podRelationships, mustParseRelationshipRule(podRel9))
+	podRelationships = append(podRelationships, mustParseRelationshipRule(podRel10))
+	podRelationships = append(podRelationships, mustParseRelationshipRule(podRel11))
 	relationshipMap[POD] = podRelationships
 
+	KindPluralMap[NODE] = "nodes"
+	kindVersionMap[NODE] = "api/v1"
+	kindGroupMap[NODE] = ""
+	compositionMap[NODE] = []string{}
+	clusterScopedKinds[NODE] = true
+
+	KindPluralMap[PRIORITY_CLASS] = "priorityclasses"
+	kindVersionMap[PRIORITY_CLASS] = "apis/scheduling.k8s.io/v1"
+	kindGroupMap[PRIORITY_CLASS] = "scheduling.k8s.io"
+	compositionMap[PRIORITY_CLASS] = []string{}
+	clusterScopedKinds[PRIORITY_CLASS] = true
+
+	KindPluralMap[RUNTIME_CLASS] = "runtimeclasses"
+	kindVersionMap[RUNTIME_CLASS] = "apis/node.k8s.io/v1"
+	kindGroupMap[RUNTIME_CLASS] = "node.k8s.io"
+	compositionMap[RUNTIME_CLASS] = []string{}
+	clusterScopedKinds[RUNTIME_CLASS] = true
+	betaVersionMap[RUNTIME_CLASS] = "v1beta1"
+
+	// Control plane wiring: the Services that back admission webhooks,
+	// aggregated APIs and CRD conversion webhooks
+	webhookRel := "objectref, on:INSTANCE.webhooks.clientConfig.service, value:Service"
+
+	KindPluralMap[VALIDATING_WEBHOOK_CONFIGURATION] = "validatingwebhookconfigurations"
+	kindVersionMap[VALIDATING_WEBHOOK_CONFIGURATION] = "apis/admissionregistration.k8s.io/v1"
+	kindGroupMap[VALIDATING_WEBHOOK_CONFIGURATION] = "admissionregistration.k8s.io"
+	compositionMap[VALIDATING_WEBHOOK_CONFIGURATION] = []string{}
+	clusterScopedKinds[VALIDATING_WEBHOOK_CONFIGURATION] = true
+	betaVersionMap[VALIDATING_WEBHOOK_CONFIGURATION] = "v1beta1"
+	validatingWebhookRelationships := make([]RelationshipRule,0)
+	validatingWebhookRelationships = append(validatingWebhookRelationships, mustParseRelationshipRule(webhookRel))
+	relationshipMap[VALIDATING_WEBHOOK_CONFIGURATION] = validatingWebhookRelationships
+
+	KindPluralMap[MUTATING_WEBHOOK_CONFIGURATION] = "mutatingwebhookconfigurations"
+	kindVersionMap[MUTATING_WEBHOOK_CONFIGURATION] = "apis/admissionregistration.k8s.io/v1"
+	kindGroupMap[MUTATING_WEBHOOK_CONFIGURATION] = "admissionregistration.k8s.io"
+	compositionMap[MUTATING_WEBHOOK_CONFIGURATION] = []string{}
+	clusterScopedKinds[MUTATING_WEBHOOK_CONFIGURATION] = true
+	betaVersionMap[MUTATING_WEBHOOK_CONFIGURATION] = "v1beta1"
+	mutatingWebhookRelationships := make([]RelationshipRule,0)
+	mutatingWebhookRelationships = append(mutatingWebhookRelationships, mustParseRelationshipRule(webhookRel))
+	relationshipMap[MUTATING_WEBHOOK_CONFIGURATION] = mutatingWebhookRelationships
+
+	KindPluralMap[API_SERVICE] = "apiservices"
+	kindVersionMap[API_SERVICE] = "apis/apiregistration.k8s.io/v1"
+	kindGroupMap[API_SERVICE] = "apiregistration.k8s.io"
+	compositionMap[API_SERVICE] = []string{}
+	clusterScopedKinds[API_SERVICE] = true
+	apiServiceRelationships := make([]RelationshipRule,0)
+	apiServiceRel := "objectref, on:INSTANCE.spec.service, value:Service"
+	apiServiceRelationships = append(apiServiceRelationships, mustParseRelationshipRule(apiServiceRel))
+	relationshipMap[API_SERVICE] = apiServiceRelationships
+
+	KindPluralMap[CRD] = "customresourcedefinitions"
+	kindVersionMap[CRD] = "apis/apiextensions.k8s.io/v1"
+	kindGroupMap[CRD] = "apiextensions.k8s.io"
+	compositionMap[CRD] = []string{}
+	clusterScopedKinds[CRD] = true
+	betaVersionMap[CRD] = "v1beta1"
+	crdRelationships := make([]RelationshipRule,0)
+	crdRel := "objectref, on:INSTANCE.spec.conversion.webhook.clientConfig.service, value:Service"
+	// apiextensions.k8s.io/v1beta1
+	crdRel1 := "objectref, on:INSTANCE.spec.conversion.webhookClientConfig.service, value:Service"
+	crdRelationships = append(crdRelationships, mustParseRelationshipRule(crdRel))
+	crdRelationships = append(crdRelationships, mustParseRelationshipRule(crdRel1))
+	relationshipMap[CRD] = crdRelationships
+
+	KindPluralMap[JOB] = "jobs"
+	kindVersionMap[JOB] = "apis/batch/v1"
+	kindGroupMap[JOB] = "batch"
+	compositionMap[JOB] = []string{"Pod"}
+	jobRelationships := make([]RelationshipRule,0)
+	jobRel := "owner reference, of:Pod, value:INSTANCE.name"
+	jobRelationships = append(jobRelationships, mustParseRelationshipRule(jobRel))
+	relationshipMap[JOB] = jobRelationships
+
+	KindPluralMap[CRONJOB] = "cronjobs"
+	kindVersionMap[CRONJOB] = "apis/batch/v1"
+	kindGroupMap[CRONJOB] = "batch"
+	compositionMap[CRONJOB] = []string{"Job"}
+	betaVersionMap[CRONJOB] = "v1beta1"
+	cronJobRelationships := make([]RelationshipRule,0)
+	cronJobRel := "owner reference, of:Job, value:INSTANCE.name"
+	cronJobRelationships = append(cronJobRelationships, mustParseRelationshipRule(cronJobRel))
+	relationshipMap[CRONJOB] = cronJobRelationships
+
 	KindPluralMap[SERVICE_ACCOUNT] = "serviceaccounts"
 	kindVersionMap[SERVICE_ACCOUNT] = "api/v1"
 	kindGroupMap[SERVICE_ACCOUNT] = ""
 	compositionMap[SERVICE_ACCOUNT] = []string{}
+	serviceAccountRelationships := make([]RelationshipRule,0)
+	serviceAccountRel := "specproperty, on:INSTANCE.secrets.name, value:Secret.metadata.name"
+	serviceAccountRel1 := "specproperty, on:INSTANCE.imagePullSecrets.name, value:Secret.metadata.name"
+	serviceAccountRelationships = append(serviceAccountRelationships, mustParseRelationshipRule(serviceAccountRel))
+	serviceAccountRelationships = append(serviceAccountRelationships, mustParseRelationshipRule(serviceAccountRel1))
+	relationshipMap[SERVICE_ACCOUNT] = serviceAccountRelationships
 
 	KindPluralMap[NAMESPACE] = "namespaces"
 	kindVersionMap[NAMESPACE] = "v1"
 	kindGroupMap[NAMESPACE] = ""
 	compositionMap[NAMESPACE] = []string{}
+	clusterScopedKinds[NAMESPACE] = true
+
+	KindPluralMap[NETWORK_POLICY] = "networkpolicies"
+	kindVersionMap[NETWORK_POLICY] = "apis/networking.k8s.io/v1"
+	kindGroupMap[NETWORK_POLICY] = "networking.k8s.io"
+	compositionMap[NETWORK_POLICY] = []string{}
+	networkPolicyRelationships := make([]RelationshipRule,0)
+	// podSelector, ingress.from and egress.to peers
+	networkPolicyRel := "networkpolicy, on:Pod; Namespace"
+	networkPolicyRelationships = append(networkPolicyRelationships, mustParseRelationshipRule(networkPolicyRel))
+	relationshipMap[NETWORK_POLICY] = networkPolicyRelationships
+
+	KindPluralMap[ROLE] = "roles"
+	kindVersionMap[ROLE] = "apis/rbac.authorization.k8s.io/v1"
+	kindGroupMap[ROLE] = "rbac.authorization.k8s.io"
+	compositionMap[ROLE] = []string{}
+
+	KindPluralMap[CLUSTER_ROLE] = "clusterroles"
+	kindVersionMap[CLUSTER_ROLE] = "apis/rbac.authorization.k8s.io/v1"
+	kindGroupMap[CLUSTER_ROLE] = "rbac.authorization.k8s.io"
+	compositionMap[CLUSTER_ROLE] = []string{}
+	clusterScopedKinds[CLUSTER_ROLE] = true
+
+	// subjects and roleRef of bindings
+	bindingRel := "rbac, on:ServiceAccount; User; Group; Role; ClusterRole"
+
+	KindPluralMap[ROLE_BINDING] = "rolebindings"
+	kindVersionMap[ROLE_BINDING] = "apis/rbac.authorization.k8s.io/v1"
+	kindGroupMap[ROLE_BINDING] = "rbac.authorization.k8s.io"
+	compositionMap[ROLE_BINDING] = []string{}
+	roleBindingRelationships := make([]RelationshipRule,0)
+	roleBindingRelationships = append(roleBindingRelationships, mustParseRelationshipRule(bindingRel))
+	relationshipMap[ROLE_BINDING] = roleBindingRelationships
+
+	KindPluralMap[CLUSTER_ROLE_BINDING] = "clusterrolebindings"
+	kindVersionMap[CLUSTER_ROLE_BINDING] = "apis/rbac.authorization.k8s.io/v1"
+	kindGroupMap[CLUSTER_ROLE_BINDING] = "rbac.authorization.k8s.io"
+	compositionMap[CLUSTER_ROLE_BINDING] = []string{}
+	clusterScopedKinds[CLUSTER_ROLE_BINDING] = true
+	clusterRoleBindingRelationships := make([]RelationshipRule,0)
+	clusterRoleBindingRelationships = append(clusterRoleBindingRelationships, mustParseRelationshipRule(bindingRel))
+	relationshipMap[CLUSTER_ROLE_BINDING] = clusterRoleBindingRelationships
 
 	KindPluralMap[SERVICE] = "services"
 	kindVersionMap[SERVICE] = "api/v1"
 	kindGroupMap[SERVICE] = ""
 	compositionMap[SERVICE] = []string{}
-	serviceRelationships := make([]string,0)
+	serviceRelationships := make([]RelationshipRule,0)
 	serviceRel := "label, on:Pod, value:INSTANCE.spec.selector"
-	serviceRelationships = append(serviceRelationships, serviceRel)
+	serviceRelationships = append(serviceRelationships, mustParseRelationshipRule(serviceRel))
 	relationshipMap[SERVICE] = serviceRelationships
 
+	KindPluralMap[ENDPOINTS] = "endpoints"
+	kindVersionMap[ENDPOINTS] = "api/v1"
+	kindGroupMap[ENDPOINTS] = ""
+	compositionMap[ENDPOINTS] = []string{}
+
+	KindPluralMap[ENDPOINT_SLICE] = "endpointslices"
+	kindVersionMap[ENDPOINT_SLICE] = "apis/discovery.k8s.io/v1"
+	kindGroupMap[ENDPOINT_SLICE] = "discovery.k8s.io"
+	compositionMap[ENDPOINT_SLICE] = []string{}
+	betaVersionMap[ENDPOINT_SLICE] = "v1beta1"
+
 	KindPluralMap[INGRESS] = "ingresses"
-	kindVersionMap[INGRESS] = "networking.k8s.io/v1beta1"//"extensions/v1beta1"
+	// Changed to networking.k8s.io/v1beta1 by resolveAPIVersions on clusters without v1
+	kindVersionMap[INGRESS] = "apis/networking.k8s.io/v1"//"extensions/v1beta1"
 	kindGroupMap[INGRESS] = "networking.k8s.io"
 	compositionMap[INGRESS] = []string{}
-	ingressRelationships := make([]string,0)
-	ingressRel := "specproperty, on:INSTANCE.spec.rules.http.paths.backend.serviceName, value:Service.spec.metadata.name"
-	ingressRelationships = append(ingressRelationships, ingressRel)
+	betaVersionMap[INGRESS] = "v1beta1"
+	ingressRelationships := make([]RelationshipRule,0)
+	// networking.k8s.io/v1 backends
+	ingressRel := "specproperty, on:INSTANCE.spec.rules.http.paths.backend.service.name, value:Service.metadata.name"
+	ingressRel1 := "specproperty, on:INSTANCE.spec.defaultBackend.service.name, value:Service.metadata.name"
+	// networking.k8s.io/v1beta1 backends
+	ingressRel2 := "specproperty, on:INSTANCE.spec.rules.http.paths.backend.serviceName, value:Service.metadata.name"
+	ingressRel3 := "specproperty, on:INSTANCE.spec.backend.serviceName, value:Service.metadata.name"
+	// Resource backends refer to objects of any kind
+	ingressRel4 := "objectref, on:INSTANCE.spec.rules.http.paths.backend.resource, value:*"
+	ingressRel5 := "objectref, on:INSTANCE.spec.defaultBackend.resource, value:*"
+	ingressRel6 := "objectref, on:INSTANCE.spec.backend.resource, value:*"
+	ingressRel7 := "specproperty, on:INSTANCE.spec.tls.secretName, value:Secret.metadata.name"
+	ingressRel8 := "specproperty, on:INSTANCE.spec.ingressClassName, value:IngressClass.metadata.name"
+	ingressRelationships = append(ingressRelationships, mustParseRelationshipRule(ingressRel))
+	ingressRelationships = append(ingressRelationships, mustParseRelationshipRule(ingressRel1))
+	ingressRelationships = append(ingressRelationships, mustParseRelationshipRule(ingressRel2))
+	ingressRelationships = append(ingressRelationships, mustParseRelationshipRule(ingressRel3))
+	ingressRelationships = append(ingressRelationships, mustParseRelationshipRule(ingressRel4))
+	ingressRelationships = append(ingressRelationships, mustParseRelationshipRule(ingressRel5))
+	ingressRelationships = append(ingressRelationships, mustParseRelationshipRule(ingressRel6))
+	ingressRelationships = append(ingressRelationships, mustParseRelationshipRule(ingressRel7))
+	ingressRelationships = append(ingressRelationships, mustParseRelationshipRule(ingressRel8))
 	relationshipMap[INGRESS] = ingressRelationships
 
+	KindPluralMap[INGRESS_CLASS] = "ingressclasses"
+	kindVersionMap[INGRESS_CLASS] = "apis/networking.k8s.io/v1"
+	kindGroupMap[INGRESS_CLASS] = "networking.k8s.io"
+	compositionMap[INGRESS_CLASS] = []string{}
+	clusterScopedKinds[INGRESS_CLASS] = true
+	ingressClassRelationships := make([]RelationshipRule,0)
+	ingressClassRel := "objectref, on:INSTANCE.spec.parameters, value:*"
+	ingressClassRelationships = append(ingressClassRelationships, mustParseRelationshipRule(ingressClassRel))
+	relationshipMap[INGRESS_CLASS] = ingressClassRelationships
+
 	KindPluralMap[SECRET] = "secrets"
 	kindVersionMap[SECRET] = "v1"
 	kindGroupMap[SECRET] = ""
@@ -290,15 +701,68 @@ func init() {
 	kindVersionMap[PVCLAIM] = "api/v1"
 	kindGroupMap[PVCLAIM] = ""
 	compositionMap[PVCLAIM] = []string{}
-	pvcRelationships := make([]string,0)
+	pvcRelationships := make([]RelationshipRule,0)
 	pvcRel := "specproperty, on:INSTANCE.spec.volumeName, value:PersistentVolume.metadata.name"
-	pvcRelationships = append(pvcRelationships, pvcRel)
+	pvcRelationships = append(pvcRelationships, mustParseRelationshipRule(pvcRel))
+	pvcRel1 := "specproperty, on:INSTANCE.spec.storageClassName, value:StorageClass.metadata.name"
+	pvcRelationships = append(pvcRelationships, mustParseRelationshipRule(pvcRel1))
+	// PVCs restored from a snapshot or cloned from another PVC
+	pvcRel2 := "objectref, on:INSTANCE.spec.dataSource, value:VolumeSnapshot; PersistentVolumeClaim"
+	pvcRelationships = append(pvcRelationships, mustParseRelationshipRule(pvcRel2))
 	relationshipMap[PVCLAIM] = pvcRelationships
 
 	KindPluralMap[PV] = "persistentvolumes"
 	kindVersionMap[PV] = "api/v1"
 	kindGroupMap[PV] = ""
 	compositionMap[PV] = []string{}
+	clusterScopedKinds[PV] = true
+	pvRelationships := make([]RelationshipRule,0)
+	pvRel := "specproperty, on:INSTANCE.spec.storageClassName, value:StorageClass.metadata.name"
+	pvRelationships = append(pvRelationships, mustParseRelationshipRule(pvRel))
+	pvRel1 := "specproperty, on:INSTANCE.spec.csi.driver, value:CSIDriver.metadata.name"
+	pvRelationships = append(pvRelationships, mustParseRelationshipRule(pvRel1))
+	relationshipMap[PV] = pvRelationships
+
+	KindPluralMap[STORAGE_CLASS] = "storageclasses"
+	kindVersionMap[STORAGE_CLASS] = "apis/storage.k8s.io/v1"
+	kindGroupMap[STORAGE_CLASS] = "storage.k8s.io"
+	compositionMap[STORAGE_CLASS] = []string{}
+	clusterScopedKinds[STORAGE_CLASS] = true
+	storageClassRelationships := make([]RelationshipRule,0)
+	// The provisioner of a CSI StorageClass is the name of the CSIDriver
+	storageClassRel := "specproperty, on:INSTANCE.provisioner, value:CSIDriver.metadata.name"
+	storageClassRelationships = append(storageClassRelationships, mustParseRelationshipRule(storageClassRel))
+	relationshipMap[STORAGE_CLASS] = storageClassRelationships
+
+	KindPluralMap[CSI_DRIVER] = "csidrivers"
+	kindVersionMap[CSI_DRIVER] = "apis/storage.k8s.io/v1"
+	kindGroupMap[CSI_DRIVER] = "storage.k8s.io"
+	compositionMap[CSI_DRIVER] = []string{}
+	clusterScopedKinds[CSI_DRIVER] = true
+	betaVersionMap[CSI_DRIVER] = "v1beta1"
+
+	KindPluralMap[VOLUME_SNAPSHOT] = "volumesnapshots"
+	kindVersionMap[VOLUME_SNAPSHOT] = "apis/snapshot.storage.k8s.io/v1"
+	kindGroupMap[VOLUME_SNAPSHOT] = "snapshot.storage.k8s.io"
+	compositionMap[VOLUME_SNAPSHOT] = []string{}
+	betaVersionMap[VOLUME_SNAPSHOT] = "v1beta1"
+	volumeSnapshotRelationships := make([]RelationshipRule,0)
+	volumeSnapshotRel := "specproperty, on:INSTANCE.spec.source.persistentVolumeClaimName, value:PersistentVolumeClaim.metadata.name"
+	volumeSnapshotRelationships = append(volumeSnapshotRelationships, mustParseRelationshipRule(volumeSnapshotRel))
+	volumeSnapshotRel1 := "specproperty, on:INSTANCE.spec.volumeSnapshotClassName, value:VolumeSnapshotClass.metadata.name"
+	volumeSnapshotRelationships = append(volumeSnapshotRelationships, mustParseRelationshipRule(volumeSnapshotRel1))
+	relationshipMap[VOLUME_SNAPSHOT] = volumeSnapshotRelationships
+
+	KindPluralMap[VOLUME_SNAPSHOT_CLASS] = "volumesnapshotclasses"
+	kindVersionMap[VOLUME_SNAPSHOT_CLASS] = "apis/snapshot.storage.k8s.io/v1"
+	kindGroupMap[VOLUME_SNAPSHOT_CLASS] = "snapshot.storage.k8s.io"
+	compositionMap[VOLUME_SNAPSHOT_CLASS] = []string{}
+	clusterScopedKinds[VOLUME_SNAPSHOT_CLASS] = true
+	betaVersionMap[VOLUME_SNAPSHOT_CLASS] = "v1beta1"
+	volumeSnapshotClassRelationships := make([]RelationshipRule,0)
+	volumeSnapshotClassRel := "specproperty, on:INSTANCE.driver, value:CSIDriver.metadata.name"
+	volumeSnapshotClassRelationships = append(volumeSnapshotClassRelationships, mustParseRelationshipRule(volumeSnapshotClassRel))
+	relationshipMap[VOLUME_SNAPSHOT_CLASS] = volumeSnapshotClassRelationships
 
 /*
 	KindPluralMap[INGRESS] = "ingresses"
@@ -310,12 +774,17 @@ func init() {
 	KindPluralMap[STATEFULSET] = "statefulsets"
 	kindVersionMap[STATEFULSET] = "apis/apps/v1"
 	kindGroupMap[STATEFULSET] = "apps"
-	compositionMap[STATEFULSET] = []string{"Pod", "ReplicaSet"}
-	ssetRelationships := make([]string,0)
-	ssRel1 := "owner reference, of:ReplicaSet, value:INSTANCE.name"
-	ssetRelationships = append(ssetRelationships, ssRel1)
+	// PVCs are owned by the StatefulSet only with a persistentVolumeClaimRetentionPolicy;
+	// the others are added to the composition by the names of the volumeClaimTemplates.
+	compositionMap[STATEFULSET] = []string{"Pod", "ControllerRevision", "PersistentVolumeClaim"}
+	ssetRelationships := make([]RelationshipRule,0)
+	ssRel1 := "owner reference, of:ControllerRevision, value:INSTANCE.name"
+	ssetRelationships = append(ssetRelationships, mustParseRelationshipRule(ssRel1))
 	ssRel2 := "owner reference, of:Pod, value:INSTANCE.name"
-	ssetRelationships = append(ssetRelationships, ssRel2)	
+	ssetRelationships = append(ssetRelationships, mustParseRelationshipRule(ssRel2))	
+	// PVCs created from volumeClaimTemplates are not owned by the StatefulSet
+	ssRel3 := "specproperty, on:INSTANCE.spec.volumeClaimTemplates.metadata.name, value:PersistentVolumeClaim.metadata.name, match:claimtemplate"
+	ssetRelationships = append(ssetRelationships, mustParseRelationshipRule(ssRel3))
 	relationshipMap[STATEFULSET] = ssetRelationships
 
 	KindPluralMap[CONFIG_MAP] = "configmaps"
@@ -323,14 +792,37 @@ func init() {
 	kindGroupMap[CONFIG_MAP] = ""
 	compositionMap[CONFIG_MAP] = []string{}
 
+	// Env variables of the pod templates of workloads (spec.template.spec,
+	// spec.jobTemplate.spec.template.spec for CronJobs)
+	workloadEnvRel0 := "specproperty, on:INSTANCE.spec.template.spec.env, value:Service.metadata.name"
+	workloadEnvRel1 := "specproperty, on:INSTANCE.spec.template.spec.env, value:ConfigMap.metadata.name"
+	workloadEnvRel2 := "specproperty, on:INSTANCE.spec.template.spec.env, value:Secret.metadata.name"
+	// Volumes of the pod templates of workloads
+	workloadVolumeRel0 := "volume, on:INSTANCE.spec.template.spec.volumes, value:ConfigMap.metadata.name"
+	workloadVolumeRel1 := "volume, on:INSTANCE.spec.template.spec.volumes, value:Secret.metadata.name"
+	workloadVolumeRel2 := "volume, on:INSTANCE.spec.template.spec.volumes, value:PersistentVolumeClaim.metadata.name"
+	for _, workloadKind := range []string{DEPLOYMENT, STATEFULSET, DAEMONSET, JOB, CRONJOB} {
+		relationshipMap[workloadKind] = append(relationshipMap[workloadKind],
+			mustParseRelationshipRule(workloadEnvRel0),
+			mustParseRelationshipRule(workloadEnvRel1),
+			mustParseRelationshipRule(workloadEnvRel2),
+			mustParseRelationshipRule(workloadVolumeRel0),
+			mustParseRelationshipRule(workloadVolumeRel1),
+			mustParseRelationshipRule(workloadVolumeRel2))
+	}
+
 	USAGE_ANNOTATION = "resource/usage"
 	COMPOSITION_ANNOTATION = "resource/composition"
 	ANNOTATION_REL_ANNOTATION = "resource/annotation-relationship"
 	LABEL_REL_ANNOTATION = "resource/label-relationship"
 	SPECPROPERTY_REL_ANNOTATION = "resource/specproperty-relationship"
+	OBJECTREF_REL_ANNOTATION = "resource/objectref-relationship"
+	MANAGED_BY_ANNOTATION = "resource/managed-by"
 }
 
 func getKindAPIDetails(kind string) (string, string, string, string) {
+	kindMapsMux.RLock()
+	defer kindMapsMux.RUnlock()
 	kindplural := KindPluralMap[kind]
 	kindResourceApiVersion := kindVersionMap[kind]
 	kindResourceGroup := kindGroupMap[kind]
@@ -339,4 +831,23 @@ func getKindAPIDetails(kind string) (string, string, string, string) {
 	kindAPI := parts[len(parts)-1]
 
 	return kindplural, kindResourceApiVersion, kindAPI, kindResourceGroup
-}
\ No newline at end of file
+}
+
+func isKnownKind(kind string) bool {
+	kindMapsMux.RLock()
+	defer kindMapsMux.RUnlock()
+	_, ok := KindPluralMap[kind]
+	return ok
+}
+
+// The endpoint is api/<version> or apis/<group>/<version>
+func setKindAPIDetails(kind, plural, endpoint, group string, clusterScoped bool) {
+	kindMapsMux.Lock()
+	defer kindMapsMux.Unlock()
+	KindPluralMap[kind] = plural
+	kindVersionMap[kind] = endpoint
+	kindGroupMap[kind] = group
+	if clusterScoped {
+		clusterScopedKinds[kind] = true
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"log"
 	"strconv"
 	"sort"
+	"strings"
 	"sync"
 	"path/filepath"
 	"github.com/coreos/etcd/client"
@@ -80,10 +81,42 @@ func getDynamicClient() (dynamic.Interface, error) {
 	}
 	if dynamicClient == nil {
 		dynamicClient, err = dynamic.NewForConfig(cfg)
+		resolveAPIVersions()
 	}
 	return dynamicClient, err
 }
 
+// Kinds that older clusters serve only with a beta version: Ingress is
+// networking.k8s.io/v1 from Kubernetes 1.19, EndpointSlice discovery.k8s.io/v1 and
+// PodDisruptionBudget policy/v1 from 1.21.
+func resolveAPIVersions() {
+	if cfg == nil {
+		return
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return
+	}
+	for kind, betaVersion := range betaVersionMap {
+		_, apiVersion, version, group := getKindAPIDetails(kind)
+		resourceList, err := clientset.Discovery().ServerResourcesForGroupVersion(group + "/" + version)
+		found := false
+		if err == nil {
+			for _, resource := range resourceList.APIResources {
+				if resource.Kind == kind {
+					found = true
+				}
+			}
+		}
+		if !found {
+			//fmt.Printf("%s %s not found, using %s\n", kind, apiVersion, betaVersion)
+			kindMapsMux.Lock()
+			kindVersionMap[kind] = strings.TrimSuffix(apiVersion, version) + betaVersion
+			kindMapsMux.Unlock()
+		}
+	}
+}
+
 func FetchGVKs(namespace string) {
 	kindPrefetchList := [...]string{"Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "Service", "ServiceAccount", "Pod", "PersistentVolume", "PersistentVolumeClaim","Secret"}
 	for _, k := range kindPrefetchList {
@@ -184,6 +217,46 @@ func getKubeObject(kind, instance, namespace string, gvk schema.GroupVersionReso
 	return obj, nil
 }
 
+// Registers API details of a Kind that is neither built-in nor known from CRD
+// annotations (e.g. the Kind of an owner reference) using the discovery API.
+func discoverKind(kind, apiVersion string) bool {
+	if isKnownKind(kind) {
+		return true
+	}
+	if cfg == nil {
+		return false
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return false
+	}
+	resourceList, err := clientset.Discovery().ServerResourcesForGroupVersion(apiVersion)
+	if err != nil {
+		return false
+	}
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil {
+		return false
+	}
+	for _, resource := range resourceList.APIResources {
+		// Skip subresources such as deployments/scale
+		if resource.Kind != kind || strings.Contains(resource.Name, "/") {
+			continue
+		}
+		setKindAPIDetails(kind, resource.Name, getAPIEndpoint(gv), gv.Group, !resource.Namespaced)
+		return true
+	}
+	return false
+}
+
+// API path prefix of a group version, as in kindVersionMap
+func getAPIEndpoint(gv schema.GroupVersion) string {
+	if gv.Group == "" {
+		return "api/" + gv.Version
+	}
+	return "apis/" + gv.Group + "/" + gv.Version
+}
+
 func checkGVK(lhs, rhs schema.GroupVersionResource) bool {
 	if lhs.Group == rhs.Group && lhs.Version == rhs.Version && lhs.Resource == rhs.Resource {
 		return true
@@ -194,39 +267,12 @@ func checkGVK(lhs, rhs schema.GroupVersionResource) bool {
 
 // Composition utility functions
 
-func getComposition1(kind, name, status string, compositionTree *[]CompositionTreeNode) Composition {
-	var compositionString string
+func getComposition1(kind, name, uid, status string, compositionTree *[]CompositionTreeNode) Composition {
 	fmt.Printf("Kind: %s Name: %s Composition:\n", kind, name)
-	compositionString = "Kind: " + kind + " Name:" + name + " Composition:\n"
-	parentComposition := Composition{}
-	parentComposition.Level = 0
-	parentComposition.Kind = kind
-	parentComposition.Name = name
-	parentComposition.Status = status
-	parentComposition.Children = []Composition{}
-	var root = parentComposition
-	for _, compositionTreeNode := range *compositionTree {
-		level := compositionTreeNode.Level
-		childKind := compositionTreeNode.ChildKind
-		metaDataAndOwnerReferences := compositionTreeNode.Children
-		//childComposition.Children = []Composition{}
-		var childrenList = []Composition{}
-		for _, metaDataNode := range metaDataAndOwnerReferences {
-			childComposition := Composition{}
-			childName := metaDataNode.MetaDataName
-			childStatus := metaDataNode.Status
-			fmt.Printf("  %d %s %s\n", level, childKind, childName)
-			compositionString = compositionString + " " + string(level) + " " + childKind + " " + childName + "\n"
-			childComposition.Level = level
-			childComposition.Kind = childKind
-			childComposition.Name = childName
-			childComposition.Status = childStatus
-			childrenList = append(childrenList, childComposition)
-		}
-		root.Children = childrenList
-		fmt.Printf("Root composition:%v\n", root)
-		root = root.Children[0]
-	}
+	childrenIndex := indexCompositionTree(compositionTree)
+	parentComposition := getComposition(kind, name, "", uid, status, Health{}, []OwnerReference{}, 0,
+										childrenIndex, map[string]bool{})
+	fmt.Printf("Root composition:%v\n", parentComposition)
 	return parentComposition
 }
 
@@ -265,7 +311,7 @@ func storeCompositions_etcd(resourceKind string, resourceName string, compositio
 		log.Fatal(err)
 	} else {
 		// print common key info
-		log.Printf("Set is done. Metadata is %q\n", resp)
+		log.Printf("Set is done. Metadata is %v\n", resp)
 	}
 	//fmt.Printf("Getting value for %s\n", resourceKey)
 	resp, err = kapi.Get(context.Background(), resourceKey, nil)
@@ -289,21 +335,31 @@ func (cp *ClusterCompositions) PrintCompositions() {
 		name := compositionItem.Name
 		compositionTree := compositionItem.CompositionTree
 		fmt.Printf("Kind: %s Name: %s Composition:\n", kind, name)
-		for _, compositionTreeNode := range *compositionTree {
-			level := compositionTreeNode.Level
-			childKind := compositionTreeNode.ChildKind
-			metaDataAndOwnerReferences := compositionTreeNode.Children
-			for _, metaDataNode := range metaDataAndOwnerReferences {
-				childName := metaDataNode.MetaDataName
-				childStatus := metaDataNode.Status
-				fmt.Printf("  %d %s %s %s\n", level, childKind, childName, childStatus)
-			}
-		}
+		childrenIndex := indexCompositionTree(compositionTree)
+		printCompositionChildren(childrenIndex, compositionItem.UID, map[string]bool{})
 		fmt.Println("============================================")
 	}
 }
 
+func printCompositionChildren(childrenIndex map[string][]CompositionTreeNode, parentUID string, visited map[string]bool) {
+	if visited[parentUID] {
+		return
+	}
+	visited[parentUID] = true
+	for _, compositionTreeNode := range childrenIndex[parentUID] {
+		level := compositionTreeNode.Level
+		childKind := compositionTreeNode.ChildKind
+		for _, metaDataNode := range compositionTreeNode.Children {
+			childName := metaDataNode.MetaDataName
+			childStatus := metaDataNode.Status
+			fmt.Printf("%s%d %s %s %s\n", strings.Repeat("  ", level-1), level, childKind, childName, childStatus)
+			printCompositionChildren(childrenIndex, metaDataNode.UID, visited)
+		}
+	}
+}
+
 func printMaps() {
+	kindMapsMux.RLock()
 	fmt.Println("Printing kindVersionMap")
 	for key, value := range kindVersionMap {
 		fmt.Printf("%s, %s\n", key, value)
@@ -312,13 +368,23 @@ func printMaps() {
 	for key, value := range KindPluralMap {
 		fmt.Printf("%s, %s\n", key, value)
 	}
+	kindMapsMux.RUnlock()
 	fmt.Println("Printing compositionMap")
+	compositionMapMux.RLock()
+	defer compositionMapMux.RUnlock()
 	for key, value := range compositionMap {
 		fmt.Printf("%s, %s\n", key, value)
 	}
 }
 
 // Connection utility functions
+// IsClusterScoped tells whether instances of the kind are not namespaced.
+func IsClusterScoped(kind string) bool {
+	kindMapsMux.RLock()
+	defer kindMapsMux.RUnlock()
+	return clusterScopedKinds[kind]
+}
+
 func CheckExistence(kind, instance, namespace string) bool {
 	if instance == "" {
 		return false
@@ -346,12 +412,13 @@ func CheckExistence(kind, instance, namespace string) bool {
 
 func findRelatedKinds(kind string) []string{
 	relatedKinds := make([]string, 0)
-	for key, relStringList := range relationshipMap {
-		for _, relString := range relStringList {
-			_, _, _, targetKindList := parseRelationship(relString)
-			for _, targetKind := range targetKindList {
+	relationshipMapMux.RLock()
+	defer relationshipMapMux.RUnlock()
+	for key, relRules := range relationshipMap {
+		for _, relRule := range relRules {
+			for _, targetKind := range relRule.TargetKinds {
 				//fmt.Printf("Kind:%s TargetKind:%s\n", kind, targetKind)
-				if targetKind == kind {
+				if targetKind == kind || targetKind == objectRefAnyKind {
 					relatedKinds = append(relatedKinds, key)
 				}
 			}
@@ -363,11 +430,12 @@ func findRelatedKinds(kind string) []string{
 
 func findChildKinds(kind string) []string {
 	childKinds := make([]string, 0)
-	for _, relStringList := range relationshipMap {
-		for _, relString := range relStringList {
-			relType, _, _, targetKindList := parseRelationship(relString)
-			if relType == relTypeOwnerReference {
-				for _, tk := range targetKindList {
+	relationshipMapMux.RLock()
+	defer relationshipMapMux.RUnlock()
+	for _, relRules := range relationshipMap {
+		for _, relRule := range relRules {
+			if relRule.Type == relTypeOwnerReference {
+				for _, tk := range relRule.TargetKinds {
 					childKinds = append(childKinds, tk)
 				}
 			}
@@ -385,7 +453,7 @@ func GetCAdvisorMetrics(nodeName string) string {
 		return err.Error()
 	}
 	responseToReturn := string(responseRawArrayOfBytes)
-	fmt.Printf(responseToReturn)
+	fmt.Printf("%s", responseToReturn)
 	return responseToReturn
 }
 
@@ -427,6 +495,9 @@ func PrintRelatives(format string, connections []Connection) {
 		printConnections(connections, "default")
 	case "json":
 		printConnectionsJSON(connections)
+	case "dot", "mermaid":
+		nodes, edges := connectionsGraph(connections)
+		fmt.Printf("%s", getGraphString(format, nodes, edges))
 	}
 }
 
@@ -491,7 +562,7 @@ func printNode(connection Connection, printtype, relType string) {
 	} else {
 		relativeEntry = "Level:" + levelStr + " " + connection.Kind + "/" + connection.Name + relType
 	}
-	fmt.Printf(relativeEntry + "\n")
+	fmt.Printf("%s\n", relativeEntry)
 }
 
 func printPath(connections []Connection, printtype string) {
@@ -514,6 +585,18 @@ func printPath(connections []Connection, printtype string) {
 					relType = relType + yellow + connection.RelationType + reset
 				case relTypeOwnerReference:
 					relType = relType + cyan + connection.RelationType + reset
+				case relTypeVolume:
+					relType = relType + blue + connection.RelationType + reset
+				case relTypeNetworkPolicy:
+					relType = relType + blue + connection.RelationType + reset
+				case relTypeRBAC:
+					relType = relType + yellow + connection.RelationType + reset
+				case relTypeEndpoints:
+					relType = relType + green + connection.RelationType + reset
+				case relTypeManagedBy:
+					relType = relType + cyan + connection.RelationType + reset
+				case relTypeObjectRef:
+					relType = relType + purple + connection.RelationType + reset
 				}
 				relationType = " [related to " + connection.Peer.Kind + "/" + connection.Peer.Name +  " by:" + relType + "]"
 			} else {
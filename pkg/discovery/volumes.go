@@ -0,0 +1,144 @@
+package discovery
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Volume relationships between Pods/workload templates and the ConfigMaps, Secrets
+// and PersistentVolumeClaims they mount. The mount paths of a volume are recorded
+// in the RelationDetails of the connection.
+
+func searchVolumes(level int, kind, instance, namespace, targetKind, targetInstance string) ([]Connection, string) {
+	relativesNames := make([]Connection, 0)
+	volumeDetail := ""
+	dynamicClient, err := getDynamicClient()
+	if err != nil {
+		return relativesNames, volumeDetail
+	}
+	lhsResKindPlural, _, lhsResApiVersion, lhsResGroup := getKindAPIDetails(kind)
+	lhsRes := schema.GroupVersionResource{Group: lhsResGroup,
+		Version:  lhsResApiVersion,
+		Resource: lhsResKindPlural}
+	lhsInstList, err := getObjects(kind, instance, namespace, lhsRes, dynamicClient)
+	if err != nil {
+		return relativesNames, volumeDetail
+	}
+
+	rhsResKindPlural, _, rhsResApiVersion, rhsResGroup := getKindAPIDetails(targetKind)
+	rhsRes := schema.GroupVersionResource{Group: rhsResGroup,
+		Version:  rhsResApiVersion,
+		Resource: rhsResKindPlural}
+	rhsInstList, err := getObjects(targetKind, targetInstance, namespace, rhsRes, dynamicClient)
+	if err != nil {
+		return relativesNames, volumeDetail
+	}
+
+	for _, instanceObj := range lhsInstList {
+		lhsName := instanceObj.GetName()
+		for _, volumeRef := range getVolumeReferences(*instanceObj) {
+			if volumeRef.Kind != targetKind {
+				continue
+			}
+			for _, unstructuredObj := range rhsInstList {
+				if volumeRef.Name != unstructuredObj.GetName() {
+					continue
+				}
+				volumeDetail = volumeRef.details()
+				conn := orientConnection(level, kind, lhsName, instanceObj.GetNamespace(), targetKind, unstructuredObj.GetName(), unstructuredObj.GetNamespace(), instance,
+					relTypeVolume, volumeDetail)
+				relativesNames = appendConnections1(relativesNames, []Connection{conn})
+			}
+		}
+	}
+	return relativesNames, volumeDetail
+}
+
+func (volumeRef volumeReference) details() string {
+	return "Volume:" + volumeRef.Volume + " MountPath:" + strings.Join(volumeRef.MountPaths, ",")
+}
+
+func getVolumeReferences(instanceObj unstructured.Unstructured) []volumeReference {
+	volumeRefs := make([]volumeReference, 0)
+	content := instanceObj.UnstructuredContent()
+	for _, podSpec := range getPodSpecs(content) {
+		mountPaths := getVolumeMountPaths(podSpec)
+		volumeList, _, _ := unstructured.NestedSlice(podSpec, "volumes")
+		for _, vol := range volumeList {
+			volume, ok := vol.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			volumeName, _, _ := unstructured.NestedString(volume, "name")
+			for _, ref := range getVolumeSources(volume) {
+				// Generic ephemeral volumes get a PVC named <pod name>-<volume name>
+				if ref.Kind == PVCLAIM && ref.Name == "" {
+					if instanceObj.GetKind() != POD {
+						continue
+					}
+					ref.Name = instanceObj.GetName() + "-" + volumeName
+				}
+				ref.Volume = volumeName
+				ref.MountPaths = mountPaths[volumeName]
+				volumeRefs = append(volumeRefs, ref)
+			}
+		}
+	}
+	return volumeRefs
+}
+
+// Resources referred to by a single volume.
+func getVolumeSources(volume map[string]interface{}) []volumeReference {
+	refs := make([]volumeReference, 0)
+	if name, found, _ := unstructured.NestedString(volume, "configMap", "name"); found {
+		refs = append(refs, volumeReference{Kind: CONFIG_MAP, Name: name})
+	}
+	if name, found, _ := unstructured.NestedString(volume, "secret", "secretName"); found {
+		refs = append(refs, volumeReference{Kind: SECRET, Name: name})
+	}
+	if name, found, _ := unstructured.NestedString(volume, "persistentVolumeClaim", "claimName"); found {
+		refs = append(refs, volumeReference{Kind: PVCLAIM, Name: name})
+	}
+	if name, found, _ := unstructured.NestedString(volume, "csi", "nodePublishSecretRef", "name"); found {
+		refs = append(refs, volumeReference{Kind: SECRET, Name: name})
+	}
+	if _, found, _ := unstructured.NestedMap(volume, "ephemeral", "volumeClaimTemplate"); found {
+		refs = append(refs, volumeReference{Kind: PVCLAIM})
+	}
+	projectedSources, _, _ := unstructured.NestedSlice(volume, "projected", "sources")
+	for _, src := range projectedSources {
+		source, ok := src.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if name, found, _ := unstructured.NestedString(source, "configMap", "name"); found {
+			refs = append(refs, volumeReference{Kind: CONFIG_MAP, Name: name})
+		}
+		if name, found, _ := unstructured.NestedString(source, "secret", "name"); found {
+			refs = append(refs, volumeReference{Kind: SECRET, Name: name})
+		}
+	}
+	return refs
+}
+
+// Volume name -> mount paths of the volume in all the containers of the pod spec.
+func getVolumeMountPaths(podSpec map[string]interface{}) map[string][]string {
+	mountPaths := make(map[string][]string)
+	for _, container := range getContainers(podSpec) {
+		volumeMounts, _, _ := unstructured.NestedSlice(container, "volumeMounts")
+		for _, mount := range volumeMounts {
+			volumeMount, ok := mount.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			volumeName, _, _ := unstructured.NestedString(volumeMount, "name")
+			mountPath, _, _ := unstructured.NestedString(volumeMount, "mountPath")
+			if !containsString(mountPaths[volumeName], mountPath) {
+				mountPaths[volumeName] = append(mountPaths[volumeName], mountPath)
+			}
+		}
+	}
+	return mountPaths
+}
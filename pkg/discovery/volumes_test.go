@@ -0,0 +1,143 @@
+package discovery
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newTestVolumeMount(name, mountPath string) map[string]interface{} {
+	return map[string]interface{}{"name": name, "mountPath": mountPath}
+}
+
+// Pod spec with a volume of every supported source, mounted by an init container and a container
+func newTestVolumesPodSpec() map[string]interface{} {
+	return map[string]interface{}{
+		"initContainers": []interface{}{
+			map[string]interface{}{"name": "init", "volumeMounts": []interface{}{
+				newTestVolumeMount("config", "/etc/app"),
+				newTestVolumeMount("data", "/data"),
+			}},
+		},
+		"containers": []interface{}{
+			map[string]interface{}{"name": "app", "volumeMounts": []interface{}{
+				newTestVolumeMount("config", "/etc/app"),
+				newTestVolumeMount("tls", "/etc/tls"),
+				newTestVolumeMount("data", "/var/lib/app"),
+				newTestVolumeMount("scratch", "/scratch"),
+			}},
+		},
+		"volumes": []interface{}{
+			map[string]interface{}{"name": "config", "configMap": map[string]interface{}{"name": "app-config"}},
+			map[string]interface{}{"name": "tls", "secret": map[string]interface{}{"secretName": "app-tls"}},
+			map[string]interface{}{"name": "data", "persistentVolumeClaim": map[string]interface{}{"claimName": "app-data"}},
+			map[string]interface{}{"name": "bundle", "projected": map[string]interface{}{"sources": []interface{}{
+				map[string]interface{}{"configMap": map[string]interface{}{"name": "ca-bundle"}},
+				map[string]interface{}{"secret": map[string]interface{}{"name": "app-token"}},
+				map[string]interface{}{"serviceAccountToken": map[string]interface{}{"path": "token"}},
+			}}},
+			map[string]interface{}{"name": "vault", "csi": map[string]interface{}{
+				"driver":               "secrets-store.csi.k8s.io",
+				"nodePublishSecretRef": map[string]interface{}{"name": "vault-creds"},
+			}},
+			map[string]interface{}{"name": "scratch", "ephemeral": map[string]interface{}{
+				"volumeClaimTemplate": map[string]interface{}{"spec": map[string]interface{}{}},
+			}},
+			map[string]interface{}{"name": "cache", "emptyDir": map[string]interface{}{}},
+		},
+	}
+}
+
+func TestGetVolumeReferences(t *testing.T) {
+	pod := newTestObject(POD, "web-1", "default", "p1")
+	_ = unstructured.SetNestedMap(pod.Object, newTestVolumesPodSpec(), "spec")
+	deployment := newTestObject(DEPLOYMENT, "web", "default", "d1")
+	_ = unstructured.SetNestedMap(deployment.Object, newTestVolumesPodSpec(), "spec", "template", "spec")
+
+	fromPod := []volumeReference{
+		{Volume: "config", Kind: CONFIG_MAP, Name: "app-config", MountPaths: []string{"/etc/app"}},
+		{Volume: "tls", Kind: SECRET, Name: "app-tls", MountPaths: []string{"/etc/tls"}},
+		{Volume: "data", Kind: PVCLAIM, Name: "app-data", MountPaths: []string{"/data", "/var/lib/app"}},
+		{Volume: "bundle", Kind: CONFIG_MAP, Name: "ca-bundle"},
+		{Volume: "bundle", Kind: SECRET, Name: "app-token"},
+		{Volume: "vault", Kind: SECRET, Name: "vault-creds"},
+		{Volume: "scratch", Kind: PVCLAIM, Name: "web-1-scratch", MountPaths: []string{"/scratch"}},
+	}
+	testCases := []struct {
+		name     string
+		obj      unstructured.Unstructured
+		expected []volumeReference
+	}{
+		{"Pod", pod, fromPod},
+		// The PVC of a generic ephemeral volume is named after the Pod
+		{"pod template of a Deployment", deployment, fromPod[:6]},
+		{"object without volumes", newTestObject(POD, "web-2", "default", "p2"), []volumeReference{}},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			volumeRefs := getVolumeReferences(testCase.obj)
+			if !reflect.DeepEqual(volumeRefs, testCase.expected) {
+				t.Errorf("getVolumeReferences = %+v, expected %+v", volumeRefs, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestSearchVolumes(t *testing.T) {
+	pod := newTestObject(POD, "web-1", "default", "p1")
+	_ = unstructured.SetNestedMap(pod.Object, newTestVolumesPodSpec(), "spec")
+	deployment := newTestObject(DEPLOYMENT, "web", "default", "d1")
+	_ = unstructured.SetNestedMap(deployment.Object, newTestVolumesPodSpec(), "spec", "template", "spec")
+	setTestDynamicClient(t,
+		pod,
+		deployment,
+		newTestObject(SECRET, "app-tls", "default", "s1"),
+		newTestObject(SECRET, "app-tls", "other", "s2"),
+		newTestObject(SECRET, "unused", "default", "s3"),
+		newTestObject(CONFIG_MAP, "ca-bundle", "default", "c1"),
+		newTestObject(PVCLAIM, "web-1-scratch", "default", "pvc1"),
+	)
+
+	testCases := []struct {
+		name           string
+		kind           string
+		instance       string
+		targetKind     string
+		targetInstance string
+		expected       []string
+	}{
+		{
+			name: "Secrets mounted by a Pod", kind: POD, instance: "web-1", targetKind: SECRET, targetInstance: "*",
+			expected: []string{"Secret default/app-tls <- Pod default/web-1: Volume:tls MountPath:/etc/tls"},
+		},
+		{
+			name: "Pods that mount a Secret", kind: POD, instance: "*", targetKind: SECRET, targetInstance: "app-tls",
+			expected: []string{"Pod default/web-1 <- Secret default/app-tls: Volume:tls MountPath:/etc/tls"},
+		},
+		{
+			name: "Deployments that mount a Secret", kind: DEPLOYMENT, instance: "*", targetKind: SECRET, targetInstance: "app-tls",
+			expected: []string{"Deployment default/web <- Secret default/app-tls: Volume:tls MountPath:/etc/tls"},
+		},
+		{
+			name: "projected ConfigMap", kind: DEPLOYMENT, instance: "web", targetKind: CONFIG_MAP, targetInstance: "*",
+			expected: []string{"ConfigMap default/ca-bundle <- Deployment default/web: Volume:bundle MountPath:"},
+		},
+		{
+			name: "PVC of a generic ephemeral volume", kind: POD, instance: "web-1", targetKind: PVCLAIM, targetInstance: "*",
+			expected: []string{"PersistentVolumeClaim default/web-1-scratch <- Pod default/web-1: Volume:scratch MountPath:/scratch"},
+		},
+		{
+			name: "Secret that is not mounted", kind: POD, instance: "*", targetKind: SECRET, targetInstance: "unused",
+			expected: []string{},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			connections, _ := searchVolumes(1, testCase.kind, testCase.instance, "default", testCase.targetKind, testCase.targetInstance)
+			if descriptions := describeConnections(connections); !reflect.DeepEqual(descriptions, testCase.expected) {
+				t.Errorf("searchVolumes = %v, expected %v", descriptions, testCase.expected)
+			}
+		})
+	}
+}